@@ -49,6 +49,24 @@ func handleLex(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+func handleExplain(c *gin.Context) {
+	var req ParseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+		return
+	}
+
+	lines := strings.Split(req.Content, "\n")
+	metadata := config.NewMetadata("explain")
+	result, err := processor.Explain(lines, metadata)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Explain error: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func handlePreparse(c *gin.Context) {
 	var req ParseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -19,6 +19,7 @@ func main() {
 	// Routes
 	r.GET("/", handleHome)
 	r.POST("/lex", handleLex)
+	r.POST("/explain", handleExplain)
 	r.POST("/preparse", handlePreparse)
 	r.POST("/parse", handleParse)
 	r.POST("/build", handleBuild)
@@ -0,0 +1,203 @@
+// Package answers classifies answers by the kind of value they hold, so that graders and
+// distractor generators can treat "9.8 m/s^2", "July 4, 1776", "True" and "Albert
+// Einstein" differently. Numbers are read with the units of a built-in registry and
+// dates with fixed layouts, so classification works offline.
+package answers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// Value is an answer in structured form. Only the fields for its Type are set.
+type Value struct {
+	Type ontology.AnswerType `json:"type"`
+
+	// Numeric: the number as written and its unit, if any, with the number converted to
+	// the SI unit of the unit's dimension
+	Number    *float64  `json:"number,omitempty"`
+	Unit      string    `json:"unit,omitempty"` // The unit's symbol in the registry
+	Dimension Dimension `json:"dimension,omitempty"`
+	SIValue   *float64  `json:"si_value,omitempty"`
+
+	// Date: the date in ISO 8601, as precise as the answer ("1776-07-04", "1776-07" or "1776")
+	Date      string        `json:"date,omitempty"`
+	Precision DatePrecision `json:"precision,omitempty"`
+
+	Boolean *bool    `json:"boolean,omitempty"` // Boolean
+	Items   []string `json:"items,omitempty"`   // List
+}
+
+// maxListItemWords is the longest item of an enumerated answer; longer parts are clauses of a sentence
+const maxListItemWords = 5
+
+// nameConnectors are the lowercase words a proper noun may contain (e.g. "Leonardo da Vinci")
+var nameConnectors = map[string]bool{
+	"of": true, "the": true, "and": true, "de": true, "del": true, "della": true, "da": true, "di": true,
+	"du": true, "des": true, "la": true, "le": true, "van": true, "von": true, "der": true, "den": true,
+	"y": true, "al": true, "bin": true, "ibn": true, "on": true, "upon": true,
+}
+
+// Classify works out what kind of value an answer holds. The prompt tells a year from a
+// number, and the question type decides for typed questions: the answers of multi-select,
+// ordering and matching questions are lists.
+func Classify(prompt string, answer string, questionType ontology.QuestionType) *Value {
+	answer = strings.TrimSpace(answer)
+	switch questionType {
+	case ontology.QuestionTypeMultiSelect, ontology.QuestionTypeOrdering, ontology.QuestionTypeMatching:
+		return &Value{Type: ontology.AnswerTypeList, Items: splitItems(answer, constants.AnswerListDelimiter)}
+	}
+
+	if truth, ok := parseBoolean(answer, questionType == ontology.QuestionTypeTrueFalse); ok {
+		return &Value{Type: ontology.AnswerTypeBoolean, Boolean: &truth}
+	}
+	if date := parseDate(prompt, answer); date != nil {
+		date.Type = ontology.AnswerTypeDate
+		return date
+	}
+	if numeric := parseNumeric(answer); numeric != nil {
+		numeric.Type = ontology.AnswerTypeNumeric
+		return numeric
+	}
+	if items := listItems(answer); items != nil {
+		return &Value{Type: ontology.AnswerTypeList, Items: items}
+	}
+	if isProperNoun(answer) {
+		return &Value{Type: ontology.AnswerTypeProperNoun}
+	}
+	return &Value{Type: ontology.AnswerTypeFreeText}
+}
+
+// parseBoolean reads "true", "false", "yes" and "no", and "t" and "f" for true/false questions
+func parseBoolean(answer string, short bool) (bool, bool) {
+	switch strings.ToLower(answer) {
+	case "true", "yes":
+		return true, true
+	case "false", "no":
+		return false, true
+	case "t":
+		return true, short
+	case "f":
+		return false, short
+	}
+	return false, false
+}
+
+// parseNumeric reads a number or fraction followed by nothing or by a known unit. Numbers
+// too large for a float64, in themselves or in SI units, are not numeric answers.
+func parseNumeric(answer string) *Value {
+	answer = strings.ReplaceAll(answer, "−", "-")
+	var number float64
+	var rest string
+	if match := regexes.FractionAnswerRegex.FindStringSubmatch(answer); match != nil {
+		numerator, _ := strconv.ParseFloat(match[1], 64)
+		denominator, _ := strconv.ParseFloat(match[2], 64)
+		if denominator == 0 {
+			return nil
+		}
+		number, rest = numerator/denominator, match[3]
+	} else if match := regexes.NumericAnswerRegex.FindStringSubmatch(answer); match != nil {
+		parsed, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+		if err != nil {
+			return nil
+		}
+		if match[2] != "" {
+			exponent, _ := strconv.Atoi(match[2])
+			parsed *= math.Pow(10, float64(exponent))
+		}
+		number, rest = parsed, match[3]
+	} else {
+		return nil
+	}
+	if !isFinite(number) {
+		return nil
+	}
+
+	value := &Value{Number: &number}
+	if rest = strings.TrimSpace(rest); rest == "" {
+		return value
+	}
+	unit, ok := LookupUnit(rest)
+	if !ok {
+		return nil
+	}
+	si := unit.ToSI(number)
+	if !isFinite(si) {
+		return nil
+	}
+	value.Unit, value.Dimension, value.SIValue = unit.Symbol, unit.Dimension, &si
+	return value
+}
+
+// isFinite reports whether x is neither infinite nor NaN, so that it can be written as JSON
+func isFinite(x float64) bool {
+	return !math.IsInf(x, 0) && !math.IsNaN(x)
+}
+
+// listItems splits an enumerated answer such as "Mercury; Venus" or "red, green and blue".
+// Items separated by commas need at least three short items, so that "Paris, France" and
+// "salt and pepper" stay single answers.
+func listItems(answer string) []string {
+	if items := splitItems(answer, constants.AnswerListDelimiter); len(items) > 1 {
+		return items
+	}
+	if !strings.Contains(answer, ",") {
+		return nil
+	}
+	var items []string
+	for _, item := range regexes.ListSeparatorRegex.Split(answer, -1) {
+		item = strings.TrimSpace(item)
+		if item == "" || len(strings.Fields(item)) > maxListItemWords {
+			return nil
+		}
+		items = append(items, item)
+	}
+	if len(items) < 3 {
+		return nil
+	}
+	return items
+}
+
+// splitItems splits text on delimiter, dropping empty items
+func splitItems(text string, delimiter string) []string {
+	var items []string
+	for _, item := range strings.Split(text, delimiter) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isProperNoun reports whether an answer reads as a name: two or more words, each
+// capitalised except connecting words such as "of" and "da", and numbers after the
+// first word ("Apollo 11"). A single capitalised word is not enough, since answers
+// usually start with a capital.
+func isProperNoun(answer string) bool {
+	words := strings.Fields(answer)
+	if len(words) < 2 || len(words) > 8 {
+		return false
+	}
+	capitals := 0
+	for i, word := range words {
+		word = strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if word == "" {
+			return false
+		}
+		first := []rune(word)[0]
+		switch {
+		case unicode.IsUpper(first):
+			capitals++
+		case i > 0 && (nameConnectors[word] || unicode.IsDigit(first)):
+		default:
+			return false
+		}
+	}
+	return capitals >= 2
+}
@@ -0,0 +1,112 @@
+package answers
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		prompt       string
+		answer       string
+		questionType ontology.QuestionType
+		want         ontology.AnswerType
+	}{
+		{"Acceleration of gravity?", "9.8 m/s^2", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeNumeric},
+		{"How many moles?", "6.02 × 10^23", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeNumeric},
+		{"Half of 7?", "7/2", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeNumeric},
+		{"How many states?", "50", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeNumeric},
+		{"In what year was the Declaration signed?", "1776", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeDate},
+		{"When was it signed?", "July 4th, 1776", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeDate},
+		{"Caesar's death?", "44 BC", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeDate},
+		{"Is water wet?", "Yes", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeBoolean},
+		{"The sky is blue", "T", ontology.QuestionTypeTrueFalse, ontology.AnswerTypeBoolean},
+		{"Grade?", "T", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"Primary colours?", "red, green and blue", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeList},
+		{"Inner planets?", "Mercury; Venus", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeList},
+		{"Pick the mammals", "Whale", ontology.QuestionTypeMultiSelect, ontology.AnswerTypeList},
+		{"Who proposed relativity?", "Albert Einstein", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeProperNoun},
+		{"Who painted the Mona Lisa?", "Leonardo da Vinci", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeProperNoun},
+		{"Capital of the US?", "Washington, D.C.", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeProperNoun},
+		{"What is velocity?", "Speed with direction", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"What divides cells?", "Mitosis", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"How many apples?", "12 apples", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"Seasoning?", "salt and pepper", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"Too fast?", "1 × 10^400 m/s", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"Too far?", "1e308 km", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+		{"Too many?", "1e999", ontology.QuestionTypeShortAnswer, ontology.AnswerTypeFreeText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			if got := Classify(tt.prompt, tt.answer, tt.questionType); got.Type != tt.want {
+				t.Errorf("Classify(%q, %q) = %s, want %s", tt.prompt, tt.answer, got.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyValues(t *testing.T) {
+	got := Classify("Acceleration of gravity?", "9.8 m/s²", ontology.QuestionTypeShortAnswer)
+	if *got.Number != 9.8 || got.Unit != "m/s^2" || got.Dimension != DimensionAcceleration || *got.SIValue != 9.8 {
+		t.Errorf("Classify(9.8 m/s²) = %+v", got)
+	}
+	got = Classify("Boiling point of water?", "212 degrees Fahrenheit", ontology.QuestionTypeShortAnswer)
+	if got.Unit != "°F" || math.Abs(*got.SIValue-373.15) > 1e-9 {
+		t.Errorf("Classify(212 degrees Fahrenheit) = %+v, want 373.15 K", got)
+	}
+	got = Classify("Population?", "1,250,000", ontology.QuestionTypeShortAnswer)
+	if *got.Number != 1250000 || got.Unit != "" || got.SIValue != nil {
+		t.Errorf("Classify(1,250,000) = %+v", got)
+	}
+
+	dates := map[string][2]string{
+		"July 4, 1776": {"1776-07-04", "day"},
+		"4 Jul 1776":   {"1776-07-04", "day"},
+		"1776-07-04":   {"1776-07-04", "day"},
+		"07/04/1776":   {"1776-07-04", "day"},
+		"Sept. 1939":   {"1939-09", "month"},
+		"AD 79":        {"0079", "year"},
+		"44 BC":        {"-0043", "year"},
+	}
+	for answer, want := range dates {
+		got := Classify("Date?", answer, ontology.QuestionTypeShortAnswer)
+		if got.Type != ontology.AnswerTypeDate || got.Date != want[0] || string(got.Precision) != want[1] {
+			t.Errorf("Classify(%q) = %+v, want %s with precision %s", answer, got, want[0], want[1])
+		}
+	}
+
+	got = Classify("Order the planets", "Mercury; Venus; Earth", ontology.QuestionTypeOrdering)
+	if !reflect.DeepEqual(got.Items, []string{"Mercury", "Venus", "Earth"}) {
+		t.Errorf("Items = %q", got.Items)
+	}
+	got = Classify("Is it true?", "False", ontology.QuestionTypeTrueFalse)
+	if got.Boolean == nil || *got.Boolean {
+		t.Errorf("Classify(False) = %+v", got)
+	}
+}
+
+func TestLookupUnit(t *testing.T) {
+	tests := map[string]string{
+		"m":                "m",
+		"Metres":           "m",
+		"mm":               "mm",
+		"km/h":             "km/h",
+		"KPH":              "km/h",
+		"degrees  Celsius": "°C",
+		"°":                "°",
+		"ohms":             "Ω",
+	}
+	for text, want := range tests {
+		if unit, ok := LookupUnit(text); !ok || unit.Symbol != want {
+			t.Errorf("LookupUnit(%q) = %q, %v, want %q", text, unit.Symbol, ok, want)
+		}
+	}
+	for _, text := range []string{"Mm", "M", "apples", ""} {
+		if unit, ok := LookupUnit(text); ok {
+			t.Errorf("LookupUnit(%q) = %q, want no unit", text, unit.Symbol)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package answers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// DatePrecision says how much of a date an answer gives
+type DatePrecision string
+
+const (
+	PrecisionDay   DatePrecision = "day"
+	PrecisionMonth DatePrecision = "month"
+	PrecisionYear  DatePrecision = "year"
+)
+
+// dateLayouts are the date formats read in answers, by precision. Numeric dates with
+// slashes are read month first.
+var dateLayouts = []struct {
+	layout    string
+	precision DatePrecision
+}{
+	{"2006-01-02", PrecisionDay},
+	{"January 2, 2006", PrecisionDay},
+	{"January 2 2006", PrecisionDay},
+	{"Jan 2, 2006", PrecisionDay},
+	{"Jan 2 2006", PrecisionDay},
+	{"2 January 2006", PrecisionDay},
+	{"2 Jan 2006", PrecisionDay},
+	{"1/2/2006", PrecisionDay},
+	{"2006-01", PrecisionMonth},
+	{"January 2006", PrecisionMonth},
+	{"January, 2006", PrecisionMonth},
+	{"Jan 2006", PrecisionMonth},
+}
+
+// parseDate reads a date answer. A year alone is a date only with an era ("44 BC") or
+// when the prompt asks for a year or asks when; otherwise it is a number.
+func parseDate(prompt string, answer string) *Value {
+	text := regexes.OrdinalDayRegex.ReplaceAllString(answer, "$1")
+	text = strings.Join(strings.Fields(strings.ReplaceAll(text, ".", " ")), " ")
+	text = strings.ReplaceAll(text, " ,", ",")
+	text = strings.Replace(text, "Sept ", "Sep ", 1)
+	for _, candidate := range dateLayouts {
+		date, err := time.Parse(candidate.layout, text)
+		if err != nil {
+			continue
+		}
+		iso := date.Format("2006-01-02")
+		if candidate.precision == PrecisionMonth {
+			iso = date.Format("2006-01")
+		}
+		return &Value{Date: iso, Precision: candidate.precision}
+	}
+
+	match := regexes.EraYearRegex.FindStringSubmatch(strings.TrimSpace(answer))
+	if match == nil {
+		return nil
+	}
+	era := strings.ToUpper(strings.ReplaceAll(match[1]+match[3], ".", ""))
+	if era == "" && !regexes.YearQuestionRegex.MatchString(prompt) {
+		return nil
+	}
+	year, err := strconv.Atoi(match[2])
+	if err != nil || year == 0 {
+		return nil
+	}
+	if era == "BC" || era == "BCE" {
+		// Astronomical year numbering, as in ISO 8601: 1 BC is year 0
+		year = 1 - year
+	}
+	return &Value{Date: formatYear(year), Precision: PrecisionYear}
+}
+
+// formatYear writes a year with at least four digits, as in ISO 8601
+func formatYear(year int) string {
+	if year < 0 {
+		return fmt.Sprintf("-%04d", -year)
+	}
+	return fmt.Sprintf("%04d", year)
+}
@@ -0,0 +1,163 @@
+package answers
+
+import (
+	"math"
+	"strings"
+)
+
+// Dimension is the quantity a unit measures
+type Dimension string
+
+const (
+	DimensionLength       Dimension = "length"
+	DimensionMass         Dimension = "mass"
+	DimensionTime         Dimension = "time"
+	DimensionSpeed        Dimension = "speed"
+	DimensionAcceleration Dimension = "acceleration"
+	DimensionForce        Dimension = "force"
+	DimensionEnergy       Dimension = "energy"
+	DimensionPower        Dimension = "power"
+	DimensionPressure     Dimension = "pressure"
+	DimensionTemperature  Dimension = "temperature"
+	DimensionVolume       Dimension = "volume"
+	DimensionArea         Dimension = "area"
+	DimensionAmount       Dimension = "amount"
+	DimensionFrequency    Dimension = "frequency"
+	DimensionCurrent      Dimension = "current"
+	DimensionVoltage      Dimension = "voltage"
+	DimensionResistance   Dimension = "resistance"
+	DimensionCharge       Dimension = "charge"
+	DimensionAngle        Dimension = "angle"
+	DimensionRatio        Dimension = "ratio"
+)
+
+// Unit is a unit of measurement. A value in the unit is converted to the SI unit of its
+// dimension as value*Factor + Offset.
+type Unit struct {
+	Symbol    string
+	Name      string
+	Dimension Dimension
+	Factor    float64
+	Offset    float64  // Only temperatures have an offset
+	Aliases   []string // Other spellings; names are matched ignoring case, symbols exactly
+}
+
+// ToSI converts a value in the unit to the SI unit of its dimension
+func (u Unit) ToSI(value float64) float64 {
+	return value*u.Factor + u.Offset
+}
+
+// Units is the registry of known units. It is built into the package, so answers are
+// classified without a network call.
+var Units = []Unit{
+	// Length, in metres
+	{Symbol: "m", Name: "metre", Dimension: DimensionLength, Factor: 1, Aliases: []string{"meter", "metres", "meters"}},
+	{Symbol: "km", Name: "kilometre", Dimension: DimensionLength, Factor: 1e3, Aliases: []string{"kilometer", "kilometres", "kilometers"}},
+	{Symbol: "cm", Name: "centimetre", Dimension: DimensionLength, Factor: 1e-2, Aliases: []string{"centimeter", "centimetres", "centimeters"}},
+	{Symbol: "mm", Name: "millimetre", Dimension: DimensionLength, Factor: 1e-3, Aliases: []string{"millimeter", "millimetres", "millimeters"}},
+	{Symbol: "µm", Name: "micrometre", Dimension: DimensionLength, Factor: 1e-6, Aliases: []string{"um", "μm", "micrometer", "micrometres", "micrometers", "micron", "microns"}},
+	{Symbol: "nm", Name: "nanometre", Dimension: DimensionLength, Factor: 1e-9, Aliases: []string{"nanometer", "nanometres", "nanometers"}},
+	{Symbol: "in", Name: "inch", Dimension: DimensionLength, Factor: 0.0254, Aliases: []string{"inches"}},
+	{Symbol: "ft", Name: "foot", Dimension: DimensionLength, Factor: 0.3048, Aliases: []string{"feet"}},
+	{Symbol: "yd", Name: "yard", Dimension: DimensionLength, Factor: 0.9144, Aliases: []string{"yards"}},
+	{Symbol: "mi", Name: "mile", Dimension: DimensionLength, Factor: 1609.344, Aliases: []string{"miles"}},
+
+	// Mass, in kilograms
+	{Symbol: "kg", Name: "kilogram", Dimension: DimensionMass, Factor: 1, Aliases: []string{"kilograms"}},
+	{Symbol: "g", Name: "gram", Dimension: DimensionMass, Factor: 1e-3, Aliases: []string{"grams"}},
+	{Symbol: "mg", Name: "milligram", Dimension: DimensionMass, Factor: 1e-6, Aliases: []string{"milligrams"}},
+	{Symbol: "t", Name: "tonne", Dimension: DimensionMass, Factor: 1e3, Aliases: []string{"tonnes", "metric ton", "metric tons"}},
+	{Symbol: "lb", Name: "pound", Dimension: DimensionMass, Factor: 0.45359237, Aliases: []string{"lbs", "pounds"}},
+	{Symbol: "oz", Name: "ounce", Dimension: DimensionMass, Factor: 0.028349523125, Aliases: []string{"ounces"}},
+
+	// Time, in seconds
+	{Symbol: "s", Name: "second", Dimension: DimensionTime, Factor: 1, Aliases: []string{"sec", "seconds"}},
+	{Symbol: "ms", Name: "millisecond", Dimension: DimensionTime, Factor: 1e-3, Aliases: []string{"milliseconds"}},
+	{Symbol: "min", Name: "minute", Dimension: DimensionTime, Factor: 60, Aliases: []string{"minutes", "mins"}},
+	{Symbol: "h", Name: "hour", Dimension: DimensionTime, Factor: 3600, Aliases: []string{"hr", "hrs", "hours"}},
+	{Symbol: "d", Name: "day", Dimension: DimensionTime, Factor: 86400, Aliases: []string{"days"}},
+	{Symbol: "yr", Name: "year", Dimension: DimensionTime, Factor: 31557600, Aliases: []string{"years"}},
+
+	// Speed, in metres per second, and acceleration, in metres per second squared
+	{Symbol: "m/s", Name: "metre per second", Dimension: DimensionSpeed, Factor: 1, Aliases: []string{"meters per second", "metres per second"}},
+	{Symbol: "km/h", Name: "kilometre per hour", Dimension: DimensionSpeed, Factor: 1 / 3.6, Aliases: []string{"kph", "kmh", "kilometers per hour", "kilometres per hour"}},
+	{Symbol: "mph", Name: "mile per hour", Dimension: DimensionSpeed, Factor: 0.44704, Aliases: []string{"miles per hour"}},
+	{Symbol: "m/s^2", Name: "metre per second squared", Dimension: DimensionAcceleration, Factor: 1, Aliases: []string{"m/s²", "m/s2", "m·s⁻²", "meters per second squared", "metres per second squared"}},
+
+	// Force, energy, power and pressure
+	{Symbol: "N", Name: "newton", Dimension: DimensionForce, Factor: 1, Aliases: []string{"newtons"}},
+	{Symbol: "kN", Name: "kilonewton", Dimension: DimensionForce, Factor: 1e3, Aliases: []string{"kilonewtons"}},
+	{Symbol: "J", Name: "joule", Dimension: DimensionEnergy, Factor: 1, Aliases: []string{"joules"}},
+	{Symbol: "kJ", Name: "kilojoule", Dimension: DimensionEnergy, Factor: 1e3, Aliases: []string{"kilojoules"}},
+	{Symbol: "cal", Name: "calorie", Dimension: DimensionEnergy, Factor: 4.184, Aliases: []string{"calories"}},
+	{Symbol: "kcal", Name: "kilocalorie", Dimension: DimensionEnergy, Factor: 4184, Aliases: []string{"kilocalories", "Cal"}},
+	{Symbol: "eV", Name: "electronvolt", Dimension: DimensionEnergy, Factor: 1.602176634e-19, Aliases: []string{"electronvolts", "electron volts"}},
+	{Symbol: "kWh", Name: "kilowatt hour", Dimension: DimensionEnergy, Factor: 3.6e6, Aliases: []string{"kilowatt hours", "kilowatt-hours"}},
+	{Symbol: "W", Name: "watt", Dimension: DimensionPower, Factor: 1, Aliases: []string{"watts"}},
+	{Symbol: "kW", Name: "kilowatt", Dimension: DimensionPower, Factor: 1e3, Aliases: []string{"kilowatts"}},
+	{Symbol: "Pa", Name: "pascal", Dimension: DimensionPressure, Factor: 1, Aliases: []string{"pascals"}},
+	{Symbol: "kPa", Name: "kilopascal", Dimension: DimensionPressure, Factor: 1e3, Aliases: []string{"kilopascals"}},
+	{Symbol: "atm", Name: "atmosphere", Dimension: DimensionPressure, Factor: 101325, Aliases: []string{"atmospheres"}},
+	{Symbol: "bar", Name: "bar", Dimension: DimensionPressure, Factor: 1e5, Aliases: []string{"bars"}},
+	{Symbol: "mmHg", Name: "millimetre of mercury", Dimension: DimensionPressure, Factor: 133.322387415},
+
+	// Temperature, in kelvins
+	{Symbol: "K", Name: "kelvin", Dimension: DimensionTemperature, Factor: 1, Aliases: []string{"kelvins"}},
+	{Symbol: "°C", Name: "degree Celsius", Dimension: DimensionTemperature, Factor: 1, Offset: 273.15, Aliases: []string{"degrees Celsius", "celsius"}},
+	{Symbol: "°F", Name: "degree Fahrenheit", Dimension: DimensionTemperature, Factor: 5.0 / 9, Offset: 459.67 * 5 / 9, Aliases: []string{"degrees Fahrenheit", "fahrenheit"}},
+
+	// Volume, in cubic metres, and area, in square metres
+	{Symbol: "m^3", Name: "cubic metre", Dimension: DimensionVolume, Factor: 1, Aliases: []string{"m³", "m3", "cubic meter", "cubic metres", "cubic meters"}},
+	{Symbol: "L", Name: "litre", Dimension: DimensionVolume, Factor: 1e-3, Aliases: []string{"l", "liter", "litres", "liters"}},
+	{Symbol: "mL", Name: "millilitre", Dimension: DimensionVolume, Factor: 1e-6, Aliases: []string{"ml", "milliliter", "millilitres", "milliliters"}},
+	{Symbol: "m^2", Name: "square metre", Dimension: DimensionArea, Factor: 1, Aliases: []string{"m²", "m2", "square meter", "square metres", "square meters"}},
+	{Symbol: "km^2", Name: "square kilometre", Dimension: DimensionArea, Factor: 1e6, Aliases: []string{"km²", "km2", "square kilometer", "square kilometres", "square kilometers"}},
+	{Symbol: "ha", Name: "hectare", Dimension: DimensionArea, Factor: 1e4, Aliases: []string{"hectares"}},
+
+	// Chemistry, waves and electricity
+	{Symbol: "mol", Name: "mole", Dimension: DimensionAmount, Factor: 1, Aliases: []string{"moles"}},
+	{Symbol: "Hz", Name: "hertz", Dimension: DimensionFrequency, Factor: 1},
+	{Symbol: "kHz", Name: "kilohertz", Dimension: DimensionFrequency, Factor: 1e3},
+	{Symbol: "MHz", Name: "megahertz", Dimension: DimensionFrequency, Factor: 1e6},
+	{Symbol: "GHz", Name: "gigahertz", Dimension: DimensionFrequency, Factor: 1e9},
+	{Symbol: "A", Name: "ampere", Dimension: DimensionCurrent, Factor: 1, Aliases: []string{"amperes", "amp", "amps"}},
+	{Symbol: "V", Name: "volt", Dimension: DimensionVoltage, Factor: 1, Aliases: []string{"volts"}},
+	{Symbol: "Ω", Name: "ohm", Dimension: DimensionResistance, Factor: 1, Aliases: []string{"ohms"}},
+	{Symbol: "C", Name: "coulomb", Dimension: DimensionCharge, Factor: 1, Aliases: []string{"coulombs"}},
+
+	// Angles, in radians, and ratios
+	{Symbol: "°", Name: "degree", Dimension: DimensionAngle, Factor: math.Pi / 180, Aliases: []string{"degrees", "deg"}},
+	{Symbol: "rad", Name: "radian", Dimension: DimensionAngle, Factor: 1, Aliases: []string{"radians"}},
+	{Symbol: "%", Name: "percent", Dimension: DimensionRatio, Factor: 1e-2, Aliases: []string{"percent", "per cent"}},
+}
+
+// unitSymbols and unitNames index Units by symbol and by lowercase name
+var unitSymbols, unitNames = indexUnits()
+
+func indexUnits() (map[string]Unit, map[string]Unit) {
+	symbols, names := make(map[string]Unit), make(map[string]Unit)
+	for _, unit := range Units {
+		symbols[unit.Symbol] = unit
+		names[strings.ToLower(unit.Name)] = unit
+		for _, alias := range unit.Aliases {
+			// A one-word alias with a capital letter, or without letters, is a symbol
+			if !strings.Contains(alias, " ") && (alias != strings.ToLower(alias) || !strings.ContainsAny(alias, "abcdefghijklmnopqrstuvwxyz")) {
+				symbols[alias] = unit
+			} else {
+				names[strings.ToLower(alias)] = unit
+			}
+		}
+	}
+	return symbols, names
+}
+
+// LookupUnit finds a unit by its symbol, matched exactly so that "mm" and "Mm" differ, or
+// by its name or another spelling, ignoring case
+func LookupUnit(text string) (Unit, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	if unit, ok := unitSymbols[text]; ok {
+		return unit, true
+	}
+	unit, ok := unitNames[strings.ToLower(text)]
+	return unit, ok
+}
@@ -0,0 +1,64 @@
+package builder
+
+import (
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// applyAnnotations copies the annotation of a question line onto q, estimating the
+// difficulty when the author did not give one
+func applyAnnotations(q *tree.Question, annotations *preparser.Annotations) {
+	if annotations != nil {
+		q.Difficulty, q.BloomLevel, q.Keywords = annotations.Difficulty, annotations.Bloom, annotations.Keywords
+	}
+	if q.Difficulty == 0 {
+		q.Difficulty = estimateDifficulty(q)
+		q.DifficultyEstimated = true
+	}
+}
+
+// estimateDifficulty guesses how hard a question is from its shape. It starts a step
+// above the easiest difficulty and adds a step for a long answer, a very long answer, a
+// long prompt, a structured answer, one with more than four items and a Bloom level
+// above Apply. A true/false question is then a step easier.
+func estimateDifficulty(q *tree.Question) int {
+	difficulty := ontology.MinDifficulty + 1
+
+	switch q.QuestionType {
+	case ontology.QuestionTypeTrueFalse:
+	case ontology.QuestionTypeMultiSelect, ontology.QuestionTypeOrdering, ontology.QuestionTypeMatching:
+		difficulty++
+		if answer := q.StructuredAnswer; answer != nil && len(answer.Answers)+len(answer.Sequence)+len(answer.Pairs) > 4 {
+			difficulty++
+		}
+	default:
+		words := len(strings.Fields(q.Answer))
+		if words > 3 {
+			difficulty++
+		}
+		if words > 10 {
+			difficulty++
+		}
+	}
+	if len(strings.Fields(q.Prompt)) > 25 {
+		difficulty++
+	}
+	switch q.BloomLevel {
+	case ontology.BloomLevelAnalyze, ontology.BloomLevelEvaluate, ontology.BloomLevelCreate:
+		difficulty++
+	}
+	if q.QuestionType == ontology.QuestionTypeTrueFalse {
+		difficulty--
+	}
+
+	if difficulty < ontology.MinDifficulty {
+		return ontology.MinDifficulty
+	}
+	if difficulty > ontology.MaxDifficulty {
+		return ontology.MaxDifficulty
+	}
+	return difficulty
+}
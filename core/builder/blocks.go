@@ -0,0 +1,95 @@
+package builder
+
+import (
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/parser"
+	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// blockBuilder groups consecutive passage content lines into blocks.
+// Lines of the same kind are merged into the current block until a
+// paragraph break (an empty line or a question) or a line of another kind.
+type blockBuilder struct {
+	blocks  []*tree.Block
+	current *tree.Block
+	// headerPending is true while the current table has a single row that may become its header
+	headerPending bool
+}
+
+// addContent adds a content line to the current block, starting a new block if needed
+func (b *blockBuilder) addContent(content *preparser.ContentResult) {
+	switch content.Kind {
+	case preparser.ContentKindListItem:
+		b.ensure(tree.BlockTypeList)
+		b.current.Items = append(b.current.Items, content.Value)
+
+	case preparser.ContentKindTableRow:
+		b.ensure(tree.BlockTypeTable)
+		b.current.Rows = append(b.current.Rows, content.Cells)
+		b.headerPending = len(b.current.Rows) == 1 && b.current.Header == nil
+
+	case preparser.ContentKindTableSeparator:
+		b.ensure(tree.BlockTypeTable)
+		// A separator directly after the first row turns that row into the header
+		if b.headerPending {
+			b.current.Header = b.current.Rows[0]
+			b.current.Rows = nil
+		}
+		b.headerPending = false
+
+	case preparser.ContentKindQuote:
+		b.ensure(tree.BlockTypeQuote)
+		b.current.Text = joinText(b.current.Text, content.Value)
+
+	default:
+		b.ensure(tree.BlockTypeParagraph)
+		b.current.Text = joinText(b.current.Text, content.Text)
+	}
+}
+
+// addCode adds a code block as a block of its own
+func (b *blockBuilder) addCode(code *tree.CodeBlock) {
+	b.blocks = append(b.blocks, &tree.Block{Type: tree.BlockTypeCode, Code: code})
+	b.breakBlock()
+}
+
+// breakBlock ends the current block so that the next line starts a new one
+func (b *blockBuilder) breakBlock() {
+	b.current = nil
+	b.headerPending = false
+}
+
+// ensure makes sure the current block has the given type
+func (b *blockBuilder) ensure(blockType tree.BlockType) {
+	if b.current != nil && b.current.Type == blockType {
+		return
+	}
+	b.current = &tree.Block{Type: blockType}
+	b.headerPending = false
+	b.blocks = append(b.blocks, b.current)
+}
+
+// joinText joins wrapped lines of a paragraph or quote with a space
+func joinText(existing string, line string) string {
+	if existing == "" {
+		return line
+	}
+	return strings.Join([]string{existing, line}, " ")
+}
+
+// buildCodeBlock collects the code lines under a code block's opening fence
+func buildCodeBlock(node *parser.Node) *tree.CodeBlock {
+	var lines []string
+	for _, child := range node.Children {
+		if code := child.Data.GetCode(); code != nil {
+			lines = append(lines, code.Text)
+		}
+	}
+	code := &tree.CodeBlock{Code: strings.Join(lines, "\n")}
+	if start := node.Data.GetCodeStart(); start != nil {
+		code.Language = start.Language
+	}
+	return code
+}
@@ -3,11 +3,14 @@ package builder
 import (
 	"strings"
 
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
 	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
 	"github.com/studyguides-com/study-guides-parser/core/lexer"
 	"github.com/studyguides-com/study-guides-parser/core/ontology"
 	"github.com/studyguides-com/study-guides-parser/core/parser"
 	"github.com/studyguides-com/study-guides-parser/core/qa"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
 	"github.com/studyguides-com/study-guides-parser/core/tree"
 )
 
@@ -20,19 +23,23 @@ func Build(ast *parser.AbstractSyntaxTree, metadata *config.Metadata) *tree.Tree
 
 	// Walk through the AST and build the tree
 	initialOrder := 0
-	buildTree(ast.Root, tree.Root, &initialOrder)
+	buildTree(ast.Root, tree.Root, &initialOrder, newBuildSettings(metadata))
+	collectReferences(tree, metadata)
+	collectAuthorNotes(tree, metadata)
+	analyzeReadability(tree, metadata)
+
+	// Apply the default content rating declared for the guide
+	if metadata.ContentRating != "" {
+		tree.ApplyContentRating(metadata.ContentRating)
+	}
 
-	// Assign tag types based on context
-	if metadata.ContextType != ontology.ContextTypeNone {
+	// Assign tag types based on context, or on the contexts declared for some branches
+	if metadata.ContextType != ontology.ContextTypeNone || tree.HasDeclaredContext() {
 		_ = tree.AssignTagTypes(metadata.ContextType)
 	}
 
 	// Run QA
-	qaRunner := qa.NewTreeQARunner(
-		qa.NewTagTypeQA(),
-		qa.NewContextTypeQA(),
-	)
-	qaRunner.RunQAAndUpdate(tree)
+	newQARunner(ast, metadata).RunQAAndUpdate(tree)
 
 	return tree
 }
@@ -47,22 +54,63 @@ func BuildWithContext(ast *parser.AbstractSyntaxTree, metadata *config.Metadata,
 
 	// Walk through the AST and build the tree
 	initialOrder := 0
-	buildTree(ast.Root, tree.Root, &initialOrder)
+	buildTree(ast.Root, tree.Root, &initialOrder, newBuildSettings(metadata))
+	collectReferences(tree, metadata)
+	collectAuthorNotes(tree, metadata)
+	analyzeReadability(tree, metadata)
+
+	// Apply the default content rating declared for the guide
+	if metadata.ContentRating != "" {
+		tree.ApplyContentRating(metadata.ContentRating)
+	}
 
 	// Assign tag types based on the provided context
 	tree.AssignTagTypes(contextType)
 
 	// Run QA
-	qaRunner := qa.NewTreeQARunner(
-		qa.NewTagTypeQA(),
-		qa.NewContextTypeQA(),
-	)
-	qaRunner.RunQAAndUpdate(tree)
+	newQARunner(ast, metadata).RunQAAndUpdate(tree)
 
 	return tree
 }
 
-func buildTree(node *parser.Node, currentTag tree.TagContainer, questionOrder *int) {
+// buildSettings holds the metadata options that change how the tree is built
+type buildSettings struct {
+	policy        cleanstring.Policy // The normalization policy the hashes are keyed with
+	templateCount int                // How many questions each template question generates
+	templateSeed  int64              // The seed their parameter values are drawn with
+}
+
+// newBuildSettings reads the build options of the metadata. The processor rejects invalid
+// values before the builder runs, so they fall back to the defaults here.
+func newBuildSettings(metadata *config.Metadata) buildSettings {
+	policy, err := cleanstring.ParsePolicy(metadata.Options[constants.OptionNormalization])
+	if err != nil {
+		policy = cleanstring.LegacyPolicy
+	}
+	count, err := templates.ParseCount(metadata.Options[constants.OptionTemplateInstances])
+	if err != nil {
+		count = templates.DefaultCount
+	}
+	seed, _ := templates.ParseSeed(metadata.Options[constants.OptionTemplateSeed])
+	return buildSettings{policy: policy, templateCount: count, templateSeed: seed}
+}
+
+// newQARunner returns the QA checks run on every built tree. The check that numbers
+// match the computed order is opt-in, since numbering that restarts in each passage
+// is a common style. Gaps in the numbering are expected when "@if" blocks removed questions.
+func newQARunner(ast *parser.AbstractSyntaxTree, metadata *config.Metadata) *qa.TreeQARunner {
+	checks := []qa.TreeQA{
+		qa.NewTagTypeQA(),
+		qa.NewContextTypeQA(),
+		qa.NewQuestionNumberingQA().WithRemovedNumbers(ast.RemovedQuestions),
+	}
+	if metadata.Options[constants.OptionCheckQuestionOrder] == "true" {
+		checks = append(checks, qa.NewQuestionOrderQA())
+	}
+	return qa.NewTreeQARunner(checks...)
+}
+
+func buildTree(node *parser.Node, currentTag tree.TagContainer, questionOrder *int, settings buildSettings) {
 	if node == nil {
 		return
 	}
@@ -75,44 +123,62 @@ func buildTree(node *parser.Node, currentTag tree.TagContainer, questionOrder *i
 				root.Title = fileHeader.Title
 			}
 		}
-		// Process children
+		// Process children. An "@context" directive applies to the top-level tags of the
+		// headers that follow it, until the next one; "@context: None" ends the override.
+		var declared ontology.ContextType
 		for _, child := range node.Children {
-			buildTree(child, currentTag, questionOrder)
+			if directive := child.Data.GetDirective(); directive != nil {
+				if directive.Name == constants.ContextDirective {
+					declared = ontology.ContextType(directive.Value)
+				}
+				continue
+			}
+			buildTree(child, currentTag, questionOrder, settings)
+			if header := child.Data.GetHeader(); header != nil && declared != "" && declared != ontology.ContextTypeNone {
+				declareContext(currentTag, header.Parts[0], declared)
+			}
 		}
 
 	case lexer.TokenTypeHeader:
 		// Header creates a new tag structure
 		if header := node.Data.GetHeader(); header != nil {
 			// Build the tag hierarchy from header parts
-			tag := buildTagHierarchy(currentTag, header.Parts)
+			tag := buildTagHierarchy(currentTag, header.Parts, settings.policy)
+			if tag != nil {
+				tag.AuthorNotes = append(tag.AuthorNotes, nodeAuthorNotes(node)...)
+			}
 			// Create a new question order counter for this tag
 			tagQuestionOrder := 0
-			// Process children (questions, passages, etc.) and add them to the last tag
+			// Process children (questions, passages, etc.) and add them to the last tag.
+			// Content outside any passage is kept as the tag's notes.
 			for _, child := range node.Children {
-				buildTree(child, tag, &tagQuestionOrder)
+				switch child.Type {
+				case lexer.TokenTypeContent:
+					if content := child.Data.GetContent(); content != nil && tag != nil {
+						tag.AddNote(content.Text)
+						tag.References = append(tag.References, content.References...)
+					}
+				case lexer.TokenTypeLearnMore:
+					if learnMore := child.Data.GetLearnMore(); learnMore != nil && tag != nil {
+						tag.AddLearnMore(learnMore.Text)
+						tag.References = append(tag.References, learnMore.References...)
+					}
+				default:
+					buildTree(child, tag, &tagQuestionOrder, settings)
+				}
 			}
 		}
 
 	case lexer.TokenTypeQuestion:
 		// Question gets added to the current tag
 		if question := node.Data.GetQuestion(); question != nil {
-			// Check for learn more content in children
-			var learnMoreText string
-			for _, child := range node.Children {
-				if child.Type == lexer.TokenTypeLearnMore {
-					if learnMore := child.Data.GetLearnMore(); learnMore != nil {
-						learnMoreText = learnMore.Text
-					}
-				}
-			}
-			// Increment order counter and create question
-			*questionOrder++
-			q := tree.NewQuestion(question.QuestionText, question.AnswerText, nil, learnMoreText, *questionOrder)
+			// Create the question, or one question per cloze index or template instance, advancing the order counter
+			questions := buildQuestions(question, node, learnMoreTexts(node), questionOrder, settings)
 			if tag, ok := currentTag.(*tree.Tag); ok {
 				if tag.Overview == nil {
 					tag.Overview = &tree.Overview{}
 				}
-				tag.Questions = append(tag.Questions, q)
+				tag.Questions = append(tag.Questions, questions...)
 			}
 		}
 
@@ -122,27 +188,23 @@ func buildTree(node *parser.Node, currentTag tree.TagContainer, questionOrder *i
 			// Process children (content and questions) and collect data
 			var contentLines []string
 			var questions []*tree.Question
+			var blocks blockBuilder
 			for _, child := range node.Children {
 				if child.Type == lexer.TokenTypeQuestion {
+					blocks.breakBlock()
 					if question := child.Data.GetQuestion(); question != nil {
-						// Check for learn more content in question children
-						var learnMoreText string
-						for _, grandChild := range child.Children {
-							if grandChild.Type == lexer.TokenTypeLearnMore {
-								if learnMore := grandChild.Data.GetLearnMore(); learnMore != nil {
-									learnMoreText = learnMore.Text
-								}
-							}
-						}
-						// Increment order counter and create question
-						*questionOrder++
-						q := tree.NewQuestion(question.QuestionText, question.AnswerText, nil, learnMoreText, *questionOrder)
-						questions = append(questions, q)
+						// Create the question, or one question per cloze index or template instance, advancing the order counter
+						questions = append(questions, buildQuestions(question, child, learnMoreTexts(child), questionOrder, settings)...)
 					}
 				} else if child.Type == lexer.TokenTypeContent {
 					if content := child.Data.GetContent(); content != nil {
 						contentLines = append(contentLines, content.Text)
+						blocks.addContent(content)
 					}
+				} else if child.Type == lexer.TokenTypeEmpty {
+					blocks.breakBlock()
+				} else if child.Type == lexer.TokenTypeCodeStart {
+					blocks.addCode(buildCodeBlock(child))
 				}
 			}
 			// Concatenate content lines with newlines
@@ -152,6 +214,18 @@ func buildTree(node *parser.Node, currentTag tree.TagContainer, questionOrder *i
 			}
 			// Create passage using NewPassage constructor
 			p := tree.NewPassage(passage.Text, content, questions)
+			p.Hash = tree.PassageHash(passage.Text, settings.policy)
+			p.Blocks = blocks.blocks
+			for _, child := range node.Children {
+				if directive := child.Data.GetDirective(); directive != nil {
+					applyPassageMetadata(p, directive)
+				}
+			}
+			for _, text := range learnMoreTexts(node) {
+				p.AddLearnMore(text)
+			}
+			p.References = nodeReferences(node)
+			p.AuthorNotes = nodeAuthorNotes(node)
 			// Add the passage to the current tag's Passages
 			if tag, ok := currentTag.(*tree.Tag); ok {
 				tag.Passages = append(tag.Passages, p)
@@ -161,12 +235,58 @@ func buildTree(node *parser.Node, currentTag tree.TagContainer, questionOrder *i
 	default:
 		// For other node types, just process children
 		for _, child := range node.Children {
-			buildTree(child, currentTag, questionOrder)
+			buildTree(child, currentTag, questionOrder, settings)
+		}
+	}
+}
+
+// collectAuthorNotes adds the author notes to the tree's output when the metadata asks for
+// them to be exported
+func collectAuthorNotes(t *tree.Tree, metadata *config.Metadata) {
+	if metadata.Options[constants.OptionExportAuthorNotes] == "true" {
+		t.CollectAuthorNotes()
+	}
+}
+
+// nodeAuthorNotes returns the comments kept on a node and on the lines that belong to it
+// (content, Learn More, code and empty lines), but not those of its questions or passages
+func nodeAuthorNotes(node *parser.Node) []string {
+	notes := append([]string{}, node.AuthorNotes...)
+	for _, child := range node.Children {
+		if child.Type != lexer.TokenTypeQuestion && child.Type != lexer.TokenTypePassage {
+			notes = append(notes, child.AuthorNotes...)
+		}
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+	return notes
+}
+
+// learnMoreTexts returns the text of each Learn More line directly under node, in order
+func learnMoreTexts(node *parser.Node) []string {
+	var texts []string
+	for _, child := range node.Children {
+		if child.Type == lexer.TokenTypeLearnMore {
+			if learnMore := child.Data.GetLearnMore(); learnMore != nil {
+				texts = append(texts, learnMore.Text)
+			}
+		}
+	}
+	return texts
+}
+
+// declareContext sets the declared context of the top-level tag titled title. The first
+// declaration of a tag wins, since all its headers share one branch.
+func declareContext(container tree.TagContainer, title string, contextType ontology.ContextType) {
+	for _, tag := range container.GetChildTags() {
+		if tag.Title == title && tag.DeclaredContext == "" {
+			tag.DeclaredContext = contextType
 		}
 	}
 }
 
-func buildTagHierarchy(parentTag tree.TagContainer, headerParts []string) *tree.Tag {
+func buildTagHierarchy(parentTag tree.TagContainer, headerParts []string, policy cleanstring.Policy) *tree.Tag {
 	if len(headerParts) == 0 {
 		if tag, ok := parentTag.(*tree.Tag); ok {
 			return tag
@@ -191,10 +311,12 @@ func buildTagHierarchy(parentTag tree.TagContainer, headerParts []string) *tree.
 			// For top-level tags (categories), always create without parent hash
 			// This ensures consistent hashing across different files
 			currentTag = tree.NewTag(headerParts[0])
+			currentTag.Hash = tree.TagHash("", headerParts[0], policy)
 		} else {
 			// For nested tags, create with parent-based hash
 			if tag, ok := parentTag.(*tree.Tag); ok {
 				currentTag = tree.NewTagWithParent(headerParts[0], tag.Title)
+				currentTag.Hash = tree.TagHash(tag.Title, headerParts[0], policy)
 			}
 		}
 		parentTag.AddChildTag(currentTag)
@@ -202,7 +324,7 @@ func buildTagHierarchy(parentTag tree.TagContainer, headerParts []string) *tree.
 
 	// Recursively build the rest of the hierarchy
 	if len(headerParts) > 1 {
-		return buildTagHierarchy(currentTag, headerParts[1:])
+		return buildTagHierarchy(currentTag, headerParts[1:], policy)
 	}
 
 	return currentTag
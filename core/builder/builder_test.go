@@ -4,13 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/studyguides-com/study-guides-parser/core/config"
 	"github.com/studyguides-com/study-guides-parser/core/idgen"
 	"github.com/studyguides-com/study-guides-parser/core/lexer"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
 	"github.com/studyguides-com/study-guides-parser/core/parser"
 	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
 )
 
 func TestBuildTree(t *testing.T) {
@@ -216,8 +219,8 @@ func TestBuildTree(t *testing.T) {
 	if tagD.Questions[0].Answer != "2" {
 		t.Errorf("Expected answer '2', got '%s'", tagD.Questions[0].Answer)
 	}
-	if tagD.Questions[0].LearnMore != "This is simple addition" {
-		t.Errorf("Expected learn more 'This is simple addition', got '%s'", tagD.Questions[0].LearnMore)
+	if len(tagD.Questions[0].LearnMore) != 1 || tagD.Questions[0].LearnMore[0] != "This is simple addition" {
+		t.Errorf("Expected learn more 'This is simple addition', got %q", tagD.Questions[0].LearnMore)
 	}
 
 	// Check the second standalone question with learn more
@@ -227,8 +230,8 @@ func TestBuildTree(t *testing.T) {
 	if tagD.Questions[1].Answer != "0" {
 		t.Errorf("Expected answer '0', got '%s'", tagD.Questions[1].Answer)
 	}
-	if tagD.Questions[1].LearnMore != "This is simple subtraction" {
-		t.Errorf("Expected learn more 'This is simple subtraction', got '%s'", tagD.Questions[1].LearnMore)
+	if len(tagD.Questions[1].LearnMore) != 1 || tagD.Questions[1].LearnMore[0] != "This is simple subtraction" {
+		t.Errorf("Expected learn more 'This is simple subtraction', got %q", tagD.Questions[1].LearnMore)
 	}
 
 	// Check the first passage
@@ -1445,3 +1448,83 @@ func TestQuestionOrderResetsPerTag(t *testing.T) {
 		t.Errorf("Tag2 Q2: expected order=2 (reset), got %d", tag2.Questions[1].Order)
 	}
 }
+
+func TestApplyAnnotations(t *testing.T) {
+	annotated := tree.NewQuestion("What is velocity?", "Speed with direction", nil, nil, 1)
+	applyAnnotations(annotated, &preparser.Annotations{Difficulty: 4, Bloom: ontology.BloomLevelApply, Keywords: []string{"kinematics"}})
+	if annotated.Difficulty != 4 || annotated.DifficultyEstimated || annotated.BloomLevel != ontology.BloomLevelApply || len(annotated.Keywords) != 1 {
+		t.Errorf("annotated question = %+v", annotated)
+	}
+
+	trueFalse := tree.NewQuestion("The sky is blue", "True", nil, nil, 1)
+	trueFalse.QuestionType = ontology.QuestionTypeTrueFalse
+	ordering := tree.NewQuestion("Order the planets", "Mercury; Venus; Earth; Mars; Jupiter", nil, nil, 1)
+	ordering.QuestionType = ontology.QuestionTypeOrdering
+	ordering.StructuredAnswer = &tree.StructuredAnswer{Sequence: []string{"Mercury", "Venus", "Earth", "Mars", "Jupiter"}}
+	tests := []struct {
+		name       string
+		question   *tree.Question
+		bloomLevel ontology.BloomLevel
+		want       int
+	}{
+		{"short answer", tree.NewQuestion("Capital of France?", "Paris", nil, nil, 1), "", 2},
+		{"long answer", tree.NewQuestion("Why do seasons change?", "The axis of the Earth is tilted relative to its orbit around the Sun", nil, nil, 1), "", 4},
+		{"true/false", trueFalse, "", 1},
+		{"long ordering", ordering, "", 4},
+		{"annotated Bloom level", tree.NewQuestion("Compare mitosis and meiosis", "Mitosis copies, meiosis halves", nil, nil, 1), ontology.BloomLevelAnalyze, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var annotations *preparser.Annotations
+			if tt.bloomLevel != "" {
+				annotations = &preparser.Annotations{Bloom: tt.bloomLevel}
+			}
+			applyAnnotations(tt.question, annotations)
+			if tt.question.Difficulty != tt.want || !tt.question.DifficultyEstimated {
+				t.Errorf("Difficulty = %d (estimated: %v), want an estimated %d", tt.question.Difficulty, tt.question.DifficultyEstimated, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateDifficultyTrueFalseIsEasier(t *testing.T) {
+	trueFalse := tree.NewQuestion("Paris is the capital of France", "True", nil, nil, 1)
+	trueFalse.QuestionType = ontology.QuestionTypeTrueFalse
+	shortAnswer := tree.NewQuestion("What is the capital of France?", "Paris", nil, nil, 1)
+	if got, other := estimateDifficulty(trueFalse), estimateDifficulty(shortAnswer); got >= other {
+		t.Errorf("true/false difficulty = %d, want less than the short answer's %d", got, other)
+	}
+
+	// The step applies after the other adjustments
+	trueFalse.Prompt = strings.Repeat("word ", 26) + "is true"
+	if got := estimateDifficulty(trueFalse); got != 2 {
+		t.Errorf("long true/false difficulty = %d, want 2", got)
+	}
+}
+
+func TestApplyQuestionDetailsClassifiesAnswers(t *testing.T) {
+	q := tree.NewQuestion("In what year did the war end?", "1945", nil, nil, 1)
+	applyQuestionDetails(q, &preparser.QuestionResult{QuestionText: q.Prompt, AnswerText: q.Answer}, &parser.Node{})
+	if q.AnswerValue == nil || q.AnswerValue.Type != ontology.AnswerTypeDate || q.AnswerValue.Date != "1945" {
+		t.Errorf("AnswerValue = %+v, want the date 1945", q.AnswerValue)
+	}
+}
+
+func TestQuestionOrderQAIsOptIn(t *testing.T) {
+	hasOrderQA := func(metadata *config.Metadata) bool {
+		tree := tree.NewTree(metadata)
+		newQARunner(&parser.AbstractSyntaxTree{}, metadata).RunQAAndUpdate(tree)
+		for _, result := range tree.GetQAResults().Results {
+			if result.Name == "Question numbering should match order" {
+				return true
+			}
+		}
+		return false
+	}
+	if hasOrderQA(config.NewMetadata("test")) {
+		t.Error("the order QA should not run by default")
+	}
+	if !hasOrderQA(config.NewMetadata("test").WithOption("check_question_order", "true")) {
+		t.Error("the order QA should run with check_question_order")
+	}
+}
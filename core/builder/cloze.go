@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/parser"
+	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// buildClozeQuestions expands a cloze question into one question per cloze index.
+// Each prompt blanks the deletions of its index and reveals the others, and the
+// answer is the text of the blanked deletions.
+func buildClozeQuestions(question *preparser.QuestionResult, node *parser.Node, learnMore []string, questionOrder *int, settings buildSettings) []*tree.Question {
+	var questions []*tree.Question
+	for _, index := range clozeIndexes(question.Clozes) {
+		prompt, answers := renderCloze(question.QuestionText, index)
+
+		*questionOrder++
+		q := tree.NewQuestion(prompt, strings.Join(answers, constants.AnswerListDelimiter+" "), nil, learnMore, *questionOrder)
+		applyQuestionDetails(q, question, node)
+		q.Hash = tree.ClozeQuestionHash(question.QuestionText, index, settings.policy)
+		q.ClozeIndex = index
+		q.ClozeSource = question.QuestionText
+		q.StructuredAnswer = &tree.StructuredAnswer{Answers: answers}
+		questions = append(questions, q)
+	}
+	return questions
+}
+
+// clozeIndexes returns the distinct cloze indexes in ascending order
+func clozeIndexes(clozes []preparser.ClozeDeletion) []int {
+	seen := map[int]bool{}
+	var indexes []int
+	for _, cloze := range clozes {
+		if !seen[cloze.Index] {
+			seen[cloze.Index] = true
+			indexes = append(indexes, cloze.Index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// renderCloze renders sentence with the deletions of index blanked. It returns the
+// prompt and the text of the blanked deletions in the order they appear.
+func renderCloze(sentence string, index int) (string, []string) {
+	var answers []string
+	prompt := regexes.ClozeRegex.ReplaceAllStringFunc(sentence, func(deletion string) string {
+		parts := regexes.ClozeRegex.FindStringSubmatch(deletion)
+		text := strings.TrimSpace(parts[2])
+		if n, _ := strconv.Atoi(parts[1]); n != index {
+			return text
+		}
+		answers = append(answers, text)
+		if hint := strings.TrimSpace(parts[3]); hint != "" {
+			return "[" + hint + "]"
+		}
+		return constants.ClozeBlank
+	})
+	return prompt, answers
+}
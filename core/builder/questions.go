@@ -0,0 +1,87 @@
+package builder
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/answers"
+	"github.com/studyguides-com/study-guides-parser/core/lexer"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/parser"
+	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// buildQuestions creates the tree questions for a parsed question. A cloze question
+// becomes one question per cloze index and a template question one question per
+// instance; any other question becomes a single question. Each question created
+// advances questionOrder.
+func buildQuestions(question *preparser.QuestionResult, node *parser.Node, learnMore []string, questionOrder *int, settings buildSettings) []*tree.Question {
+	if node.Template != nil {
+		return buildTemplateQuestions(question, node, learnMore, questionOrder, settings)
+	}
+	if question.Type == ontology.QuestionTypeCloze {
+		return buildClozeQuestions(question, node, learnMore, questionOrder, settings)
+	}
+	*questionOrder++
+	q := tree.NewQuestion(question.QuestionText, question.AnswerText, nil, learnMore, *questionOrder)
+	q.Hash = tree.QuestionHash(question.QuestionText, question.AnswerText, settings.policy)
+	applyQuestionDetails(q, question, node)
+	return []*tree.Question{q}
+}
+
+// applyQuestionDetails copies everything beyond the prompt, answer and Learn More text
+// from a parsed question and its node's children onto q
+func applyQuestionDetails(q *tree.Question, question *preparser.QuestionResult, node *parser.Node) {
+	q.SourceLabel, q.SourceNumber = question.Label, question.Number
+	if question.Type != "" {
+		q.QuestionType = question.Type
+	}
+	q.StructuredAnswer = buildStructuredAnswer(question)
+	q.AcceptableAnswers, q.Tolerance = question.Alternatives, question.Tolerance
+	q.References = nodeReferences(node)
+	q.AuthorNotes = nodeAuthorNotes(node)
+	addQuestionCode(q, node)
+	applyAnnotations(q, question.Annotations)
+	q.AnswerValue = answers.Classify(q.Prompt, answerValue(q), q.QuestionType)
+}
+
+// answerValue returns the answer of q without the tolerance written after a numeric answer
+func answerValue(q *tree.Question) string {
+	if match := regexes.ToleranceRegex.FindStringSubmatch(q.Answer); match != nil && q.Tolerance > 0 {
+		return match[1]
+	}
+	return q.Answer
+}
+
+// buildStructuredAnswer converts the structured answer of a typed question, nil for short answers
+func buildStructuredAnswer(question *preparser.QuestionResult) *tree.StructuredAnswer {
+	switch question.Type {
+	case ontology.QuestionTypeTrueFalse:
+		return &tree.StructuredAnswer{Truth: question.Truth}
+	case ontology.QuestionTypeMultiSelect:
+		return &tree.StructuredAnswer{Answers: question.Items}
+	case ontology.QuestionTypeOrdering:
+		return &tree.StructuredAnswer{Sequence: question.Items}
+	case ontology.QuestionTypeMatching:
+		pairs := make([]tree.MatchPair, len(question.Pairs))
+		for i, pair := range question.Pairs {
+			pairs[i] = tree.MatchPair{Left: pair.Left, Right: pair.Right}
+		}
+		return &tree.StructuredAnswer{Pairs: pairs}
+	}
+	return nil
+}
+
+// addQuestionCode attaches the code blocks under a question node to its prompt or answer
+func addQuestionCode(q *tree.Question, node *parser.Node) {
+	for _, child := range node.Children {
+		if child.Type != lexer.TokenTypeCodeStart {
+			continue
+		}
+		code := buildCodeBlock(child)
+		if start := child.Data.GetCodeStart(); start != nil && start.Target == preparser.CodeTargetAnswer {
+			q.AnswerCode = append(q.AnswerCode, code)
+		} else {
+			q.PromptCode = append(q.PromptCode, code)
+		}
+	}
+}
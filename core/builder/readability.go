@@ -0,0 +1,34 @@
+package builder
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/readability"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// analyzeReadability scores the passages and tags of the tree at the reading speed of the
+// metadata. The processor rejects an invalid speed before the builder runs, so it falls
+// back to the default here.
+func analyzeReadability(t *tree.Tree, metadata *config.Metadata) {
+	speed, err := readability.ParseReadingSpeed(metadata.Options[constants.OptionReadingSpeed])
+	if err != nil {
+		speed = readability.DefaultReadingSpeed
+	}
+	t.AnalyzeReadability(speed)
+}
+
+// applyPassageMetadata copies a passage metadata directive onto the passage
+func applyPassageMetadata(p *tree.Passage, directive *preparser.DirectiveResult) {
+	switch directive.Name {
+	case constants.AuthorDirective:
+		p.Author = directive.Value
+	case constants.SourceDirective:
+		p.Source = directive.Value
+	case constants.YearDirective:
+		p.Year = directive.Number
+	case constants.GradeDirective:
+		p.GradeLevel = directive.Number
+	}
+}
@@ -0,0 +1,32 @@
+package builder
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/parser"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// nodeReferences returns the references found in a node's own line and in the
+// passage title, content and Learn More lines directly under it
+func nodeReferences(node *parser.Node) []reference.Reference {
+	references := append([]reference.Reference{}, node.Data.GetReferences()...)
+	for _, child := range node.Children {
+		references = append(references, child.Data.GetReferences()...)
+	}
+	if len(references) == 0 {
+		return nil
+	}
+	return references
+}
+
+// collectReferences drops the references that fail validation, using the URL schemes
+// allowed by the metadata, and collects the rest into the tree's bibliography
+func collectReferences(t *tree.Tree, metadata *config.Metadata) {
+	allowed := reference.ParseSchemes(metadata.Options[constants.OptionAllowedURLSchemes])
+	t.FilterReferences(func(r reference.Reference) bool {
+		return reference.Validate(r, allowed) == nil
+	})
+	t.CollectBibliography()
+}
@@ -0,0 +1,27 @@
+package builder
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/parser"
+	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// buildTemplateQuestions expands a template question into one question per instance.
+// The hash of each question depends on the template and the parameter values, so it
+// stays the same across builds with the same seed.
+func buildTemplateQuestions(question *preparser.QuestionResult, node *parser.Node, learnMore []string, questionOrder *int, settings buildSettings) []*tree.Question {
+	var questions []*tree.Question
+	for _, instance := range node.Template.Generate(settings.templateCount, settings.templateSeed) {
+		*questionOrder++
+		q := tree.NewQuestion(instance.Texts[0], instance.Texts[1], nil, learnMore, *questionOrder)
+		applyQuestionDetails(q, question, node)
+		if len(instance.Texts) > 2 {
+			q.AcceptableAnswers = instance.Texts[2:]
+		}
+		q.Hash = tree.TemplateQuestionHash(node.Template.Source, instance.Values, settings.policy)
+		q.TemplateSource = node.Template.Source
+		q.TemplateValues = instance.Values
+		questions = append(questions, q)
+	}
+	return questions
+}
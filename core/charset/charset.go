@@ -0,0 +1,183 @@
+// Package charset detects the encoding of a guide and decodes it to UTF-8 lines.
+// Guides exported from word processors often arrive as UTF-16 with a byte order mark
+// or as Windows-1252; both are transcoded so that the lexer sees plain UTF-8 text.
+package charset
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// Encoding names a text encoding detected by Detect
+type Encoding string
+
+const (
+	EncodingUTF8        Encoding = "utf-8"
+	EncodingUTF8BOM     Encoding = "utf-8-bom"
+	EncodingUTF16LE     Encoding = "utf-16le"
+	EncodingUTF16BE     Encoding = "utf-16be"
+	EncodingWindows1252 Encoding = "windows-1252"
+	// EncodingUnknown is content that is not text in any of the encodings above, such as a binary file
+	EncodingUnknown Encoding = "unknown"
+)
+
+// ErrNotText is returned by Decode for content whose encoding is EncodingUnknown
+var ErrNotText = errors.New("content is not UTF-8, UTF-16 or Windows-1252 text")
+
+// minNULRatio is the share of even or odd bytes that must be NUL for text without a
+// byte order mark to be taken as UTF-16. Mostly-ASCII UTF-16 has a NUL in every other byte.
+const minNULRatio = 0.3
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Detect guesses the encoding of content from its byte order mark, from the NUL bytes
+// of UTF-16 text without one, and otherwise from whether it is valid UTF-8. Content
+// that is not valid UTF-8 is taken as Windows-1252, the usual legacy encoding, when
+// every byte is a printable character there. Anything else is EncodingUnknown.
+func Detect(content []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return EncodingUTF16BE
+	}
+	if guess := guessUTF16(content); guess != "" {
+		return guess
+	}
+	if utf8.Valid(content) {
+		return EncodingUTF8
+	}
+	if isWindows1252(content) {
+		return EncodingWindows1252
+	}
+	return EncodingUnknown
+}
+
+// guessUTF16 returns the byte order of UTF-16 content without a byte order mark, or an
+// empty encoding. The NUL bytes only suggest UTF-16; binary content has them too, so the
+// guess is kept only when the content decodes to printable text.
+func guessUTF16(content []byte) Encoding {
+	if len(content) < 2 {
+		return ""
+	}
+	var even, odd int
+	for i, b := range content {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+	var guess Encoding
+	half := float64(len(content) / 2)
+	switch {
+	case float64(odd) >= half*minNULRatio && odd > even:
+		guess = EncodingUTF16LE
+	case float64(even) >= half*minNULRatio && even > odd:
+		guess = EncodingUTF16BE
+	default:
+		return ""
+	}
+	decoded, err := decoder(guess).Bytes(content)
+	if err != nil || !isPrintable(string(decoded)) {
+		return ""
+	}
+	return guess
+}
+
+// isPrintable reports whether text has only printable characters and line breaks, and no
+// replacement characters left by invalid input
+func isPrintable(text string) bool {
+	for _, r := range text {
+		if r == utf8.RuneError || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isWindows1252 reports whether every byte of content is a printable Windows-1252
+// character, a tab or a line break. The control bytes and the five bytes Windows-1252
+// leaves undefined do not appear in text.
+func isWindows1252(content []byte) bool {
+	for _, b := range content {
+		switch {
+		case b == '\t' || b == '\n' || b == '\r':
+		case b < 0x20 || b == 0x7F:
+			return false
+		case b == 0x81 || b == 0x8D || b == 0x8F || b == 0x90 || b == 0x9D:
+			return false
+		}
+	}
+	return true
+}
+
+// decoder returns the decoder for an encoding, or nil when content in it is already UTF-8
+func decoder(detected Encoding) *encoding.Decoder {
+	switch detected {
+	case EncodingUTF16LE:
+		return xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM).NewDecoder()
+	case EncodingUTF16BE:
+		return xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM).NewDecoder()
+	case EncodingWindows1252:
+		return charmap.Windows1252.NewDecoder()
+	}
+	return nil
+}
+
+// Decode transcodes content to UTF-8 from the encoding Detect finds. Byte order marks
+// are removed and CRLF and CR line endings become LF. Content that is not text returns
+// ErrNotText.
+func Decode(content []byte) (string, Encoding, error) {
+	detected := Detect(content)
+	if detected == EncodingUnknown {
+		return "", detected, ErrNotText
+	}
+	text := string(content)
+	if decoder := decoder(detected); decoder != nil {
+		decoded, err := decoder.Bytes(content)
+		if err != nil {
+			return "", detected, err
+		}
+		text = string(decoded)
+	}
+	text = strings.TrimPrefix(text, "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return text, detected, nil
+}
+
+// Lines decodes content and splits it into lines
+func Lines(content []byte) ([]string, Encoding, error) {
+	text, detected, err := Decode(content)
+	if err != nil {
+		return nil, detected, err
+	}
+	return strings.Split(text, "\n"), detected, nil
+}
+
+// ReadLines reads all of r, decodes it and splits it into lines
+func ReadLines(r io.Reader) ([]string, Encoding, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return Lines(content)
+}
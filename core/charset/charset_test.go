@@ -0,0 +1,96 @@
+package charset
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16Bytes encodes text as UTF-16, with a byte order mark when bom is set
+func utf16Bytes(text string, littleEndian bool, bom bool) []byte {
+	units := utf16.Encode([]rune(text))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	var out []byte
+	for _, u := range units {
+		if littleEndian {
+			out = append(out, byte(u), byte(u>>8))
+		} else {
+			out = append(out, byte(u>>8), byte(u))
+		}
+	}
+	return out
+}
+
+func TestDecode(t *testing.T) {
+	guide := "Guide\r\nCollege: A: B: C\r\n1. “Why?” - Because"
+	want := "Guide\nCollege: A: B: C\n1. “Why?” - Because"
+
+	tests := []struct {
+		name         string
+		content      []byte
+		wantEncoding Encoding
+		want         string
+	}{
+		{"utf-8", []byte(guide), EncodingUTF8, want},
+		{"utf-8 with a BOM", append([]byte{0xEF, 0xBB, 0xBF}, guide...), EncodingUTF8BOM, want},
+		{"utf-16le with a BOM", utf16Bytes(guide, true, true), EncodingUTF16LE, want},
+		{"utf-16be with a BOM", utf16Bytes(guide, false, true), EncodingUTF16BE, want},
+		{"utf-16le without a BOM", utf16Bytes(guide, true, false), EncodingUTF16LE, want},
+		{"windows-1252", []byte("Guide\r\n1. \x93Why?\x94 - It\x92s \x96 simple"), EncodingWindows1252, "Guide\n1. “Why?” - It’s – simple"},
+		{"old Mac line endings", []byte("Guide\rLine"), EncodingUTF8, "Guide\nLine"},
+		{"empty", nil, EncodingUTF8, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding, err := Decode(tt.content)
+			if err != nil {
+				t.Fatalf("Decode() error: %v", err)
+			}
+			if encoding != tt.wantEncoding {
+				t.Errorf("encoding = %s, want %s", encoding, tt.wantEncoding)
+			}
+			if got != tt.want {
+				t.Errorf("Decode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectBinary(t *testing.T) {
+	// Every other byte is NUL, as in UTF-16, but the other bytes are control characters
+	padded := []byte{0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0xFF, 0x00, 0x1B, 0x00, 0x07, 0x00}
+	tests := []struct {
+		name    string
+		content []byte
+		want    Encoding
+	}{
+		{"NUL-padded binary that is not UTF-8", padded, EncodingUnknown},
+		{"NUL-padded binary that is UTF-8", []byte{'a', 0x00, 0x01, 0x00, 0x02, 0x00}, EncodingUTF8},
+		{"undefined Windows-1252 byte", []byte("Guide\n1. \x81Why? - Because"), EncodingUnknown},
+		{"control bytes", []byte("Guide\x00\x01\xFF"), EncodingUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.content); got != tt.want {
+				t.Errorf("Detect() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, encoding, err := Decode(padded); err != ErrNotText || encoding != EncodingUnknown {
+		t.Errorf("Decode() = %s, %v, want %v", encoding, err, ErrNotText)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	lines, encoding, err := ReadLines(strings.NewReader("\uFEFFGuide\r\nSecond"))
+	if err != nil {
+		t.Fatalf("ReadLines() error: %v", err)
+	}
+	if encoding != EncodingUTF8BOM || len(lines) != 2 || lines[0] != "Guide" || lines[1] != "Second" {
+		t.Errorf("ReadLines() = %q, %s", lines, encoding)
+	}
+}
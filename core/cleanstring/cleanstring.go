@@ -7,6 +7,10 @@ package cleanstring
 import (
 	"strings"
 	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 // CleanString is a string type that implements text cleaning operations.
@@ -39,6 +43,18 @@ func (s CleanString) CleanLower() string {
 	return normalizeText(string(s), true)
 }
 
+// Comparable returns a form of the string for lenient comparison of answers.
+// In addition to Clean it lowercases the text, removes diacritics ("café" -> "cafe"),
+// replaces punctuation with spaces, collapses whitespace and drops a leading
+// English article ("The Nile" -> "nile"). The result is not meant for display.
+func (s CleanString) Comparable() string {
+	text := removeDiacritics(s.CleanLower())
+	text = strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}), " ")
+	return removeLeadingArticle(text)
+}
+
 // IsEmpty checks if the string is empty after trimming whitespace.
 // Returns true if the string contains only whitespace characters or is empty.
 func (s CleanString) IsEmpty() bool {
@@ -101,3 +117,57 @@ func removeInvisibleCharacters(text string) string {
 	}
 	return string(result)
 }
+
+// EditDistance returns the Levenshtein distance between a and b: the number of
+// single-rune insertions, deletions and substitutions that turn a into b.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// articles are dropped from the start of text by Comparable
+var articles = []string{"the", "an", "a"}
+
+// removeDiacritics decomposes text and removes the combining marks, so that
+// accented letters compare equal to their base letters
+func removeDiacritics(text string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, text)
+	if err != nil {
+		return text
+	}
+	return result
+}
+
+// removeLeadingArticle drops a leading "the", "an" or "a" unless it is the whole text
+func removeLeadingArticle(text string) string {
+	for _, article := range articles {
+		if rest, ok := strings.CutPrefix(text, article+" "); ok && rest != "" {
+			return rest
+		}
+	}
+	return text
+}
@@ -208,6 +208,59 @@ func TestCleanString_CleanLower(t *testing.T) {
 	}
 }
 
+func TestCleanString_Comparable(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    CleanString
+		expected string
+	}{
+		{
+			name:     "case and surrounding whitespace",
+			input:    CleanString("  Paris "),
+			expected: "paris",
+		},
+		{
+			name:     "diacritics",
+			input:    CleanString("Café Crème"),
+			expected: "cafe creme",
+		},
+		{
+			name:     "punctuation",
+			input:    CleanString("Washington, D.C."),
+			expected: "washington d c",
+		},
+		{
+			name:     "leading article",
+			input:    CleanString("The Nile"),
+			expected: "nile",
+		},
+		{
+			name:     "article alone is kept",
+			input:    CleanString("A"),
+			expected: "a",
+		},
+		{
+			name:     "article inside the text is kept",
+			input:    CleanString("Gone with the Wind"),
+			expected: "gone with the wind",
+		},
+		{
+			name:     "invisible characters",
+			input:    CleanString("an\u200B apple"),
+			expected: "apple",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.input.Comparable()
+			if result != tt.expected {
+				t.Errorf("Comparable() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestCleanString_IsEmpty(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -523,3 +576,20 @@ func TestNormalizeText(t *testing.T) {
 		})
 	}
 }
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package cleanstring
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Form is a Unicode normalization form applied by a Policy
+type Form string
+
+const (
+	FormNone Form = ""     // Leave the text as written
+	FormNFC  Form = "nfc"  // Compose characters, so "e" plus a combining accent becomes "é"
+	FormNFKC Form = "nfkc" // Compose and replace compatibility characters, so "ﬁ" becomes "fi"
+)
+
+// Policy names and settings accepted by ParsePolicy
+const (
+	PolicyLegacy          = "legacy"
+	PolicyStandard        = "standard"
+	PolicyConvertSpaces   = "convert_spaces"
+	PolicyCollapseSpaces  = "collapse_spaces"
+	PolicyFoldPunctuation = "fold_punctuation"
+	PolicyKeepCase        = "keep_case"
+)
+
+// Policy says how text is normalised before it is classified, stored and hashed.
+// Apply runs before Clean, which still trims the text and removes invisible characters.
+type Policy struct {
+	Form            Form // Unicode normalization form, none by default
+	ConvertSpaces   bool // Turn space separators such as non-breaking spaces into spaces instead of letting Clean remove them
+	CollapseSpaces  bool // Replace each run of whitespace with one space
+	FoldPunctuation bool // Turn curly quotes into straight quotes and dashes into hyphens
+	KeepCase        bool // Keep the case of keys instead of lowercasing them
+}
+
+// LegacyPolicy leaves text as written and keeps the case of keys. It is the default,
+// so that hashes stay the same as before policies were introduced.
+var LegacyPolicy = Policy{KeepCase: true}
+
+// StandardPolicy makes text typed in different editors compare and hash the same
+var StandardPolicy = Policy{
+	Form:            FormNFC,
+	ConvertSpaces:   true,
+	CollapseSpaces:  true,
+	FoldPunctuation: true,
+	KeepCase:        true,
+}
+
+// punctuationFolds maps curly quotes and dashes to their ASCII forms
+var punctuationFolds = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+)
+
+// Apply normalises text according to the policy. LegacyPolicy returns the text unchanged.
+func (p Policy) Apply(text string) string {
+	switch p.Form {
+	case FormNFC:
+		text = norm.NFC.String(text)
+	case FormNFKC:
+		text = norm.NFKC.String(text)
+	}
+	if p.ConvertSpaces {
+		text = strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Zs, r) {
+				return ' '
+			}
+			return r
+		}, text)
+	}
+	if p.FoldPunctuation {
+		text = punctuationFolds.Replace(text)
+	}
+	if p.CollapseSpaces {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	return text
+}
+
+// Key returns the form of text that is hashed: the cleaned text after Apply,
+// lowercased unless the policy keeps case
+func (p Policy) Key(text string) string {
+	return normalizeText(p.Apply(text), !p.KeepCase)
+}
+
+// ParsePolicy resolves a configuration value to a policy. The value is a comma-separated
+// list of policy names ("legacy" or "standard") and settings ("nfc", "nfkc",
+// "convert_spaces", "collapse_spaces", "fold_punctuation" and "keep_case"), applied in
+// order to a policy with no settings (e.g. "standard,nfkc" or "nfkc,convert_spaces").
+// An empty value resolves to LegacyPolicy.
+func ParsePolicy(value string) (Policy, error) {
+	if strings.TrimSpace(value) == "" {
+		return LegacyPolicy, nil
+	}
+	var policy Policy
+	for _, name := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case PolicyLegacy:
+			policy = LegacyPolicy
+		case PolicyStandard:
+			policy = StandardPolicy
+		case string(FormNFC):
+			policy.Form = FormNFC
+		case string(FormNFKC):
+			policy.Form = FormNFKC
+		case PolicyConvertSpaces:
+			policy.ConvertSpaces = true
+		case PolicyCollapseSpaces:
+			policy.CollapseSpaces = true
+		case PolicyFoldPunctuation:
+			policy.FoldPunctuation = true
+		case PolicyKeepCase:
+			policy.KeepCase = true
+		default:
+			return Policy{}, fmt.Errorf("unknown normalization setting %q", strings.TrimSpace(name))
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,77 @@
+//go:build !prod
+
+package cleanstring
+
+import (
+	"testing"
+)
+
+func TestPolicy_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		input    string
+		expected string
+	}{
+		{"legacy leaves text as written", LegacyPolicy, "New\u00A0York “quoted” — e\u0301", "New\u00A0York “quoted” — e\u0301"},
+		{"nfc composes accents", Policy{Form: FormNFC}, "cafe\u0301", "café"},
+		{"nfkc replaces ligatures", Policy{Form: FormNFKC}, "ﬁle", "file"},
+		{"non-breaking space becomes a space", Policy{ConvertSpaces: true}, "New\u00A0York", "New York"},
+		{"collapse spaces", Policy{CollapseSpaces: true}, "  a \t b  ", "a b"},
+		{"fold quotes", Policy{FoldPunctuation: true}, "“It’s”", "\"It's\""},
+		{"fold dashes", Policy{FoldPunctuation: true}, "What? — Paris – France", "What? - Paris - France"},
+		{"standard", StandardPolicy, " New\u00A0\u00A0York’s  cafe\u0301 ", "New York's café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Apply(tt.input); got != tt.expected {
+				t.Errorf("Apply(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPolicy_Key(t *testing.T) {
+	// Without a policy a non-breaking space is removed and merges the words
+	if got := New("New\u00A0York").Clean(); got != "NewYork" {
+		t.Errorf("Clean() = %q, want %q", got, "NewYork")
+	}
+	if got := StandardPolicy.Key("New\u00A0York"); got != "New York" {
+		t.Errorf("StandardPolicy.Key() = %q, want %q", got, "New York")
+	}
+	if got := (Policy{ConvertSpaces: true}).Key(" New\u00A0York "); got != "new york" {
+		t.Errorf("Key() without keep_case = %q, want %q", got, "new york")
+	}
+	if StandardPolicy.Key("cafe\u0301") != StandardPolicy.Key("café") {
+		t.Error("StandardPolicy.Key() should match decomposed and composed text")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Policy
+		wantErr bool
+	}{
+		{"", LegacyPolicy, false},
+		{"legacy", LegacyPolicy, false},
+		{" Standard ", StandardPolicy, false},
+		{"standard,nfkc", Policy{Form: FormNFKC, ConvertSpaces: true, CollapseSpaces: true, FoldPunctuation: true, KeepCase: true}, false},
+		{"nfc,convert_spaces", Policy{Form: FormNFC, ConvertSpaces: true}, false},
+		{"collapse_spaces, fold_punctuation, keep_case", Policy{CollapseSpaces: true, FoldPunctuation: true, KeepCase: true}, false},
+		{"nfd", Policy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePolicy(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package conditions selects the lines of a guide that belong to an edition. A block
+// between "@if" and "@end" is kept when its condition holds for the options the guide is
+// built with, and blocks may be nested:
+//
+//	@if edition == "teacher"
+//	1. What do students usually get wrong here? - Units
+//	@end
+//
+// A condition compares an option with a value using "==" or "!=". An option that is not
+// set compares as empty.
+package conditions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// Operator compares an option with a value
+type Operator string
+
+const (
+	OperatorEqual    Operator = "=="
+	OperatorNotEqual Operator = "!="
+)
+
+// Condition is the test of an "@if" directive (e.g. edition == "teacher")
+type Condition struct {
+	Option   string
+	Operator Operator
+	Value    string
+}
+
+// Parse reads the value of an "@if" directive. The value may be written in double quotes.
+func Parse(text string) (*Condition, *ConditionError) {
+	match := regexes.ConditionRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return nil, NewConditionError(CodeInvalidCondition,
+			fmt.Sprintf("invalid condition %q, expected \"option == \\\"value\\\"\" or \"option != \\\"value\\\"\"", text), 0)
+	}
+	value := match[3]
+	if strings.HasPrefix(value, `"`) {
+		value = strings.Trim(value, `"`)
+	}
+	return &Condition{Option: match[1], Operator: Operator(match[2]), Value: value}, nil
+}
+
+// Evaluate reports whether the condition holds for the given options
+func (c *Condition) Evaluate(options map[string]string) bool {
+	equal := options[c.Option] == c.Value
+	if c.Operator == OperatorNotEqual {
+		return !equal
+	}
+	return equal
+}
+
+// String returns the condition as written in a guide
+func (c *Condition) String() string {
+	return fmt.Sprintf("%s %s %q", c.Option, c.Operator, c.Value)
+}
+
+// Block is an "@if" block that is still open
+type Block struct {
+	Condition *Condition
+	Line      int  // The line of the "@if" directive
+	Included  bool // Whether the lines of the block are kept, which needs every enclosing block to be kept too
+}
+
+// Stack tracks the open blocks while the lines of a guide are read in order
+type Stack struct {
+	options map[string]string
+	blocks  []Block
+}
+
+// NewStack creates a stack that evaluates conditions against the given options
+func NewStack(options map[string]string) *Stack {
+	return &Stack{options: options}
+}
+
+// If opens a block on the given line. A nil condition, which could not be parsed, keeps
+// the lines of its block so that later errors are still reported.
+func (s *Stack) If(condition *Condition, line int) {
+	included := s.Included() && (condition == nil || condition.Evaluate(s.options))
+	s.blocks = append(s.blocks, Block{Condition: condition, Line: line, Included: included})
+}
+
+// End closes the innermost open block
+func (s *Stack) End(line int) *ConditionError {
+	if len(s.blocks) == 0 {
+		return NewConditionError(CodeUnmatchedEnd, "@end has no matching @if", line)
+	}
+	s.blocks = s.blocks[:len(s.blocks)-1]
+	return nil
+}
+
+// Included reports whether the lines read now are kept
+func (s *Stack) Included() bool {
+	return len(s.blocks) == 0 || s.blocks[len(s.blocks)-1].Included
+}
+
+// Unclosed reports the blocks that are still open, outermost first. Call it after the last line.
+func (s *Stack) Unclosed() []*ConditionError {
+	var errs []*ConditionError
+	for _, block := range s.blocks {
+		message := "@if has no matching @end"
+		if block.Condition != nil {
+			message = fmt.Sprintf("@if %s has no matching @end", block.Condition)
+		}
+		errs = append(errs, NewConditionError(CodeUnclosedBlock, message, block.Line))
+	}
+	return errs
+}
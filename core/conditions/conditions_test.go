@@ -0,0 +1,90 @@
+package conditions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want *Condition
+	}{
+		{`edition == "teacher"`, &Condition{Option: "edition", Operator: OperatorEqual, Value: "teacher"}},
+		{` tier!=premium `, &Condition{Option: "tier", Operator: OperatorNotEqual, Value: "premium"}},
+		{`edition == ""`, &Condition{Option: "edition", Operator: OperatorEqual, Value: ""}},
+		{`edition == "teacher edition"`, &Condition{Option: "edition", Operator: OperatorEqual, Value: "teacher edition"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.text)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.text, got, err, tt.want)
+		}
+	}
+	for _, text := range []string{"", "edition", `edition = "teacher"`, "edition == teacher edition", `== "teacher"`} {
+		if _, err := Parse(text); err == nil || err.Code != CodeInvalidCondition {
+			t.Errorf("Parse(%q) error = %v, want %s", text, err, CodeInvalidCondition)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	options := map[string]string{"edition": "teacher"}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{`edition == "teacher"`, true},
+		{`edition == "student"`, false},
+		{`edition != "student"`, true},
+		{`tier == "premium"`, false},
+		{`tier == ""`, true},
+	}
+	for _, tt := range tests {
+		condition, err := Parse(tt.text)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.text, err)
+		}
+		if got := condition.Evaluate(options); got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStack(t *testing.T) {
+	stack := NewStack(map[string]string{"edition": "teacher", "tier": "free"})
+	teacher, _ := Parse(`edition == "teacher"`)
+	premium, _ := Parse(`tier == "premium"`)
+
+	stack.If(teacher, 2)
+	if !stack.Included() {
+		t.Error("lines in a block whose condition holds should be kept")
+	}
+	stack.If(premium, 3)
+	if stack.Included() {
+		t.Error("lines in a block whose condition fails should be dropped")
+	}
+	stack.If(teacher, 4)
+	if stack.Included() {
+		t.Error("lines nested in a dropped block should be dropped")
+	}
+	for _, line := range []int{5, 6} {
+		if err := stack.End(line); err != nil {
+			t.Fatalf("End(%d) error: %v", line, err)
+		}
+	}
+	if !stack.Included() {
+		t.Error("lines after a dropped block should be kept again")
+	}
+
+	unclosed := stack.Unclosed()
+	if len(unclosed) != 1 || unclosed[0].Code != CodeUnclosedBlock || unclosed[0].Line != 2 {
+		t.Errorf("Unclosed() = %v, want the block opened on line 2", unclosed)
+	}
+	if err := stack.End(7); err != nil {
+		t.Fatalf("End(7) error: %v", err)
+	}
+	if err := stack.End(8); err == nil || err.Code != CodeUnmatchedEnd || err.Line != 8 {
+		t.Errorf("End(8) error = %v, want %s on line 8", err, CodeUnmatchedEnd)
+	}
+}
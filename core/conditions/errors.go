@@ -0,0 +1,33 @@
+package conditions
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeInvalidCondition ErrorCode = "INVALID_CONDITION"
+	CodeUnmatchedEnd     ErrorCode = "UNMATCHED_END"
+	CodeUnclosedBlock    ErrorCode = "UNCLOSED_BLOCK"
+)
+
+// ConditionError reports a malformed condition or an unbalanced block
+type ConditionError struct {
+	Message string
+	Code    ErrorCode
+	Line    int // The line of the unbalanced directive; zero for a malformed condition, whose caller knows the line
+}
+
+// Error implements the error interface
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("%s (line: %d)", e.Message, e.Line)
+}
+
+// NewConditionError creates a new condition error with the given code and message
+func NewConditionError(code ErrorCode, message string, line int) *ConditionError {
+	return &ConditionError{
+		Message: message,
+		Code:    code,
+		Line:    line,
+	}
+}
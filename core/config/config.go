@@ -4,9 +4,15 @@ import "github.com/studyguides-com/study-guides-parser/core/ontology"
 
 // Metadata contains configuration and metadata for parsing
 type Metadata struct {
-	Type        string               `json:"type"`
-	Options     map[string]string    `json:"options,omitempty"`
-	ContextType ontology.ContextType `json:"context_type,omitempty"`
+	Type          string                     `json:"type"`
+	Options       map[string]string          `json:"options,omitempty"`
+	ContextType   ontology.ContextType       `json:"context_type,omitempty"`
+	ContentRating ontology.ContentRatingType `json:"content_rating,omitempty"`
+	Language      string                     `json:"language,omitempty"`
+	Author        string                     `json:"author,omitempty"`
+	FormatVersion string                     `json:"format_version,omitempty"`
+	Encoding      string                     `json:"encoding,omitempty"`  // The detected encoding of a guide read from a file or reader
+	Variables     map[string]string          `json:"variables,omitempty"` // Values of the "{{name}}" references in guides of format 2 and later
 }
 
 // NewMetadata creates a new Metadata struct with the given type
@@ -22,3 +28,19 @@ func (m *Metadata) WithOption(key, value string) *Metadata {
 	m.Options[key] = value
 	return m
 }
+
+// Clone returns a copy of the metadata that can be modified without affecting the original
+func (m *Metadata) Clone() *Metadata {
+	clone := *m
+	clone.Options = make(map[string]string, len(m.Options))
+	for key, value := range m.Options {
+		clone.Options[key] = value
+	}
+	if m.Variables != nil {
+		clone.Variables = make(map[string]string, len(m.Variables))
+		for name, value := range m.Variables {
+			clone.Variables[name] = value
+		}
+	}
+	return &clone
+}
@@ -34,4 +34,93 @@ const (
 
 	// LearnMorePrefix is the prefix for learn more lines
 	LearnMorePrefix = "learn more:"
+
+	// AnswerListDelimiter separates the answers of multi-select, ordering and matching questions
+	AnswerListDelimiter = ";"
+
+	// MatchPairDelimiter separates the two sides of a matching pair (e.g. "France = Paris")
+	MatchPairDelimiter = "="
+
+	// AlternativeAnswerDelimiter separates the acceptable answers of a short-answer question
+	// (e.g. "Paris | City of Light"). The first answer is the one shown.
+	AlternativeAnswerDelimiter = " | "
+
+	// ClozeBlank replaces a cloze deletion in a generated prompt when it has no hint
+	ClozeBlank = "[...]"
+)
+
+// Metadata option keys
+const (
+	// OptionListItemPrefixes selects the list item prefixes accepted for questions.
+	// The value is "default", "extended" or a comma-separated list of prefix names.
+	OptionListItemPrefixes = "list_item_prefixes"
+
+	// OptionAllowedURLSchemes lists the URL schemes accepted in references, separated by commas
+	OptionAllowedURLSchemes = "allowed_url_schemes"
+
+	// OptionNormalization selects the normalisation policy for text and hashes (see cleanstring.ParsePolicy)
+	OptionNormalization = "normalization"
+
+	// OptionKeepComments keeps comments as author notes on the node that follows them when "true"
+	OptionKeepComments = "keep_comments"
+
+	// OptionExportAuthorNotes keeps comments as author notes and adds them to the tree's output when "true"
+	OptionExportAuthorNotes = "export_author_notes"
+
+	// OptionCheckQuestionOrder reports question numbers that differ from the computed order when "true"
+	OptionCheckQuestionOrder = "check_question_order"
+
+	// OptionTemplateInstances sets how many questions each template question generates (see templates.ParseCount)
+	OptionTemplateInstances = "template_instances"
+
+	// OptionTemplateSeed seeds the choice of template parameter values; the same seed gives the same questions
+	OptionTemplateSeed = "template_seed"
+
+	// OptionReadingSpeed sets the words per minute used to estimate reading times (see readability.ParseReadingSpeed)
+	OptionReadingSpeed = "reading_speed"
+
+	// DefaultAllowedURLSchemes is used when OptionAllowedURLSchemes is not set
+	DefaultAllowedURLSchemes = "http,https"
+)
+
+// Directive constants
+const (
+	// IncludeDirective is the prefix of lines that insert another file (e.g. "@include part.txt")
+	IncludeDirective = "@include"
+
+	// DirectivePrefix starts the directive lines the lexer classifies (e.g. "@context: APExams")
+	DirectivePrefix = "@"
+
+	// ContextDirective sets the context type of the headers that follow it (e.g. "@context: APExams")
+	ContextDirective = "context"
+
+	// FormatDirective declares the version of the input format a guide is written in (e.g. "@format: 2")
+	FormatDirective = "format"
+
+	// DefineDirective defines a variable that "{{name}}" expands to (e.g. "@define course = BIO 101")
+	DefineDirective = "define"
+
+	// IfDirective starts a block that is kept only when its condition holds (e.g. "@if edition == \"teacher\"")
+	IfDirective = "if"
+
+	// EndDirective closes the innermost "@if" block
+	EndDirective = "end"
+
+	// ParamDirective declares a parameter of the template question that follows (e.g. "@param a in 1..20")
+	ParamDirective = "param"
+
+	// AuthorDirective names the author of the passage it follows (e.g. "@author: Mark Twain")
+	AuthorDirective = "author"
+
+	// SourceDirective names the work the passage it follows is taken from (e.g. "@source: Life on the Mississippi")
+	SourceDirective = "source"
+
+	// YearDirective gives the year the passage it follows was published (e.g. "@year: 1883")
+	YearDirective = "year"
+
+	// GradeDirective gives the school grade the passage it follows is meant for (e.g. "@grade: 8")
+	GradeDirective = "grade"
+
+	// DefaultMaxIncludeDepth is how deeply included files may themselves include other files
+	DefaultMaxIncludeDepth = 8
 )
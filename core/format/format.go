@@ -0,0 +1,119 @@
+// Package format versions the input grammar of study guides. A guide declares the version
+// it is written in with a "@format: 2" directive after its file header, or with the
+// format_version front matter key; guides without a declaration are read as Version1.
+// Syntax added in a later version is only recognised in guides that declare it, so
+// older guides keep parsing as they always have.
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+)
+
+// Version is a version of the input grammar
+type Version int
+
+const (
+	// Version1 is the grammar of guides that do not declare a version
+	Version1 Version = 1
+	// Version2 reads every "@name" line as a directive, rejects unknown directives and adds
+	// question types, cloze questions, code blocks, acceptable answers, "@define" variables,
+	// "@if" blocks, "@param" template questions, passage metadata and question annotations
+	Version2 Version = 2
+
+	// Default is the version of guides that do not declare one
+	Default = Version1
+	// Latest is the newest version this parser understands
+	Latest = Version2
+)
+
+// Feature is a part of the grammar that is switched on from some version
+type Feature string
+
+const (
+	// FeatureStrictDirectives reads every line starting with "@" and a name as a directive,
+	// so that a misspelled directive is an error instead of content
+	FeatureStrictDirectives Feature = "strict_directives"
+	// FeatureAcceptableAnswers splits short answers on " | " into acceptable answers and
+	// reads a tolerance such as "± 0.1" after a numeric answer
+	FeatureAcceptableAnswers Feature = "acceptable_answers"
+	// FeatureVariables expands "{{name}}" references to variables defined with "@define"
+	FeatureVariables Feature = "variables"
+	// FeatureConditionals keeps the lines between "@if" and "@end" only when the condition holds
+	FeatureConditionals Feature = "conditionals"
+	// FeatureTemplates generates questions from a question whose "@param" directives declare parameters
+	FeatureTemplates Feature = "templates"
+	// FeaturePassageMetadata reads "@author", "@source", "@year" and "@grade" after a passage line
+	FeaturePassageMetadata Feature = "passage_metadata"
+	// FeatureAnnotations reads a "{key: value}" annotation at the end of a question line
+	FeatureAnnotations Feature = "annotations"
+	// FeatureQuestionTypes reads a type tag such as "[tf]" or "[multi]" at the start of a
+	// prompt, with the structured answer of its type
+	FeatureQuestionTypes Feature = "question_types"
+	// FeatureCloze reads "{{c1::text}}" deletions as cloze questions, which need no answer delimiter
+	FeatureCloze Feature = "cloze"
+	// FeatureCodeBlocks passes the lines between "```" fences through as a code block
+	FeatureCodeBlocks Feature = "code_blocks"
+)
+
+// features maps each feature to the version that introduced it
+var features = map[Feature]Version{
+	FeatureStrictDirectives:  Version2,
+	FeatureAcceptableAnswers: Version2,
+	FeatureVariables:         Version2,
+	FeatureConditionals:      Version2,
+	FeatureTemplates:         Version2,
+	FeaturePassageMetadata:   Version2,
+	FeatureAnnotations:       Version2,
+	FeatureQuestionTypes:     Version2,
+	FeatureCloze:             Version2,
+	FeatureCodeBlocks:        Version2,
+}
+
+// directives maps each directive name to the version that introduced it
+var directives = map[string]Version{
+	constants.ContextDirective: Version1,
+	constants.FormatDirective:  Version1,
+	constants.DefineDirective:  Version2,
+	constants.IfDirective:      Version2,
+	constants.EndDirective:     Version2,
+	constants.ParamDirective:   Version2,
+	constants.AuthorDirective:  Version2,
+	constants.SourceDirective:  Version2,
+	constants.YearDirective:    Version2,
+	constants.GradeDirective:   Version2,
+}
+
+// Parse reads a version number such as "2". An empty value is the Default version.
+func Parse(value string) (Version, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Default, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < int(Version1) || number > int(Latest) {
+		return 0, fmt.Errorf("unknown format version %q, expected a version from %d to %d", value, Version1, Latest)
+	}
+	return Version(number), nil
+}
+
+// Supports reports whether the feature is switched on in this version
+func (v Version) Supports(feature Feature) bool {
+	since, ok := features[feature]
+	return ok && v >= since
+}
+
+// Directive returns the version that introduced the named directive, and false for
+// names that are not directives
+func Directive(name string) (Version, bool) {
+	since, ok := directives[strings.ToLower(name)]
+	return since, ok
+}
+
+// String returns the version number
+func (v Version) String() string {
+	return strconv.Itoa(int(v))
+}
@@ -0,0 +1,47 @@
+package format
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Version
+		wantErr bool
+	}{
+		{"", Default, false},
+		{"1", Version1, false},
+		{" 2 ", Version2, false},
+		{"0", 0, true},
+		{"3", 0, true},
+		{"two", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Parse(%q) = %v, %v, want %v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSupportsAndDirectives(t *testing.T) {
+	if Version1.Supports(FeatureStrictDirectives) || !Version2.Supports(FeatureStrictDirectives) {
+		t.Error("strict directives should be switched on from Version2")
+	}
+	if Latest.Supports("no_such_feature") {
+		t.Error("unknown features are never supported")
+	}
+	if since, ok := Directive("Context"); !ok || since != Version1 {
+		t.Errorf("Directive(Context) = %v, %v, want %v, true", since, ok, Version1)
+	}
+	for _, name := range []string{"define", "if", "end", "param", "author", "source", "year", "grade"} {
+		if since, ok := Directive(name); !ok || since != Version2 {
+			t.Errorf("Directive(%s) = %v, %v, want %v, true", name, since, ok, Version2)
+		}
+	}
+	if _, ok := Directive("teacher"); ok {
+		t.Error("Directive(teacher) should not be known")
+	}
+}
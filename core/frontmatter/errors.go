@@ -0,0 +1,34 @@
+package frontmatter
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeInvalidFrontMatter  ErrorCode = "INVALID_FRONT_MATTER"
+	CodeUnclosedFrontMatter ErrorCode = "UNCLOSED_FRONT_MATTER"
+)
+
+// FrontMatterError represents a front matter error with its source line
+type FrontMatterError struct {
+	Message string
+	Code    ErrorCode
+	Line    int    // Line number in the original source
+	Text    string // The source line the error refers to
+}
+
+// Error implements the error interface
+func (e *FrontMatterError) Error() string {
+	return fmt.Sprintf("%s (line: %d)", e.Message, e.Line)
+}
+
+// NewFrontMatterError creates a new front matter error with the given code, message and line
+func NewFrontMatterError(code ErrorCode, message string, line int, text string) *FrontMatterError {
+	return &FrontMatterError{
+		Message: message,
+		Code:    code,
+		Line:    line,
+		Text:    text,
+	}
+}
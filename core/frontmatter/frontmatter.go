@@ -0,0 +1,221 @@
+// Package frontmatter extracts optional YAML front matter from the top of a study guide.
+// Front matter lets a guide declare what it is (context type, content rating, language, ...)
+// instead of relying only on the caller's config.Metadata.
+//
+//	---
+//	context_type: APExams
+//	content_rating: Everyone
+//	language: en
+//	---
+//	AP Biology Study Guide
+//	...
+package frontmatter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/schema"
+	"github.com/studyguides-com/study-guides-parser/core/variables"
+)
+
+const (
+	// Delimiter opens and closes the front matter block
+	Delimiter = "---"
+	// EndDelimiter is the YAML document end marker, also accepted as a closing line
+	EndDelimiter = "..."
+)
+
+// FrontMatter is the typed content of a front matter block
+type FrontMatter struct {
+	ContextType   ontology.ContextType       `yaml:"context_type" json:"context_type,omitempty"`
+	ContentRating ontology.ContentRatingType `yaml:"content_rating" json:"content_rating,omitempty"`
+	Language      string                     `yaml:"language" json:"language,omitempty"`
+	Author        string                     `yaml:"author" json:"author,omitempty"`
+	SchemaVersion string                     `yaml:"schema_version" json:"schema_version,omitempty"`
+	FormatVersion string                     `yaml:"format_version" json:"format_version,omitempty"`
+	Options       map[string]string          `yaml:"options" json:"options,omitempty"`
+	Variables     map[string]string          `yaml:"variables" json:"variables,omitempty"`
+}
+
+// Document is a guide split into its front matter and body
+type Document struct {
+	FrontMatter *FrontMatter // nil when the guide has no front matter
+	Body        []string     // The lines after the front matter, starting with the file header
+	BodyStart   int          // Line number of the first body line in the original source
+}
+
+// LineOffset returns the number of source lines that precede the body
+func (d *Document) LineOffset() int {
+	return d.BodyStart - 1
+}
+
+// Split separates the front matter from the body of a guide. A guide has front matter
+// only when its first line is "---"; the block ends at the next "---" or "..." line.
+// Guides without front matter are returned unchanged as the body.
+func Split(lines []string) (*Document, *FrontMatterError) {
+	if len(lines) == 0 || !isDelimiter(lines[0], Delimiter) {
+		return &Document{Body: lines, BodyStart: 1}, nil
+	}
+
+	closing := -1
+	for i := 1; i < len(lines); i++ {
+		if isDelimiter(lines[i], Delimiter) || isDelimiter(lines[i], EndDelimiter) {
+			closing = i
+			break
+		}
+	}
+	if closing == -1 {
+		return nil, NewFrontMatterError(CodeUnclosedFrontMatter, "front matter is not closed with '---'", 1, lines[0])
+	}
+
+	frontMatter, err := parse(lines[1:closing])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		FrontMatter: frontMatter,
+		Body:        lines[closing+1:],
+		BodyStart:   closing + 2,
+	}, nil
+}
+
+// yamlLineRegex finds the line number in yaml.v3 error messages
+var yamlLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// parse decodes and validates the YAML between the delimiters. yamlLines starts on source line 2.
+func parse(yamlLines []string) (*FrontMatter, *FrontMatterError) {
+	frontMatter := &FrontMatter{}
+	source := strings.Join(yamlLines, "\n")
+	if strings.TrimSpace(source) == "" {
+		return frontMatter, nil
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(source))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(frontMatter); err != nil {
+		line := 1
+		if match := yamlLineRegex.FindStringSubmatch(err.Error()); match != nil {
+			if n, convErr := strconv.Atoi(match[1]); convErr == nil {
+				line = n + 1
+			}
+		}
+		text := ""
+		if line-2 >= 0 && line-2 < len(yamlLines) {
+			text = yamlLines[line-2]
+		}
+		message := strings.ReplaceAll(err.Error(), "\n", " ")
+		return nil, NewFrontMatterError(CodeInvalidFrontMatter, fmt.Sprintf("invalid front matter: %s", message), line, text)
+	}
+
+	return frontMatter, frontMatter.validate(yamlLines)
+}
+
+// validate checks enumerated values and the declared format and schema versions
+func (f *FrontMatter) validate(yamlLines []string) *FrontMatterError {
+	if f.ContextType != "" {
+		contextType, ok := ontology.ParseContextType(string(f.ContextType))
+		if !ok {
+			return f.keyError(yamlLines, "context_type", fmt.Sprintf("unknown context_type %q", f.ContextType))
+		}
+		f.ContextType = contextType
+	}
+	if f.ContentRating != "" {
+		rating, ok := ontology.ParseContentRating(string(f.ContentRating))
+		if !ok {
+			return f.keyError(yamlLines, "content_rating", fmt.Sprintf("unknown content_rating %q", f.ContentRating))
+		}
+		f.ContentRating = rating
+	}
+	if f.FormatVersion != "" {
+		if _, err := format.Parse(f.FormatVersion); err != nil {
+			return f.keyError(yamlLines, "format_version", err.Error())
+		}
+	}
+	for name := range f.Variables {
+		if !variables.ValidName(name) {
+			return f.keyError(yamlLines, "variables", fmt.Sprintf("invalid variable name %q", name))
+		}
+	}
+	if f.SchemaVersion != "" && majorVersion(f.SchemaVersion) != majorVersion(schema.Version) {
+		return f.keyError(yamlLines, "schema_version",
+			fmt.Sprintf("schema_version %q is not supported (current schema version is %s)", f.SchemaVersion, schema.Version))
+	}
+	return nil
+}
+
+// keyError creates an error pointing at the source line that declares key
+func (f *FrontMatter) keyError(yamlLines []string, key string, message string) *FrontMatterError {
+	for i, line := range yamlLines {
+		if strings.HasPrefix(strings.TrimSpace(line), key+":") {
+			return NewFrontMatterError(CodeInvalidFrontMatter, message, i+2, line)
+		}
+	}
+	return NewFrontMatterError(CodeInvalidFrontMatter, message, 1, Delimiter)
+}
+
+// Apply merges the front matter into metadata and returns the result as a new Metadata.
+// Values supplied by the caller take precedence: front matter only fills fields that are
+// empty in metadata, and only adds options and variables the caller has not set.
+// A nil front matter returns metadata unchanged.
+func (f *FrontMatter) Apply(metadata *config.Metadata) *config.Metadata {
+	if f == nil {
+		return metadata
+	}
+
+	var merged *config.Metadata
+	if metadata == nil {
+		merged = config.NewMetadata("")
+	} else {
+		merged = metadata.Clone()
+	}
+
+	if merged.ContextType == "" && f.ContextType != "" {
+		merged.ContextType = f.ContextType
+	}
+	if merged.ContentRating == "" && f.ContentRating != "" {
+		merged.ContentRating = f.ContentRating
+	}
+	if merged.Language == "" {
+		merged.Language = f.Language
+	}
+	if merged.Author == "" {
+		merged.Author = f.Author
+	}
+	if merged.FormatVersion == "" {
+		merged.FormatVersion = f.FormatVersion
+	}
+	for key, value := range f.Options {
+		if _, exists := merged.Options[key]; !exists {
+			merged.Options[key] = value
+		}
+	}
+	for name, value := range f.Variables {
+		if _, exists := merged.Variables[name]; !exists {
+			if merged.Variables == nil {
+				merged.Variables = make(map[string]string, len(f.Variables))
+			}
+			merged.Variables[name] = value
+		}
+	}
+
+	return merged
+}
+
+func isDelimiter(line string, delimiter string) bool {
+	return strings.TrimSpace(line) == delimiter
+}
+
+func majorVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
@@ -0,0 +1,176 @@
+package frontmatter
+
+import (
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+)
+
+func TestSplitWithoutFrontMatter(t *testing.T) {
+	lines := []string{"Study Guide", "A: B: C", "1. Q? - A"}
+	doc, err := Split(lines)
+	if err != nil {
+		t.Fatalf("Split() unexpected error: %v", err)
+	}
+	if doc.FrontMatter != nil {
+		t.Errorf("expected no front matter, got %+v", doc.FrontMatter)
+	}
+	if len(doc.Body) != 3 || doc.BodyStart != 1 || doc.LineOffset() != 0 {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+}
+
+func TestSplitWithFrontMatter(t *testing.T) {
+	lines := []string{
+		"---",
+		"context_type: apexams",
+		"content_rating: Teen",
+		"language: en",
+		"author: Jane Doe",
+		"schema_version: 2.1",
+		"format_version: 2",
+		"options:",
+		"  edition: teacher",
+		"  premium: true",
+		"---",
+		"AP Biology Study Guide",
+		"A: B: C",
+	}
+
+	doc, err := Split(lines)
+	if err != nil {
+		t.Fatalf("Split() unexpected error: %v", err)
+	}
+	fm := doc.FrontMatter
+	if fm == nil {
+		t.Fatal("expected front matter")
+	}
+	if fm.ContextType != ontology.ContextTypeAPExams {
+		t.Errorf("ContextType = %q, want %q", fm.ContextType, ontology.ContextTypeAPExams)
+	}
+	if fm.ContentRating != ontology.ContentRatingTeen {
+		t.Errorf("ContentRating = %q, want %q", fm.ContentRating, ontology.ContentRatingTeen)
+	}
+	if fm.Language != "en" || fm.Author != "Jane Doe" || fm.FormatVersion != "2" || fm.SchemaVersion != "2.1" {
+		t.Errorf("unexpected front matter: %+v", fm)
+	}
+	if fm.Options["edition"] != "teacher" || fm.Options["premium"] != "true" {
+		t.Errorf("unexpected options: %v", fm.Options)
+	}
+	if doc.BodyStart != 12 || doc.Body[0] != "AP Biology Study Guide" {
+		t.Errorf("BodyStart = %d, first body line = %q", doc.BodyStart, doc.Body[0])
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		wantCode ErrorCode
+		wantLine int
+	}{
+		{
+			name:     "unclosed",
+			lines:    []string{"---", "language: en", "Study Guide"},
+			wantCode: CodeUnclosedFrontMatter,
+			wantLine: 1,
+		},
+		{
+			name:     "unknown key",
+			lines:    []string{"---", "language: en", "contxt_type: College", "---", "Study Guide"},
+			wantCode: CodeInvalidFrontMatter,
+			wantLine: 3,
+		},
+		{
+			name:     "malformed yaml",
+			lines:    []string{"---", "language: [en", "---", "Study Guide"},
+			wantCode: CodeInvalidFrontMatter,
+			wantLine: 2,
+		},
+		{
+			name:     "unknown context type",
+			lines:    []string{"---", "language: en", "context_type: Kindergarten", "---", "Study Guide"},
+			wantCode: CodeInvalidFrontMatter,
+			wantLine: 3,
+		},
+		{
+			name:     "unknown format version",
+			lines:    []string{"---", "language: en", "format_version: 7", "---", "Study Guide"},
+			wantCode: CodeInvalidFrontMatter,
+			wantLine: 3,
+		},
+		{
+			name:     "invalid variable name",
+			lines:    []string{"---", "variables:", "  course code: BIO 101", "---", "Study Guide"},
+			wantCode: CodeInvalidFrontMatter,
+			wantLine: 2,
+		},
+		{
+			name:     "unsupported schema version",
+			lines:    []string{"---", "schema_version: 1.0.0", "---", "Study Guide"},
+			wantCode: CodeInvalidFrontMatter,
+			wantLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Split(tt.lines)
+			if err == nil {
+				t.Fatal("Split() expected error, got nil")
+			}
+			if err.Code != tt.wantCode || err.Line != tt.wantLine {
+				t.Errorf("got code=%s line=%d (%s), want code=%s line=%d", err.Code, err.Line, err.Message, tt.wantCode, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestApplyPrecedence(t *testing.T) {
+	fm := &FrontMatter{
+		ContextType:   ontology.ContextTypeCollege,
+		ContentRating: ontology.ContentRatingEveryone,
+		Language:      "fr",
+		Author:        "Front Matter",
+		Options:       map[string]string{"edition": "teacher", "premium": "true"},
+		Variables:     map[string]string{"course": "BIO 101", "term": "Fall"},
+	}
+
+	caller := config.NewMetadata("build").WithOption("edition", "student")
+	caller.ContextType = ontology.ContextTypeAPExams
+	caller.Language = "en"
+	caller.Variables = map[string]string{"course": "BIO 102"}
+
+	merged := fm.Apply(caller)
+
+	// Caller values win
+	if merged.ContextType != ontology.ContextTypeAPExams {
+		t.Errorf("ContextType = %q, want caller value", merged.ContextType)
+	}
+	if merged.Language != "en" {
+		t.Errorf("Language = %q, want caller value", merged.Language)
+	}
+	if merged.Options["edition"] != "student" {
+		t.Errorf("edition option = %q, want caller value", merged.Options["edition"])
+	}
+	// Front matter fills the gaps
+	if merged.ContentRating != ontology.ContentRatingEveryone || merged.Author != "Front Matter" {
+		t.Errorf("unexpected merged metadata: %+v", merged)
+	}
+	if merged.Options["premium"] != "true" {
+		t.Errorf("premium option = %q, want front matter value", merged.Options["premium"])
+	}
+	if merged.Variables["course"] != "BIO 102" || merged.Variables["term"] != "Fall" {
+		t.Errorf("Variables = %v, want the caller's course and the front matter's term", merged.Variables)
+	}
+	// The caller's metadata is not modified
+	if caller.Author != "" || len(caller.Options) != 1 || len(caller.Variables) != 1 {
+		t.Errorf("caller metadata was modified: %+v", caller)
+	}
+
+	var none *FrontMatter
+	if none.Apply(caller) != caller {
+		t.Error("nil front matter should return the caller's metadata")
+	}
+}
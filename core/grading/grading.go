@@ -0,0 +1,275 @@
+// Package grading scores a learner's response against a question from the tree.
+// It needs no network access and no model: short answers are compared after
+// normalising case, diacritics, punctuation and leading articles, numbers are
+// compared within the question's tolerance, small typos are accepted as close,
+// and the structured question types are given partial credit.
+package grading
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// Verdict is the outcome of grading a response
+type Verdict string
+
+const (
+	VerdictCorrect   Verdict = "correct"   // The response matches an acceptable answer
+	VerdictClose     Verdict = "close"     // The response is an acceptable answer with a small typo
+	VerdictPartial   Verdict = "partial"   // Part of a multi-select, ordering or matching answer is right
+	VerdictIncorrect Verdict = "incorrect" // Nothing in the response is right
+)
+
+const (
+	// DefaultMaxTypoRatio is the share of an answer's characters that may be mistyped in a close response
+	DefaultMaxTypoRatio = 0.2
+
+	// DefaultCloseScore is the score given to a close response
+	DefaultCloseScore = 0.8
+)
+
+// Result is the graded response
+type Result struct {
+	Verdict       Verdict `json:"verdict"`
+	Score         float64 `json:"score"`                    // From 0 (incorrect) to 1 (correct)
+	MatchedAnswer string  `json:"matched_answer,omitempty"` // The acceptable answer the response matched, for short answers
+}
+
+// Grader grades responses. The zero value accepts no typos; use NewGrader for the defaults.
+type Grader struct {
+	MaxTypoRatio float64
+	CloseScore   float64
+}
+
+// NewGrader creates a grader with the default typo allowance and close score
+func NewGrader() *Grader {
+	return &Grader{
+		MaxTypoRatio: DefaultMaxTypoRatio,
+		CloseScore:   DefaultCloseScore,
+	}
+}
+
+// WithMaxTypoRatio sets the share of an answer's characters that may be mistyped
+// in a close response. Zero turns typo tolerance off.
+func (g *Grader) WithMaxTypoRatio(ratio float64) *Grader {
+	g.MaxTypoRatio = ratio
+	return g
+}
+
+// WithCloseScore sets the score given to a close response
+func (g *Grader) WithCloseScore(score float64) *Grader {
+	g.CloseScore = score
+	return g
+}
+
+// Grade scores response against q. Multi-select, ordering and matching responses
+// are written as in a guide: items separated by ';', and pairs as "Left = Right".
+func (g *Grader) Grade(q *tree.Question, response string) Result {
+	structured := q.StructuredAnswer
+	switch {
+	case q.QuestionType == ontology.QuestionTypeTrueFalse && structured != nil && structured.Truth != nil:
+		return gradeTrueFalse(*structured.Truth, response)
+	case q.QuestionType == ontology.QuestionTypeMultiSelect && structured != nil && len(structured.Answers) > 0:
+		return gradeMultiSelect(structured.Answers, response)
+	case q.QuestionType == ontology.QuestionTypeOrdering && structured != nil && len(structured.Sequence) > 0:
+		return gradeOrdering(structured.Sequence, response)
+	case q.QuestionType == ontology.QuestionTypeMatching && structured != nil && len(structured.Pairs) > 0:
+		return gradeMatching(structured.Pairs, response)
+	}
+	return g.gradeText(append([]string{q.Answer}, q.AcceptableAnswers...), q.Tolerance, response)
+}
+
+// gradeText compares a free-text response with each acceptable answer and keeps the best result
+func (g *Grader) gradeText(answers []string, tolerance float64, response string) Result {
+	best := Result{Verdict: VerdictIncorrect}
+	given := cleanstring.New(response).Comparable()
+	if given == "" {
+		return best
+	}
+
+	for _, answer := range answers {
+		// Numbers are right or wrong: "1991" is not a typo of "1990". An answer with a
+		// tolerance keeps it as written ("9.8 ± 0.1"), so only its value is compared.
+		number := answer
+		if match := regexes.ToleranceRegex.FindStringSubmatch(answer); match != nil && tolerance > 0 {
+			number = match[1]
+		}
+		if value, ok := parseNumber(number); ok {
+			if got, ok := parseNumber(response); ok && math.Abs(got-value) <= tolerance+1e-9 {
+				return Result{Verdict: VerdictCorrect, Score: 1, MatchedAnswer: answer}
+			}
+			continue
+		}
+
+		expected := cleanstring.New(answer).Comparable()
+		if given == expected {
+			return Result{Verdict: VerdictCorrect, Score: 1, MatchedAnswer: answer}
+		}
+		allowed := int(float64(len([]rune(expected))) * g.MaxTypoRatio)
+		if best.Verdict != VerdictClose && allowed > 0 && levenshtein(given, expected) <= allowed {
+			best = Result{Verdict: VerdictClose, Score: g.CloseScore, MatchedAnswer: answer}
+		}
+	}
+	return best
+}
+
+// gradeTrueFalse accepts the same spellings as the guide syntax
+func gradeTrueFalse(truth bool, response string) Result {
+	var given bool
+	switch cleanstring.New(response).CleanLower() {
+	case "true", "t":
+		given = true
+	case "false", "f":
+		given = false
+	default:
+		return Result{Verdict: VerdictIncorrect}
+	}
+	if given != truth {
+		return Result{Verdict: VerdictIncorrect}
+	}
+	return Result{Verdict: VerdictCorrect, Score: 1}
+}
+
+// gradeMultiSelect gives credit for each correct answer selected and takes it away
+// for each wrong one, so that selecting everything does not score
+func gradeMultiSelect(answers []string, response string) Result {
+	correct := map[string]bool{}
+	for _, answer := range answers {
+		correct[cleanstring.New(answer).Comparable()] = true
+	}
+	hits, misses := 0, 0
+	for _, item := range splitItems(response) {
+		if correct[item] {
+			hits++
+			delete(correct, item)
+		} else {
+			misses++
+		}
+	}
+	return scored(float64(hits-misses) / float64(len(answers)))
+}
+
+// gradeOrdering scores the longest run of items given in the correct relative order
+func gradeOrdering(sequence []string, response string) Result {
+	expected := make([]string, len(sequence))
+	for i, item := range sequence {
+		expected[i] = cleanstring.New(item).Comparable()
+	}
+	given := splitItems(response)
+	score := float64(longestCommonSubsequence(expected, given)) / float64(len(expected))
+	if score == 1 && len(given) != len(expected) {
+		score = float64(len(expected)) / float64(len(given))
+	}
+	return scored(score)
+}
+
+// gradeMatching scores the share of pairs matched correctly
+func gradeMatching(pairs []tree.MatchPair, response string) Result {
+	correct := map[string]string{}
+	for _, pair := range pairs {
+		correct[cleanstring.New(pair.Left).Comparable()] = cleanstring.New(pair.Right).Comparable()
+	}
+	hits := 0
+	for _, item := range strings.Split(response, constants.AnswerListDelimiter) {
+		sides := strings.SplitN(item, constants.MatchPairDelimiter, 2)
+		if len(sides) != 2 {
+			continue
+		}
+		left := cleanstring.New(sides[0]).Comparable()
+		right, ok := correct[left]
+		if ok && right == cleanstring.New(sides[1]).Comparable() {
+			hits++
+			delete(correct, left)
+		}
+	}
+	return scored(float64(hits) / float64(len(pairs)))
+}
+
+// scored turns a share of credit into a result, clamping it to [0, 1]
+func scored(score float64) Result {
+	switch {
+	case score >= 1:
+		return Result{Verdict: VerdictCorrect, Score: 1}
+	case score <= 0:
+		return Result{Verdict: VerdictIncorrect}
+	}
+	return Result{Verdict: VerdictPartial, Score: score}
+}
+
+// splitItems splits a list response on ';' into comparable items, skipping empty ones
+func splitItems(response string) []string {
+	var items []string
+	for _, part := range strings.Split(response, constants.AnswerListDelimiter) {
+		if item := cleanstring.New(part).Comparable(); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNumber reads a plain number, ignoring thousands separators (e.g. "1,000")
+func parseNumber(text string) (float64, bool) {
+	clean := strings.ReplaceAll(cleanstring.New(text).Clean(), ",", "")
+	value, err := strconv.ParseFloat(clean, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, false
+	}
+	return value, true
+}
+
+// levenshtein returns the number of single-rune edits that turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// longestCommonSubsequence returns the length of the longest sequence of items found in both a and b in order
+func longestCommonSubsequence(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				lengths[i][j] = lengths[i-1][j-1] + 1
+			} else {
+				lengths[i][j] = lengths[i-1][j]
+				if lengths[i][j-1] > lengths[i][j] {
+					lengths[i][j] = lengths[i][j-1]
+				}
+			}
+		}
+	}
+	return lengths[len(a)][len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
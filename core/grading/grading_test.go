@@ -0,0 +1,141 @@
+package grading
+
+import (
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+func TestGradeShortAnswer(t *testing.T) {
+	capital := tree.NewQuestion("Capital of France?", "Paris", nil, nil, 1)
+	capital.AcceptableAnswers = []string{"City of Light"}
+
+	river := tree.NewQuestion("Longest river in Africa?", "The Nile", nil, nil, 2)
+
+	gravity := tree.NewQuestion("Acceleration of gravity in m/s²?", "9.8", nil, nil, 3)
+	gravity.Tolerance = 0.1
+
+	written := tree.NewQuestion("Acceleration of gravity in m/s²?", "9.8 ± 0.1", nil, nil, 3)
+	written.Tolerance = 0.1
+
+	year := tree.NewQuestion("Year the Berlin Wall fell?", "1989", nil, nil, 4)
+
+	tests := []struct {
+		name        string
+		question    *tree.Question
+		response    string
+		wantVerdict Verdict
+		wantScore   float64
+		wantMatched string
+	}{
+		{"exact", capital, "Paris", VerdictCorrect, 1, "Paris"},
+		{"case and whitespace", capital, "  pARIS ", VerdictCorrect, 1, "Paris"},
+		{"alternative", capital, "the city of light", VerdictCorrect, 1, "City of Light"},
+		{"typo in alternative", capital, "city of lihgt", VerdictClose, DefaultCloseScore, "City of Light"},
+		{"wrong", capital, "Lyon", VerdictIncorrect, 0, ""},
+		{"empty", capital, "  ", VerdictIncorrect, 0, ""},
+		{"article dropped", river, "Nile", VerdictCorrect, 1, "The Nile"},
+		{"short answers allow no typo", river, "Nole", VerdictIncorrect, 0, ""},
+		{"within tolerance", gravity, "9.75", VerdictCorrect, 1, "9.8"},
+		{"at the tolerance", gravity, "9.9", VerdictCorrect, 1, "9.8"},
+		{"outside tolerance", gravity, "9.95", VerdictIncorrect, 0, ""},
+		{"tolerance written in the answer", written, "9.75", VerdictCorrect, 1, "9.8 ± 0.1"},
+		{"numbers are not typos", year, "1988", VerdictIncorrect, 0, ""},
+		{"thousands separator", tree.NewQuestion("Meters in a km?", "1000", nil, nil, 5), "1,000", VerdictCorrect, 1, "1000"},
+	}
+
+	grader := NewGrader()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := grader.Grade(tt.question, tt.response)
+			if got.Verdict != tt.wantVerdict || got.Score != tt.wantScore || got.MatchedAnswer != tt.wantMatched {
+				t.Errorf("Grade(%q) = %+v, want {%s %v %q}", tt.response, got, tt.wantVerdict, tt.wantScore, tt.wantMatched)
+			}
+		})
+	}
+}
+
+func TestGradeWithoutTypos(t *testing.T) {
+	question := tree.NewQuestion("Author of Hamlet?", "Shakespeare", nil, nil, 1)
+	if got := NewGrader().Grade(question, "Shakespear"); got.Verdict != VerdictClose {
+		t.Errorf("default grader verdict = %s, want %s", got.Verdict, VerdictClose)
+	}
+	if got := NewGrader().WithMaxTypoRatio(0).Grade(question, "Shakespear"); got.Verdict != VerdictIncorrect {
+		t.Errorf("strict grader verdict = %s, want %s", got.Verdict, VerdictIncorrect)
+	}
+	if got := NewGrader().WithCloseScore(0.5).Grade(question, "Shakespear"); got.Score != 0.5 {
+		t.Errorf("close score = %v, want 0.5", got.Score)
+	}
+}
+
+func TestGradeStructuredAnswers(t *testing.T) {
+	truth := false
+	trueFalse := tree.NewQuestion("The moon is a planet.", "False", nil, nil, 1)
+	trueFalse.QuestionType = ontology.QuestionTypeTrueFalse
+	trueFalse.StructuredAnswer = &tree.StructuredAnswer{Truth: &truth}
+
+	multi := tree.NewQuestion("Which are primes?", "2; 3; 5; 7", nil, nil, 2)
+	multi.QuestionType = ontology.QuestionTypeMultiSelect
+	multi.StructuredAnswer = &tree.StructuredAnswer{Answers: []string{"2", "3", "5", "7"}}
+
+	ordering := tree.NewQuestion("Order the planets from the sun", "Mercury; Venus; Earth; Mars", nil, nil, 3)
+	ordering.QuestionType = ontology.QuestionTypeOrdering
+	ordering.StructuredAnswer = &tree.StructuredAnswer{Sequence: []string{"Mercury", "Venus", "Earth", "Mars"}}
+
+	matching := tree.NewQuestion("Match the capitals", "France = Paris; Spain = Madrid", nil, nil, 4)
+	matching.QuestionType = ontology.QuestionTypeMatching
+	matching.StructuredAnswer = &tree.StructuredAnswer{Pairs: []tree.MatchPair{
+		{Left: "France", Right: "Paris"},
+		{Left: "Spain", Right: "Madrid"},
+	}}
+
+	tests := []struct {
+		name        string
+		question    *tree.Question
+		response    string
+		wantVerdict Verdict
+		wantScore   float64
+	}{
+		{"true/false correct", trueFalse, "f", VerdictCorrect, 1},
+		{"true/false wrong", trueFalse, "True", VerdictIncorrect, 0},
+		{"true/false unreadable", trueFalse, "maybe", VerdictIncorrect, 0},
+		{"multi-select all", multi, "7; 5; 3; 2", VerdictCorrect, 1},
+		{"multi-select half", multi, "2; 3", VerdictPartial, 0.5},
+		{"multi-select wrong answers cost credit", multi, "2; 3; 4", VerdictPartial, 0.25},
+		{"multi-select everything", multi, "1; 2; 3; 4; 5; 6; 7; 8; 9", VerdictIncorrect, 0},
+		{"ordering correct", ordering, "mercury; venus; earth; mars", VerdictCorrect, 1},
+		{"ordering one moved", ordering, "Venus; Mercury; Earth; Mars", VerdictPartial, 0.75},
+		{"ordering with an extra item", ordering, "Mercury; Venus; Earth; Mars; Pluto", VerdictPartial, 0.8},
+		{"matching correct", matching, "Spain = Madrid; France = Paris", VerdictCorrect, 1},
+		{"matching half", matching, "France = Paris; Spain = Lisbon", VerdictPartial, 0.5},
+		{"matching unreadable", matching, "Paris, Madrid", VerdictIncorrect, 0},
+	}
+
+	grader := NewGrader()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := grader.Grade(tt.question, tt.response)
+			if got.Verdict != tt.wantVerdict || got.Score != tt.wantScore {
+				t.Errorf("Grade(%q) = %+v, want {%s %v}", tt.response, got, tt.wantVerdict, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
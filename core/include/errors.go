@@ -0,0 +1,38 @@
+package include
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeInvalidInclude  ErrorCode = "INVALID_INCLUDE"
+	CodeIncludeNotFound ErrorCode = "INCLUDE_NOT_FOUND"
+	CodeIncludeCycle    ErrorCode = "INCLUDE_CYCLE"
+	CodeIncludeDepth    ErrorCode = "INCLUDE_TOO_DEEP"
+)
+
+// IncludeError reports a problem with an @include directive at its original file and line
+type IncludeError struct {
+	Message string
+	Code    ErrorCode
+	File    string // The file containing the directive
+	Line    int    // The line of the directive in File
+	Text    string // The directive as written
+}
+
+// Error implements the error interface
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("%s (file: %s, line: %d)", e.Message, e.File, e.Line)
+}
+
+// NewIncludeError creates a new include error with the given code, message and directive position
+func NewIncludeError(code ErrorCode, message string, file string, line int, text string) *IncludeError {
+	return &IncludeError{
+		Message: message,
+		Code:    code,
+		File:    file,
+		Line:    line,
+		Text:    text,
+	}
+}
@@ -0,0 +1,138 @@
+// Package include assembles a guide from several files. A line of the form
+//
+//	@include domains/networking.txt
+//
+// is replaced by the lines of the named file, resolved relative to the file that
+// contains the directive. Included files may include other files up to a depth limit,
+// and cycles are rejected. The result carries a source.Map so that later errors can
+// be reported against the original file and line.
+package include
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/charset"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/source"
+)
+
+// Resolver expands @include directives using files from an fs.FS
+type Resolver struct {
+	FS       fs.FS
+	MaxDepth int
+	encoding charset.Encoding // The detected encoding of the last file passed to Resolve
+}
+
+// NewResolver creates a resolver that reads files from fsys with the default depth limit
+func NewResolver(fsys fs.FS) *Resolver {
+	return &Resolver{
+		FS:       fsys,
+		MaxDepth: constants.DefaultMaxIncludeDepth,
+	}
+}
+
+// WithMaxDepth sets how many levels of nested includes are allowed
+func (r *Resolver) WithMaxDepth(depth int) *Resolver {
+	r.MaxDepth = depth
+	return r
+}
+
+// Encoding returns the detected encoding of the last file passed to Resolve
+func (r *Resolver) Encoding() charset.Encoding {
+	return r.encoding
+}
+
+// Resolve reads the named file and expands its includes. It returns the flattened
+// lines and a map from each flattened line to its original file and line. Each file
+// is decoded to UTF-8 from the encoding charset.Detect finds for it.
+// Problems with a directive are returned as *IncludeError; failing to read the
+// named file itself returns the underlying fs error.
+func (r *Resolver) Resolve(name string) ([]string, *source.Map, error) {
+	lines, detected, err := readLines(r.FS, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.encoding = detected
+
+	var out []string
+	var positions []source.Position
+	if includeErr := r.expand(name, lines, []string{name}, &out, &positions); includeErr != nil {
+		return nil, nil, includeErr
+	}
+	return out, source.NewMap(positions), nil
+}
+
+// expand appends lines to out, replacing include directives with the included files.
+// stack holds the chain of files currently being expanded, outermost first.
+func (r *Resolver) expand(file string, lines []string, stack []string, out *[]string, positions *[]source.Position) *IncludeError {
+	for i, line := range lines {
+		target, ok := ParseDirective(line)
+		if !ok {
+			*out = append(*out, line)
+			*positions = append(*positions, source.Position{File: file, Line: i + 1})
+			continue
+		}
+
+		if target == "" {
+			return NewIncludeError(CodeInvalidInclude, "include directive must name a file", file, i+1, line)
+		}
+		resolved := path.Join(path.Dir(file), target)
+		if !fs.ValidPath(resolved) {
+			return NewIncludeError(CodeInvalidInclude,
+				fmt.Sprintf("included file %q is outside the guide's directory", target), file, i+1, line)
+		}
+		for _, open := range stack {
+			if open == resolved {
+				chain := append(append([]string{}, stack...), resolved)
+				return NewIncludeError(CodeIncludeCycle,
+					fmt.Sprintf("include cycle: %s", strings.Join(chain, " -> ")), file, i+1, line)
+			}
+		}
+		if len(stack) > r.MaxDepth {
+			return NewIncludeError(CodeIncludeDepth,
+				fmt.Sprintf("includes are nested more than %d levels deep", r.MaxDepth), file, i+1, line)
+		}
+
+		included, _, err := readLines(r.FS, resolved)
+		if err != nil {
+			code := CodeInvalidInclude
+			if errors.Is(err, fs.ErrNotExist) {
+				code = CodeIncludeNotFound
+			}
+			return NewIncludeError(code, fmt.Sprintf("cannot include %q: %v", target, err), file, i+1, line)
+		}
+		if includeErr := r.expand(resolved, included, append(stack, resolved), out, positions); includeErr != nil {
+			return includeErr
+		}
+	}
+	return nil
+}
+
+// ParseDirective reports whether line is an include directive and returns its target path.
+// The path may be wrapped in double quotes. An empty target is returned for "@include" alone.
+func ParseDirective(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(strings.ToLower(trimmed), constants.IncludeDirective) {
+		return "", false
+	}
+	rest := trimmed[len(constants.IncludeDirective):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", false
+	}
+	target := strings.TrimSpace(rest)
+	target = strings.TrimSuffix(strings.TrimPrefix(target, `"`), `"`)
+	return target, true
+}
+
+// readLines reads a file and decodes it into UTF-8 lines
+func readLines(fsys fs.FS, name string) ([]string, charset.Encoding, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, "", err
+	}
+	return charset.Lines(content)
+}
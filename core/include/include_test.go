@@ -0,0 +1,151 @@
+package include
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/studyguides-com/study-guides-parser/core/source"
+)
+
+func TestResolveFlattensIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"guide.txt":            {Data: []byte("Security+ Study Guide\n@include domains/threats.txt\nCertifications: CompTIA: Security+: Ops\n1. Q3? - A3")},
+		"domains/threats.txt":  {Data: []byte("Certifications: CompTIA: Security+: Threats\n@include \"shared/q.txt\"")},
+		"domains/shared/q.txt": {Data: []byte("1. Q1? - A1\n2. Q2? - A2")},
+	}
+
+	lines, sources, err := NewResolver(fsys).Resolve("guide.txt")
+	if err != nil {
+		t.Fatalf("Resolve() unexpected error: %v", err)
+	}
+
+	want := []string{
+		"Security+ Study Guide",
+		"Certifications: CompTIA: Security+: Threats",
+		"1. Q1? - A1",
+		"2. Q2? - A2",
+		"Certifications: CompTIA: Security+: Ops",
+		"1. Q3? - A3",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Resolve() lines = %q, want %q", lines, want)
+	}
+
+	wantPositions := []source.Position{
+		{File: "guide.txt", Line: 1},
+		{File: "domains/threats.txt", Line: 1},
+		{File: "domains/shared/q.txt", Line: 1},
+		{File: "domains/shared/q.txt", Line: 2},
+		{File: "guide.txt", Line: 3},
+		{File: "guide.txt", Line: 4},
+	}
+	for i, want := range wantPositions {
+		if got := sources.Position(i + 1); got != want {
+			t.Errorf("Position(%d) = %+v, want %+v", i+1, got, want)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		maxDepth int
+		wantCode ErrorCode
+		wantFile string
+		wantLine int
+	}{
+		{
+			name: "missing file",
+			fsys: fstest.MapFS{
+				"guide.txt": {Data: []byte("Guide\n@include missing.txt")},
+			},
+			wantCode: CodeIncludeNotFound,
+			wantFile: "guide.txt",
+			wantLine: 2,
+		},
+		{
+			name: "cycle",
+			fsys: fstest.MapFS{
+				"guide.txt": {Data: []byte("Guide\n@include a.txt")},
+				"a.txt":     {Data: []byte("A: B: C\n@include b.txt")},
+				"b.txt":     {Data: []byte("\n\n@include a.txt")},
+			},
+			wantCode: CodeIncludeCycle,
+			wantFile: "b.txt",
+			wantLine: 3,
+		},
+		{
+			name: "too deep",
+			fsys: fstest.MapFS{
+				"guide.txt": {Data: []byte("Guide\n@include a.txt")},
+				"a.txt":     {Data: []byte("@include b.txt")},
+				"b.txt":     {Data: []byte("A: B: C")},
+			},
+			maxDepth: 1,
+			wantCode: CodeIncludeDepth,
+			wantFile: "a.txt",
+			wantLine: 1,
+		},
+		{
+			name: "outside directory",
+			fsys: fstest.MapFS{
+				"guide.txt": {Data: []byte("Guide\n@include ../secret.txt")},
+			},
+			wantCode: CodeInvalidInclude,
+			wantFile: "guide.txt",
+			wantLine: 2,
+		},
+		{
+			name: "no target",
+			fsys: fstest.MapFS{
+				"guide.txt": {Data: []byte("Guide\n\n@include")},
+			},
+			wantCode: CodeInvalidInclude,
+			wantFile: "guide.txt",
+			wantLine: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewResolver(tt.fsys)
+			if tt.maxDepth > 0 {
+				resolver.WithMaxDepth(tt.maxDepth)
+			}
+			_, _, err := resolver.Resolve("guide.txt")
+			includeErr, ok := err.(*IncludeError)
+			if !ok {
+				t.Fatalf("Resolve() error = %v, want *IncludeError", err)
+			}
+			if includeErr.Code != tt.wantCode || includeErr.File != tt.wantFile || includeErr.Line != tt.wantLine {
+				t.Errorf("got %s at %s:%d (%s), want %s at %s:%d",
+					includeErr.Code, includeErr.File, includeErr.Line, includeErr.Message, tt.wantCode, tt.wantFile, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		line       string
+		wantTarget string
+		wantOK     bool
+	}{
+		{"@include part.txt", "part.txt", true},
+		{"  @INCLUDE \"my part.txt\"  ", "my part.txt", true},
+		{"@include", "", true},
+		{"@included part.txt", "", false},
+		{"Include: part.txt", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			target, ok := ParseDirective(tt.line)
+			if target != tt.wantTarget || ok != tt.wantOK {
+				t.Errorf("ParseDirective(%q) = %q, %v, want %q, %v", tt.line, target, ok, tt.wantTarget, tt.wantOK)
+			}
+		})
+	}
+}
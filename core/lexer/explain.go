@@ -0,0 +1,109 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+const (
+	// WinnerCodeBlock is the Explanation winner of lines inside or fencing a code block,
+	// which are not classified
+	WinnerCodeBlock = "code_block"
+	// WinnerDefault is the Explanation winner of lines no classifier matched, read as content
+	WinnerDefault = "default"
+	// WinnerPassageListItem is the Explanation winner of bullet lines without an answer
+	// inside a passage, read as content
+	WinnerPassageListItem = "passage_list_item"
+)
+
+// namedClassifier pairs a classifier with the name reported by Explain
+type namedClassifier struct {
+	name     string
+	classify TokenClassifier
+}
+
+// ClassifierResult is what one classifier returned for a line
+type ClassifierResult struct {
+	Classifier string    `json:"classifier"`
+	Type       TokenType `json:"type,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Explanation records how the lexer classified a line
+type Explanation struct {
+	Line        LineInfo           `json:"line"`
+	Classifiers []ClassifierResult `json:"classifiers,omitempty"` // Every classifier, in the order ProcessLine consults them
+	Winner      string             `json:"winner"`                // The classifier that decided the type, WinnerCodeBlock or WinnerDefault
+	Matches     []TokenType        `json:"matches,omitempty"`     // The line types whose patterns match the line; more than one is ambiguous
+}
+
+// Explain processes a line like ProcessLine and records how it was classified. Unlike
+// ProcessLine it consults every classifier, so the explanation shows the classifiers
+// that also matched after the winner. Lines inside a code block are not classified.
+func (l *Lexer) Explain(line string, lineNum int) (Explanation, *LexerError) {
+	lineInfo, err := l.ProcessLine(line, lineNum)
+	explanation := Explanation{Line: lineInfo}
+	switch lineInfo.Type {
+	case TokenTypeCodeStart, TokenTypeCode, TokenTypeCodeEnd:
+		explanation.Winner = WinnerCodeBlock
+		return explanation, err
+	}
+
+	cleaned := cleanstring.New(lineInfo.Text).Clean()
+	for _, classifier := range l.classifiers {
+		tokenType, classifierErr := classifier.classify(cleaned, lineNum)
+		result := ClassifierResult{Classifier: classifier.name, Type: tokenType}
+		if classifierErr != nil {
+			result.Error = classifierErr.Message
+		}
+		explanation.Classifiers = append(explanation.Classifiers, result)
+		if tokenType != "" && explanation.Winner == "" {
+			explanation.Winner = classifier.name
+		}
+	}
+	switch {
+	case explanation.Winner == "":
+		explanation.Winner = WinnerDefault
+	case lineInfo.Type == TokenTypeContent:
+		explanation.Winner = WinnerPassageListItem
+	}
+	explanation.Matches = l.patternMatches(cleaned, lineNum)
+	return explanation, err
+}
+
+// patternMatches returns the line types whose patterns match a cleaned line, without the
+// rules that let one type rule out another (e.g. a question is never a header)
+func (l *Lexer) patternMatches(line string, lineNum int) []TokenType {
+	var matches []TokenType
+	if tokenType, _ := classifyQuestion(line, lineNum, l.listItemPrefixes, l.format); tokenType != "" {
+		matches = append(matches, TokenTypeQuestion)
+	}
+	// The colon of a Passage or Learn More prefix and those inside URLs do not separate header parts
+	headerText := regexes.URLRegex.ReplaceAllString(line, "")
+	if tokenType, _ := isPassage(line, lineNum); tokenType != "" {
+		matches = append(matches, TokenTypePassage)
+		_, headerText, _ = strings.Cut(headerText, constants.ColonDelimiter)
+	}
+	if tokenType, _ := isLearnMore(line, lineNum); tokenType != "" {
+		matches = append(matches, TokenTypeLearnMore)
+		_, headerText, _ = strings.Cut(headerText, constants.ColonDelimiter)
+	}
+	if hasHeaderParts(headerText, l.format) {
+		matches = append(matches, TokenTypeHeader)
+	}
+	return matches
+}
+
+// newAmbiguityError builds the warning for a line that matches several line types
+func newAmbiguityError(matches []TokenType, lineInfo LineInfo) *LexerError {
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = string(match)
+	}
+	return NewLexerError(CodeAmbiguousLine, fmt.Sprintf("line matches %s; it is read as %s",
+		strings.Join(names, " and "), lineInfo.Type), lineInfo)
+}
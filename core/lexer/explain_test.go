@@ -0,0 +1,85 @@
+//go:build !prod
+
+package lexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/format"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantType    TokenType
+		wantWinner  string
+		wantMatches string
+		wantWarning bool
+	}{
+		{"question with header parts", "1. Ratio: 1: 2? - Half", TokenTypeQuestion, "question", "[question header]", true},
+		{"plain question", "1. What is 2 + 2? - 4", TokenTypeQuestion, "question", "[question]", false},
+		{"header", "College: Math: MATH 101", TokenTypeHeader, "header", "[header]", false},
+		{"passage with header parts", "Passage: Europe: Rivers: Danube", TokenTypePassage, "passage", "[passage header]", true},
+		{"learn more with a URL", "Learn More: https://example.com:8080/page", TokenTypeLearnMore, "learn_more", "[learn_more]", false},
+		{"comment", "# Notes: to: self", TokenTypeComment, "comment", "[header]", false},
+		{"content", "Plain text", TokenTypeContent, WinnerDefault, "[]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := NewLexer()
+			explanation, err := lexer.Explain(tt.line, 2)
+			if err != nil {
+				t.Fatalf("Explain() error: %v", err)
+			}
+			if explanation.Line.Type != tt.wantType || explanation.Winner != tt.wantWinner {
+				t.Errorf("Explain() = %s won by %s, want %s won by %s", explanation.Line.Type, explanation.Winner, tt.wantType, tt.wantWinner)
+			}
+			if got := fmt.Sprint(explanation.Matches); got != tt.wantMatches {
+				t.Errorf("Matches = %s, want %s", got, tt.wantMatches)
+			}
+			if len(explanation.Classifiers) != len(lexer.classifiers) {
+				t.Errorf("len(Classifiers) = %d, want every classifier (%d)", len(explanation.Classifiers), len(lexer.classifiers))
+			}
+			warned := false
+			for _, warning := range lexer.Warnings() {
+				warned = warned || warning.Code == CodeAmbiguousLine
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("ambiguity warning = %v, want %v (warnings: %v)", warned, tt.wantWarning, lexer.Warnings())
+			}
+		})
+	}
+}
+
+func TestExplainRecordsEveryClassifier(t *testing.T) {
+	explanation, _ := NewLexer().Explain("1. Capital of France? - Paris", 2)
+	want := "[{binary  } {file_header  } {directive  } {comment  } {question question } {header  } {passage  } {learn_more  } {empty  }]"
+	if got := fmt.Sprint(explanation.Classifiers); got != want {
+		t.Errorf("Classifiers = %s, want %s", got, want)
+	}
+
+	// Classifier errors are recorded with the classifier that raised them
+	explanation, err := NewLexer().Explain("1. Capital of France?", 2)
+	if err == nil || err.Code != CodeMissingAnswerDelimiter {
+		t.Fatalf("Explain() error = %v, want %s", err, CodeMissingAnswerDelimiter)
+	}
+	if result := explanation.Classifiers[4]; result.Classifier != "question" || result.Error != err.Message {
+		t.Errorf("question result = %+v, want the error %q", result, err.Message)
+	}
+}
+
+func TestExplainCodeBlock(t *testing.T) {
+	lexer := NewLexer().WithFormat(format.Version2)
+	for i, line := range []string{"```go", "x := a: b: c", "```"} {
+		explanation, err := lexer.Explain(line, i+2)
+		if err != nil {
+			t.Fatalf("Explain(%q) error: %v", line, err)
+		}
+		if explanation.Winner != WinnerCodeBlock || explanation.Classifiers != nil {
+			t.Errorf("Explain(%q) = %+v, want an unclassified code block line", line, explanation)
+		}
+	}
+}
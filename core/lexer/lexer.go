@@ -1,13 +1,36 @@
 package lexer
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
 )
 
 // Lexer provides functionality to process individual lines of text, detecting their type and
 // parsing their content accordingly.
+//
+// Lines are classified independently, except inside a fenced code block: from the opening
+// fence to the closing fence every line is passed through verbatim as a code token. Inside a
+// passage, bullet lines without an answer delimiter are list items of the passage rather than
+// questions missing their answer. The lexer therefore keeps state between calls to
+// ProcessLine, and Finish must be called after the last line to report a code block that
+// was never closed.
 type Lexer struct {
-	classifiers []TokenClassifier
+	classifiers      []namedClassifier
+	listItemPrefixes []regexes.ListItemPrefix
+	policy           cleanstring.Policy
+	format           format.Version
+	formatDeclared   bool     // Whether the format was set with WithFormat or a "@format" directive
+	started          bool     // Whether a line other than the file header, a directive, a comment or an empty line was seen
+	fence            string   // The opening fence of the current code block, empty outside code blocks
+	fenceLine        LineInfo // The opening fence line of the current code block
+	inPassage        bool     // Whether the lines follow a passage line, up to the next header
+	headerKeywords   []string // The first parts of the headers seen so far, as returned by headerKeyword
+	warnings         []*LexerError
 }
 
 // NewLexer creates and returns a new instance of Lexer.
@@ -17,35 +40,85 @@ type Lexer struct {
 //  1. Binary content detection
 //  2. File header detection (must be first line)
 //  3. Empty line detection
-//  4. Comment detection
-//  5. Question detection
-//  6. Header detection
-//  7. Passage detection
-//  8. Learn More line detection
+//  4. Directive detection
+//  5. Comment detection
+//  6. Question detection
+//  7. Header detection
+//  8. Passage detection
+//  9. Learn More line detection
 func NewLexer() *Lexer {
-	return &Lexer{
-		classifiers: []TokenClassifier{
-			isBinary,     // Check for binary content first
-			isFileHeader, // Then file headers (must be first line)
-			isComment,    // Then comments
-			isQuestion,   // Then questions
-			isHeader,     // Then headers
-			isPassage,    // Then passages
-			isLearnMore,  // Then learn more lines
-			isEmpty,      // Empty lines last since they're the most generic
-		},
+	l := &Lexer{policy: cleanstring.LegacyPolicy, format: format.Default}
+	return l.WithListItemPrefixes(regexes.DefaultListItemPrefixes)
+}
+
+// WithFormat sets the version of the input format, as declared outside the guide (e.g.
+// in its front matter). A "@format" directive in the guide must then declare the same version.
+func (l *Lexer) WithFormat(version format.Version) *Lexer {
+	l.format = version
+	l.formatDeclared = true
+	l.buildClassifiers()
+	return l
+}
+
+// Format returns the version of the input format the lines are read as
+func (l *Lexer) Format() format.Version {
+	return l.format
+}
+
+// WithPolicy sets the normalisation policy applied to each line outside code blocks
+// before it is classified. The normalised text is kept in the line's LineInfo, so
+// later stages see the same text.
+func (l *Lexer) WithPolicy(policy cleanstring.Policy) *Lexer {
+	l.policy = policy
+	return l
+}
+
+// WithListItemPrefixes sets the list item prefixes that mark a line as a question
+// and rebuilds the classifiers that depend on them.
+func (l *Lexer) WithListItemPrefixes(prefixes []regexes.ListItemPrefix) *Lexer {
+	l.listItemPrefixes = prefixes
+	l.buildClassifiers()
+	return l
+}
+
+// buildClassifiers builds the classifiers for the list item prefixes and the format version
+func (l *Lexer) buildClassifiers() {
+	l.classifiers = []namedClassifier{
+		{"binary", isBinary},                                              // Check for binary content first
+		{"file_header", isFileHeader},                                     // Then file headers (must be first line)
+		{"directive", newDirectiveClassifier(l.format)},                   // Then directives
+		{"comment", isComment},                                            // Then comments
+		{"question", newQuestionClassifier(l.listItemPrefixes, l.format)}, // Then questions
+		{"header", newHeaderClassifier(l.listItemPrefixes, l.format)},     // Then headers
+		{"passage", isPassage},                                            // Then passages
+		{"learn_more", isLearnMore},                                       // Then learn more lines
+		{"empty", isEmpty},                                                // Empty lines last since they're the most generic
 	}
 }
 
+// Warnings returns the content lines that almost matched a directive, such as
+// "Pasage: The Alps", each with a suggested correction in its metadata, and the
+// lines that matched more than one line type
+func (l *Lexer) Warnings() []*LexerError {
+	return l.warnings
+}
+
+// ListItemPrefixes returns the list item prefixes used for question detection
+func (l *Lexer) ListItemPrefixes() []regexes.ListItemPrefix {
+	return l.listItemPrefixes
+}
+
 // ProcessLine processes a single line of text, determining its type and parsing
 // its content. It returns a LineInfo struct containing the processed information
 // and any errors that occurred during processing.
 //
 // The function:
-//  1. Trims whitespace from the line
+//  1. Normalises the line with the lexer's policy and trims whitespace from it
 //  2. Attempts to detect the line type using registered classifiers
-//  3. Falls back to content type if no specific type is detected
-//  4. Parses the line using the appropriate parser for its type
+//  3. Falls back to content type if no specific type is detected, recording a
+//     warning (see Warnings) when the line nearly matches a directive
+//  4. Records a warning when the line matches more than one line type
+//  5. Parses the line using the appropriate parser for its type
 //
 // Parameters:
 //   - line: The text line to process
@@ -61,11 +134,23 @@ func (l *Lexer) ProcessLine(line string, lineNum int) (LineInfo, *LexerError) {
 		Text:   line,
 	}
 
+	// Code block lines are not classified so that indentation and '#' lines survive
+	if tokenType, ok := l.classifyCode(cleaned, lineNum); ok {
+		lineInfo.Type = tokenType
+		if tokenType == TokenTypeCodeStart {
+			l.fenceLine = lineInfo
+		}
+		return lineInfo, nil
+	}
+	line = l.policy.Apply(line)
+	lineInfo.Text = line
+	cleaned = cleanstring.New(line).Clean()
+
 	// Try each classifier in order
 	var tokenType TokenType
 	var classifierErr *LexerError
-	for _, classify := range l.classifiers {
-		tokenType, classifierErr = classify(cleaned, lineNum)
+	for _, classifier := range l.classifiers {
+		tokenType, classifierErr = classifier.classify(cleaned, lineNum)
 		if tokenType != "" {
 			lineInfo.Type = tokenType
 			break
@@ -77,12 +162,114 @@ func (l *Lexer) ProcessLine(line string, lineNum int) (LineInfo, *LexerError) {
 		return lineInfo, NewLexerError(CodeBinaryContent, "contains binary or non-printable characters", lineInfo)
 	}
 
-	// If no type was detected, it's content
+	if l.isPassageListItem(cleaned, tokenType, classifierErr) {
+		tokenType, classifierErr = TokenTypeContent, nil
+	}
+
+	// If no type was detected, it's content, unless it looks like a mistyped directive
 	if tokenType == "" {
 		tokenType = TokenTypeContent
+		if warning := l.nearMiss(cleaned, lineNum); warning != nil {
+			warning.LineInfo.Text = line
+			l.warnings = append(l.warnings, warning)
+		}
 	}
 
 	lineInfo.Type = tokenType
 
+	switch tokenType {
+	case TokenTypeDirective:
+		if err := l.declareFormat(cleaned, lineInfo); err != nil {
+			return lineInfo, err
+		}
+	case TokenTypeFileHeader, TokenTypeComment, TokenTypeEmpty:
+	default:
+		l.started = true
+	}
+	switch tokenType {
+	case TokenTypePassage:
+		l.inPassage = true
+	case TokenTypeHeader:
+		l.inPassage = false
+		l.addHeaderKeyword(cleaned)
+	}
+
+	// Comments, directives and the file header are decided before any other type is considered
+	if tokenType != TokenTypeComment && tokenType != TokenTypeDirective && tokenType != TokenTypeFileHeader {
+		if matches := l.patternMatches(cleaned, lineNum); len(matches) > 1 {
+			l.warnings = append(l.warnings, newAmbiguityError(matches, lineInfo))
+		}
+	}
+
 	return lineInfo, classifierErr
 }
+
+// isPassageListItem reports whether a line classified as a question is a list item of the
+// current passage: a bullet line, such as "- Mitochondria", without an answer delimiter
+func (l *Lexer) isPassageListItem(line string, tokenType TokenType, err *LexerError) bool {
+	if !l.inPassage || tokenType != TokenTypeQuestion || err == nil || err.Code != CodeMissingAnswerDelimiter {
+		return false
+	}
+	match, ok := regexes.MatchListItemPrefix(line, l.listItemPrefixes)
+	return ok && match.Prefix.Style == regexes.NumberingNone
+}
+
+// declareFormat switches the lexer to the version declared by a "@format" directive. The
+// directive must come before the first header, and may not contradict a version declared
+// earlier or set with WithFormat.
+func (l *Lexer) declareFormat(line string, lineInfo LineInfo) *LexerError {
+	match := regexes.DirectiveRegex.FindStringSubmatch(line)
+	if match == nil || !strings.EqualFold(match[1], constants.FormatDirective) {
+		return nil
+	}
+	if strings.TrimSpace(match[2]) == "" {
+		return NewLexerError(CodeInvalidFormat, fmt.Sprintf("%s%s needs a version, e.g. \"%s%s: %d\"",
+			constants.DirectivePrefix, constants.FormatDirective, constants.DirectivePrefix, constants.FormatDirective, format.Latest), lineInfo)
+	}
+	version, err := format.Parse(match[2])
+	if err != nil {
+		return NewLexerError(CodeInvalidFormat, err.Error(), lineInfo)
+	}
+	if l.started {
+		return NewLexerError(CodeInvalidFormat, fmt.Sprintf("%s%s must come before the first header",
+			constants.DirectivePrefix, constants.FormatDirective), lineInfo)
+	}
+	if l.formatDeclared && version != l.format {
+		return NewLexerError(CodeInvalidFormat, fmt.Sprintf("format %s contradicts format %s declared earlier", version, l.format), lineInfo)
+	}
+	l.format = version
+	l.formatDeclared = true
+	l.buildClassifiers()
+	return nil
+}
+
+// Finish reports an error if the input ended inside a code block. It should be
+// called once after the last line has been processed.
+func (l *Lexer) Finish() *LexerError {
+	if l.fence == "" {
+		return nil
+	}
+	l.fence = ""
+	return NewLexerError(CodeUnclosedCodeBlock, "code block is never closed", l.fenceLine)
+}
+
+// classifyCode tracks fenced code blocks. It returns the token type of a fence or of
+// a line inside a code block, and false for lines outside code blocks.
+func (l *Lexer) classifyCode(cleaned string, lineNum int) (TokenType, bool) {
+	if l.fence != "" {
+		// A closing fence uses the same character and is at least as long as the opening fence
+		if strings.HasPrefix(cleaned, l.fence) && strings.Trim(cleaned, l.fence[:1]) == "" {
+			l.fence = ""
+			return TokenTypeCodeEnd, true
+		}
+		return TokenTypeCode, true
+	}
+	if lineNum == constants.FirstLineNumber || !l.format.Supports(format.FeatureCodeBlocks) {
+		return "", false
+	}
+	if match := regexes.CodeFenceRegex.FindStringSubmatch(cleaned); match != nil {
+		l.fence = match[1]
+		return TokenTypeCodeStart, true
+	}
+	return "", false
+}
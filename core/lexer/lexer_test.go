@@ -4,6 +4,10 @@ package lexer
 
 import (
 	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
 )
 
 func TestNewLexerBasic(t *testing.T) {
@@ -21,6 +25,7 @@ func TestNewLexerBasic(t *testing.T) {
 	expectedOrder := []string{
 		"isBinary",
 		"isFileHeader",
+		"isDirective",
 		"isComment",
 		"isQuestion",
 		"isHeader",
@@ -179,3 +184,195 @@ func TestProcessLineIntegrationBasic(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessLineWithListItemPrefixes(t *testing.T) {
+	lines := []struct {
+		line        string
+		defaultType TokenType
+		extendType  TokenType
+	}{
+		{"1) What is Go? - A programming language", TokenTypeContent, TokenTypeQuestion},
+		{"Q1: What is Go? - A programming language", TokenTypeContent, TokenTypeQuestion},
+		{"(iv) What is Go? - A programming language", TokenTypeContent, TokenTypeQuestion},
+		{"Q1: What is Go: the language: the runtime? - Both", TokenTypeHeader, TokenTypeQuestion},
+		{"1. What is Go? - A programming language", TokenTypeQuestion, TokenTypeQuestion},
+	}
+
+	defaultLexer := NewLexer()
+	extendedLexer := NewLexer().WithListItemPrefixes(regexes.ExtendedListItemPrefixes)
+
+	for _, tt := range lines {
+		t.Run(tt.line, func(t *testing.T) {
+			if got, _ := defaultLexer.ProcessLine(tt.line, 2); got.Type != tt.defaultType {
+				t.Errorf("default lexer got type = %v, want %v", got.Type, tt.defaultType)
+			}
+			if got, _ := extendedLexer.ProcessLine(tt.line, 2); got.Type != tt.extendType {
+				t.Errorf("extended lexer got type = %v, want %v", got.Type, tt.extendType)
+			}
+		})
+	}
+}
+
+func TestProcessLineWithPolicy(t *testing.T) {
+	legacy := NewLexer()
+	standard := NewLexer().WithPolicy(cleanstring.StandardPolicy).WithFormat(format.Version2)
+
+	line := "1. What is the capital of France? \u2014 Paris"
+	if got, err := legacy.ProcessLine(line, 2); err == nil || err.Code != CodeMissingAnswerDelimiter || got.Text != line {
+		t.Errorf("legacy lexer got %+v (error %v), want %s with the text as written", got, err, CodeMissingAnswerDelimiter)
+	}
+	got, err := standard.ProcessLine(line, 2)
+	if err != nil {
+		t.Fatalf("ProcessLine() error: %v", err)
+	}
+	if want := "1. What is the capital of France? - Paris"; got.Type != TokenTypeQuestion || got.Text != want {
+		t.Errorf("standard lexer got %+v, want a question with text %q", got, want)
+	}
+
+	// Code is kept verbatim whatever the policy
+	for i, code := range []string{"```", "x  =  \u201cy\u201d", "```"} {
+		if got, _ := standard.ProcessLine(code, i+3); got.Text != code {
+			t.Errorf("code line text = %q, want %q", got.Text, code)
+		}
+	}
+}
+
+func TestProcessLineFormatDirective(t *testing.T) {
+	// Guides without a declaration read unknown "@name" lines as content
+	lexer := NewLexer()
+	if got, _ := lexer.ProcessLine("@teacher: Smith", 2); got.Type != TokenTypeContent || lexer.Format() != format.Version1 {
+		t.Errorf("got %s in format %s, want content in format 1", got.Type, lexer.Format())
+	}
+
+	lexer = NewLexer()
+	if _, err := lexer.ProcessLine("@format: 2", 2); err != nil {
+		t.Fatalf("ProcessLine() error: %v", err)
+	}
+	if got, _ := lexer.ProcessLine("@teacher: Smith", 3); got.Type != TokenTypeDirective || lexer.Format() != format.Version2 {
+		t.Errorf("got %s in format %s, want a directive in format 2", got.Type, lexer.Format())
+	}
+
+	tests := []struct {
+		name  string
+		lexer *Lexer
+		lines []string
+	}{
+		{"unknown version", NewLexer(), []string{"@format: 9"}},
+		{"missing version", NewLexer(), []string{"@format"}},
+		{"after a header", NewLexer(), []string{"College: Biology: BIO 101: Cells", "@format: 2"}},
+		{"contradicts the metadata", NewLexer().WithFormat(format.Version1), []string{"@format: 2"}},
+		{"contradicts an earlier declaration", NewLexer(), []string{"@format: 2", "@format: 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err *LexerError
+			for i, line := range tt.lines {
+				_, err = tt.lexer.ProcessLine(line, i+2)
+			}
+			if err == nil || err.Code != CodeInvalidFormat {
+				t.Errorf("ProcessLine() error = %v, want %s", err, CodeInvalidFormat)
+			}
+		})
+	}
+}
+
+func TestProcessLineCodeBlock(t *testing.T) {
+	lines := []struct {
+		text     string
+		wantType TokenType
+	}{
+		{"AP Computer Science", TokenTypeFileHeader},
+		{"1. What does this print? - 3", TokenTypeQuestion},
+		{"```python", TokenTypeCodeStart},
+		{"# add the numbers", TokenTypeCode},
+		{"def add(a, b):", TokenTypeCode},
+		{"    return a + b", TokenTypeCode},
+		{"", TokenTypeCode},
+		{"1. not a question - inside code", TokenTypeCode},
+		{"```", TokenTypeCodeEnd},
+		{"# a comment again", TokenTypeComment},
+	}
+
+	lexer := NewLexer().WithFormat(format.Version2)
+	for i, line := range lines {
+		got, err := lexer.ProcessLine(line.text, i+1)
+		if err != nil {
+			t.Fatalf("line %d: unexpected error: %v", i+1, err)
+		}
+		if got.Type != line.wantType {
+			t.Errorf("line %d %q: type = %v, want %v", i+1, line.text, got.Type, line.wantType)
+		}
+		if got.Text != line.text {
+			t.Errorf("line %d: text = %q, want it unchanged", i+1, got.Text)
+		}
+	}
+	if err := lexer.Finish(); err != nil {
+		t.Errorf("Finish() unexpected error: %v", err)
+	}
+}
+
+func TestProcessLinePassageListItems(t *testing.T) {
+	lines := []struct {
+		text     string
+		wantType TokenType
+		wantErr  ErrorCode
+	}{
+		{"Biology Study Guide", TokenTypeFileHeader, ""},
+		{"- Not in a passage", TokenTypeQuestion, CodeMissingAnswerDelimiter},
+		{"Passage: Cells", TokenTypePassage, ""},
+		{"- Mitochondria", TokenTypeContent, ""},
+		{"* Ribosomes", TokenTypeContent, ""},
+		{"1. Numbered without an answer", TokenTypeQuestion, CodeMissingAnswerDelimiter},
+		{"- What makes proteins? - Ribosomes", TokenTypeQuestion, ""},
+		{"College: Biology: BIO 101: Cells", TokenTypeHeader, ""},
+		{"- After the passage", TokenTypeQuestion, CodeMissingAnswerDelimiter},
+	}
+
+	lexer := NewLexer()
+	for i, line := range lines {
+		got, err := lexer.ProcessLine(line.text, i+1)
+		var code ErrorCode
+		if err != nil {
+			code = err.Code
+		}
+		if got.Type != line.wantType || code != line.wantErr {
+			t.Errorf("line %d %q: type = %v, error = %v, want %v, %v", i+1, line.text, got.Type, code, line.wantType, line.wantErr)
+		}
+	}
+}
+
+func TestProcessLineCodeFenceInFormat1(t *testing.T) {
+	// Format 1 has no code blocks, so fences and the lines between them are classified as usual
+	lexer := NewLexer()
+	for i, line := range []struct {
+		text     string
+		wantType TokenType
+	}{
+		{"AP Computer Science", TokenTypeFileHeader},
+		{"```python", TokenTypeContent},
+		{"# add the numbers", TokenTypeComment},
+		{"```", TokenTypeContent},
+	} {
+		if got, _ := lexer.ProcessLine(line.text, i+1); got.Type != line.wantType {
+			t.Errorf("line %d %q: type = %v, want %v", i+1, line.text, got.Type, line.wantType)
+		}
+	}
+}
+
+func TestFinishReportsUnclosedCodeBlock(t *testing.T) {
+	lexer := NewLexer().WithFormat(format.Version2)
+	lexer.ProcessLine("AP Computer Science", 1)
+	lexer.ProcessLine("~~~~go", 2)
+	// A shorter fence does not close the block
+	if got, _ := lexer.ProcessLine("~~~", 3); got.Type != TokenTypeCode {
+		t.Errorf("shorter fence type = %v, want %v", got.Type, TokenTypeCode)
+	}
+
+	err := lexer.Finish()
+	if err == nil {
+		t.Fatal("Finish() expected an error for an unclosed code block")
+	}
+	if err.Code != CodeUnclosedCodeBlock || err.LineInfo.Number != 2 {
+		t.Errorf("Finish() error = %v on line %d, want %v on line 2", err.Code, err.LineInfo.Number, CodeUnclosedCodeBlock)
+	}
+}
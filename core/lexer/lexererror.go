@@ -11,6 +11,14 @@ const (
 	CodeBinaryContent ErrorCode = "BINARY_CONTENT"
 	// File header errors
 	CodeMissingFileHeader ErrorCode = "MISSING_FILE_HEADER"
+	// Code block errors
+	CodeUnclosedCodeBlock ErrorCode = "UNCLOSED_CODE_BLOCK"
+	// Format version errors: an unknown version, or a "@format" directive that is misplaced or contradicts another
+	CodeInvalidFormat ErrorCode = "INVALID_FORMAT"
+	// Content lines that almost match a directive; reported as warnings
+	CodeNearMiss ErrorCode = "NEAR_MISS"
+	// Lines that match more than one line type; reported as warnings
+	CodeAmbiguousLine ErrorCode = "AMBIGUOUS_LINE"
 )
 
 // GeneralError is a base struct for all error types
@@ -0,0 +1,158 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// MetadataSuggestion is the LexerError metadata key holding the suggested correction of a near miss
+const MetadataSuggestion = "suggestion"
+
+// maxHeaderWords is the most words a part of a line may have to be taken for a header part
+const maxHeaderWords = 5
+
+// directive is a line prefix that a near miss may have been meant to be
+type directive struct {
+	name   string // The directive in lowercase without spaces, for comparison
+	prefix string // The directive as it should be written
+}
+
+var nearMissDirectives = []directive{
+	{"passage", "Passage:"},
+	{"learnmore", "Learn More:"},
+}
+
+// contextKeywords are the header keywords every guide knows: the names of the context
+// types, except "None", which is too close to words such as "Note"
+var contextKeywords = func() []string {
+	keywords := []string{}
+	for _, contextType := range ontology.ContextTypes {
+		if contextType != ontology.ContextTypeNone {
+			keywords = append(keywords, headerKeyword(string(contextType)))
+		}
+	}
+	return keywords
+}()
+
+// nearMiss checks a line classified as content for a directive it almost matches: a
+// misspelled or wrongly punctuated "Passage:" or "Learn More:" prefix, a question with
+// a missing or malformed list item prefix, or a header with too few parts. It returns
+// a warning with the suggested correction in its metadata, or nil.
+func (l *Lexer) nearMiss(line string, lineNum int) *LexerError {
+	lineInfo := LineInfo{Number: lineNum, Text: line, Type: TokenTypeContent}
+	if message, suggestion := nearMissDirective(line); suggestion != "" {
+		return newNearMissError(message, suggestion, lineInfo)
+	}
+	if message, suggestion := nearMissQuestion(line, l.listItemPrefixes); suggestion != "" {
+		return newNearMissError(message, suggestion, lineInfo)
+	}
+	// Passage text is prose, where a colon rarely starts a header
+	if !l.inPassage && nearMissHeader(line, l.headerKeywords) {
+		return NewLexerError(CodeNearMiss, fmt.Sprintf(
+			"looks like a header but has only %d parts; a header needs at least %d parts separated by '%s'",
+			len(strings.Split(line, constants.ColonDelimiter)), constants.MinHeaderParts, constants.ColonDelimiter), lineInfo)
+	}
+	return nil
+}
+
+// newNearMissError builds a near-miss warning that suggests a corrected line
+func newNearMissError(message string, suggestion string, lineInfo LineInfo) *LexerError {
+	err := NewLexerError(CodeNearMiss, fmt.Sprintf("%s; did you mean %q?", message, suggestion), lineInfo)
+	err.Metadata[MetadataSuggestion] = suggestion
+	return err
+}
+
+// nearMissDirective looks for a directive prefix that is misspelled or uses the wrong separator.
+// Up to one edit is allowed for every four letters of the directive.
+func nearMissDirective(line string) (string, string) {
+	match := regexes.DirectiveLikeRegex.FindStringSubmatch(line)
+	if match == nil {
+		return "", ""
+	}
+	name := strings.ToLower(strings.Join(strings.Fields(match[1]), ""))
+	for _, d := range nearMissDirectives {
+		if cleanstring.EditDistance(name, d.name) > len(d.name)/4 {
+			continue
+		}
+		return fmt.Sprintf("%q looks like a %s line", strings.TrimSpace(match[1]+match[2]), d.prefix),
+			strings.TrimSpace(d.prefix + " " + match[3])
+	}
+	return "", ""
+}
+
+// nearMissQuestion looks for a question and answer written without a list item prefix the lexer accepts
+func nearMissQuestion(line string, prefixes []regexes.ListItemPrefix) (string, string) {
+	if !regexes.QuestionShapeRegex.MatchString(line) {
+		return "", ""
+	}
+	candidates := []string{"1. " + line, "- " + line, "* " + line}
+	if match := regexes.NumberedLineRegex.FindStringSubmatch(line); match != nil {
+		candidates = []string{match[1] + ". " + match[2], match[1] + ") " + match[2], "Q" + match[1] + ": " + match[2]}
+	}
+	for _, candidate := range candidates {
+		if regexes.HasListItemPrefix(candidate, prefixes) {
+			return "question without a list item prefix", candidate
+		}
+	}
+	return "", ""
+}
+
+// nearMissHeader reports whether a line looks like a header with too few parts: its first
+// part is close to a context type or to the first part of an earlier header of the guide,
+// and every part is a short title whose words start with a capital letter or a digit.
+// Up to one edit is allowed for every four letters of the keyword.
+func nearMissHeader(line string, keywords []string) bool {
+	parts := strings.Split(line, constants.ColonDelimiter)
+	if len(parts) < 2 || len(parts) >= constants.MinHeaderParts || !isHeaderKeyword(parts[0], keywords) {
+		return false
+	}
+	for _, part := range parts {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > maxHeaderWords {
+			return false
+		}
+		for _, word := range words {
+			first := []rune(word)[0]
+			if !unicode.IsUpper(first) && !unicode.IsDigit(first) {
+				return false
+			}
+		}
+	}
+	return !strings.ContainsAny(line, ".?!")
+}
+
+// isHeaderKeyword reports whether part is within edit distance of a context type or of one of keywords
+func isHeaderKeyword(part string, keywords []string) bool {
+	name := headerKeyword(part)
+	for _, list := range [][]string{contextKeywords, keywords} {
+		for _, keyword := range list {
+			if cleanstring.EditDistance(name, keyword) <= len(keyword)/4 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addHeaderKeyword records the first part of a header line, so that later lines with too
+// few parts are recognised as headers by the same keyword
+func (l *Lexer) addHeaderKeyword(line string) {
+	keyword := headerKeyword(strings.Split(line, constants.ColonDelimiter)[0])
+	for _, known := range l.headerKeywords {
+		if known == keyword {
+			return
+		}
+	}
+	l.headerKeywords = append(l.headerKeywords, keyword)
+}
+
+// headerKeyword returns the first part of a header in lowercase without spaces, for comparison
+func headerKeyword(part string) string {
+	return strings.ToLower(strings.Join(strings.Fields(part), ""))
+}
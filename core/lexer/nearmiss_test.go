@@ -0,0 +1,104 @@
+//go:build !prod
+
+package lexer
+
+import (
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+func TestProcessLineNearMiss(t *testing.T) {
+	tests := []struct {
+		name           string
+		line           string
+		prefixes       []regexes.ListItemPrefix
+		wantWarning    bool
+		wantSuggestion string
+	}{
+		{"misspelled passage", "Pasage: The Alps", nil, true, "Passage: The Alps"},
+		{"passage with a dash", "Passage - The Alps", nil, true, "Passage: The Alps"},
+		{"learn more with a dash", "Learn more - https://example.com", nil, true, "Learn More: https://example.com"},
+		{"learn more without a space", "LearnMore: chapter 3", nil, true, "Learn More: chapter 3"},
+		{"question missing the dot", "1 What is the capital of France? - Paris", nil, true, "1. What is the capital of France? - Paris"},
+		{"question with a comma", "2, What is 2 + 2? - 4", nil, true, "2. What is 2 + 2? - 4"},
+		{"question without a prefix", "What is the capital of Spain? - Madrid", nil, true, "1. What is the capital of Spain? - Madrid"},
+		{"suggestion uses an accepted prefix", "3 Who wrote Hamlet? - Shakespeare",
+			[]regexes.ListItemPrefix{regexes.NumberParenPrefix}, true, "3) Who wrote Hamlet? - Shakespeare"},
+		{"header with one colon", "College: Geography", nil, true, ""},
+		{"misspelled context with one colon", "Colege: Geography", nil, true, ""},
+		{"label that is not a header keyword", "Key Terms: Photosynthesis", nil, false, ""},
+		{"author label", "Author: Jane Austen", nil, false, ""},
+		{"sentence with a colon", "Note: the capital moved in 1990.", nil, false, ""},
+		{"label with a lowercase word", "Population: about 47 million", nil, false, ""},
+		{"unrelated word", "Message: hello there", nil, false, ""},
+		{"plain content", "The Alps span eight countries.", nil, false, ""},
+		{"question mark in prose", "Why? Nobody knows.", nil, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := NewLexer()
+			if tt.prefixes != nil {
+				lexer = lexer.WithListItemPrefixes(tt.prefixes)
+			}
+			lineInfo, err := lexer.ProcessLine(tt.line, 2)
+			if err != nil {
+				t.Fatalf("ProcessLine() error: %v", err)
+			}
+			if lineInfo.Type != TokenTypeContent {
+				t.Fatalf("Type = %s, want %s", lineInfo.Type, TokenTypeContent)
+			}
+			warnings := lexer.Warnings()
+			if !tt.wantWarning {
+				if len(warnings) != 0 {
+					t.Errorf("unexpected warning: %v", warnings[0])
+				}
+				return
+			}
+			if len(warnings) != 1 {
+				t.Fatalf("Warnings() = %v, want 1 warning", warnings)
+			}
+			if warnings[0].Code != CodeNearMiss || warnings[0].LineInfo.Number != 2 {
+				t.Errorf("warning = %s on line %d, want %s on line 2", warnings[0].Code, warnings[0].LineInfo.Number, CodeNearMiss)
+			}
+			if got := warnings[0].Metadata[MetadataSuggestion]; got != tt.wantSuggestion {
+				t.Errorf("suggestion = %q, want %q", got, tt.wantSuggestion)
+			}
+		})
+	}
+}
+
+func TestProcessLineNearMissHeader(t *testing.T) {
+	lexer := NewLexer()
+	lines := []string{
+		"Geography Study Guide",
+		"Geography: Europe: Countries: France",
+		"Geografy: Asia",            // close to the keyword of the header above
+		"Capital: Paris",            // an ordinary label
+		"Passage: The Alps",         // passage text is prose
+		"Geography: Mountain Range", // even with a header keyword
+	}
+	for i, line := range lines {
+		if _, err := lexer.ProcessLine(line, i+1); err != nil {
+			t.Fatalf("ProcessLine(%q) error: %v", line, err)
+		}
+	}
+	warnings := lexer.Warnings()
+	if len(warnings) != 1 || warnings[0].Code != CodeNearMiss || warnings[0].LineInfo.Number != 3 {
+		t.Errorf("Warnings() = %v, want 1 near miss on line 3", warnings)
+	}
+}
+
+func TestProcessLineNearMissSkipsCode(t *testing.T) {
+	lexer := NewLexer().WithFormat(format.Version2)
+	for i, line := range []string{"```", "Pasage: not a directive in code", "```"} {
+		if _, err := lexer.ProcessLine(line, i+2); err != nil {
+			t.Fatalf("ProcessLine(%q) error: %v", line, err)
+		}
+	}
+	if warnings := lexer.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() = %v, want none inside a code block", warnings)
+	}
+}
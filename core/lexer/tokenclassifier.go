@@ -5,12 +5,13 @@ import (
 
 	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
 	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/format"
 	"github.com/studyguides-com/study-guides-parser/core/regexes"
 )
 
 type TokenClassifier func(string, int) (TokenType, *LexerError)
 
-// isQuestion checks if a line is a question and validates its format.
+// isQuestion checks if a line is a question using the default list item prefixes.
 // A valid question must:
 //  1. Start with a list item prefix (number or bullet)
 //  2. Contain the answer delimiter " - "
@@ -19,12 +20,26 @@ type TokenClassifier func(string, int) (TokenType, *LexerError)
 //   - TokenType: The type of line (Question if valid, empty string if not)
 //   - *TokenizerError: Any validation errors found
 func isQuestion(line string, lineNum int) (TokenType, *LexerError) {
+	return classifyQuestion(line, lineNum, regexes.DefaultListItemPrefixes, format.Default)
+}
+
+// newQuestionClassifier returns a question classifier for the format version that accepts
+// the given list item prefixes
+func newQuestionClassifier(prefixes []regexes.ListItemPrefix, version format.Version) TokenClassifier {
+	return func(line string, lineNum int) (TokenType, *LexerError) {
+		return classifyQuestion(line, lineNum, prefixes, version)
+	}
+}
+
+func classifyQuestion(line string, lineNum int, prefixes []regexes.ListItemPrefix, version format.Version) (TokenType, *LexerError) {
 	// Use cleanstring for consistent text normalization
 	cleanedLine := cleanstring.New(line).Clean()
-	if !regexes.ListItemPrefixRegex.MatchString(cleanedLine) {
+	if !regexes.HasListItemPrefix(cleanedLine, prefixes) {
 		return "", nil
 	}
-	if !strings.Contains(line, constants.AnswerDelimiter) {
+	// Cloze deletions carry their own answers, so they need no answer delimiter
+	isCloze := version.Supports(format.FeatureCloze) && regexes.ClozeRegex.MatchString(line)
+	if !strings.Contains(line, constants.AnswerDelimiter) && !isCloze {
 		return TokenTypeQuestion, NewLexerError(
 			CodeMissingAnswerDelimiter,
 			"missing answer delimiter ' - '",
@@ -34,7 +49,7 @@ func isQuestion(line string, lineNum int) (TokenType, *LexerError) {
 	return TokenTypeQuestion, nil
 }
 
-// isHeader checks if a line is a header. A line is considered a header if:
+// isHeader checks if a line is a header in the default format version. A line is considered a header if:
 //  1. It contains 2 or more colons (e.g., "Subject: Topic: Subtopic")
 //  2. It's not a passage, question, or learn more line
 //
@@ -42,22 +57,47 @@ func isQuestion(line string, lineNum int) (TokenType, *LexerError) {
 //   - TokenType: The type of line (Header if valid, empty string if not)
 //   - *LexerError: Any validation errors found
 func isHeader(line string, lineNum int) (TokenType, *LexerError) {
+	return classifyHeader(line, lineNum, regexes.DefaultListItemPrefixes, format.Default)
+}
+
+// newHeaderClassifier returns a header classifier for the format version that excludes
+// questions using the given list item prefixes
+func newHeaderClassifier(prefixes []regexes.ListItemPrefix, version format.Version) TokenClassifier {
+	return func(line string, lineNum int) (TokenType, *LexerError) {
+		return classifyHeader(line, lineNum, prefixes, version)
+	}
+}
+
+func classifyHeader(line string, lineNum int, prefixes []regexes.ListItemPrefix, version format.Version) (TokenType, *LexerError) {
 	if lineType, _ := isPassage(line, lineNum); lineType != "" {
 		return "", nil
 	}
-	if lineType, _ := isQuestion(line, lineNum); lineType != "" {
+	if lineType, _ := classifyQuestion(line, lineNum, prefixes, version); lineType != "" {
 		return "", nil
 	}
 	if lineType, _ := isLearnMore(line, lineNum); lineType != "" {
 		return "", nil
 	}
-	parts := strings.Split(line, constants.ColonDelimiter)
-	if len(parts) >= constants.MinHeaderParts {
+	if hasHeaderParts(line, version) {
 		return TokenTypeHeader, nil
 	}
 	return "", nil
 }
 
+// hasHeaderParts reports whether a line has enough colon-separated parts to be a header,
+// before passages, questions and Learn More lines are ruled out
+func hasHeaderParts(line string, version format.Version) bool {
+	// Passage table rows and quotes may contain colons but are never headers
+	if regexes.TableRowRegex.MatchString(line) || regexes.QuoteRegex.MatchString(line) {
+		return false
+	}
+	// The colons of a question annotation such as "{difficulty: 3}" do not separate parts
+	if version.Supports(format.FeatureAnnotations) {
+		line = regexes.AnnotationRegex.ReplaceAllString(line, "")
+	}
+	return len(strings.Split(line, constants.ColonDelimiter)) >= constants.MinHeaderParts
+}
+
 // isComment checks if a line is a comment. A valid comment:
 //  1. Starts with exactly one '#' character
 //  2. Does not start with multiple '#' characters
@@ -73,6 +113,43 @@ func isComment(line string, lineNum int) (TokenType, *LexerError) {
 	return "", nil
 }
 
+// isDirective checks if a line is a directive using the default format version.
+// A valid directive:
+//  1. Starts with "@" followed by a directive name (e.g. "@context")
+//  2. Is followed by its value, optionally after a colon
+//
+// Returns:
+//   - TokenType: The type of line (Directive if valid, empty string if not)
+//   - *LexerError: Any validation errors found
+func isDirective(line string, lineNum int) (TokenType, *LexerError) {
+	return classifyDirective(line, lineNum, format.Default)
+}
+
+// newDirectiveClassifier returns a directive classifier for the given format version
+func newDirectiveClassifier(version format.Version) TokenClassifier {
+	return func(line string, lineNum int) (TokenType, *LexerError) {
+		return classifyDirective(line, lineNum, version)
+	}
+}
+
+// classifyDirective accepts the directives known in the format version. With strict
+// directives every "@name" line is a directive; before that, other lines starting with "@",
+// including directives of later versions, are content. The preparser rejects unknown
+// directives and those the format version does not have yet.
+func classifyDirective(line string, lineNum int, version format.Version) (TokenType, *LexerError) {
+	match := regexes.DirectiveRegex.FindStringSubmatch(line)
+	if match == nil {
+		return "", nil
+	}
+	if version.Supports(format.FeatureStrictDirectives) {
+		return TokenTypeDirective, nil
+	}
+	if since, ok := format.Directive(match[1]); ok && since <= version {
+		return TokenTypeDirective, nil
+	}
+	return "", nil
+}
+
 // isEmpty checks if a line is empty (contains only whitespace).
 //
 // Returns:
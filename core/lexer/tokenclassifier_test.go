@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
 )
 
 // errorMatches checks if a LexerError matches the expected error code and message
@@ -473,6 +475,54 @@ func TestIsLearnMore(t *testing.T) {
 	}
 }
 
+func TestIsDirective(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantType TokenType
+	}{
+		{"context with colon", "@context: APExams", TokenTypeDirective},
+		{"context without colon", "@context APExams", TokenTypeDirective},
+		{"name in another case", "@Context: College", TokenTypeDirective},
+		{"unknown directive", "@teacher: Smith", ""},
+		{"longer name", "@contexts: College", ""},
+		{"mention in content", "Follow @context on social media", ""},
+		{"format 2 directive in format 1", "@define course = BIO 101", ""},
+		{"format 2 directive word in format 1 content", "@author is my handle on the site", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotErr := isDirective(tt.line, 3)
+			if gotType != tt.wantType || gotErr != nil {
+				t.Errorf("isDirective(%q) = %v, %v, want %v, nil", tt.line, gotType, gotErr, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestClassifyHeaderAnnotations(t *testing.T) {
+	// The colons of an annotation only stop separating header parts from format 2
+	line := "What is velocity? {difficulty: 3, bloom: apply}"
+	if got, _ := classifyHeader(line, 3, regexes.DefaultListItemPrefixes, format.Version2); got != "" {
+		t.Errorf("classifyHeader(%q, 2) = %v, want no header", line, got)
+	}
+	if got, _ := classifyHeader(line, 3, regexes.DefaultListItemPrefixes, format.Version1); got != TokenTypeHeader {
+		t.Errorf("classifyHeader(%q, 1) = %v, want %v", line, got, TokenTypeHeader)
+	}
+}
+
+func TestClassifyDirectiveByFormat(t *testing.T) {
+	for _, line := range []string{"@define course = BIO 101", "@author is my handle on the site", "@teacher: Smith"} {
+		if got, err := classifyDirective(line, 3, format.Version1); got != "" || err != nil {
+			t.Errorf("classifyDirective(%q, 1) = %v, %v, want content", line, got, err)
+		}
+		if got, err := classifyDirective(line, 3, format.Version2); got != TokenTypeDirective || err != nil {
+			t.Errorf("classifyDirective(%q, 2) = %v, %v, want a directive", line, got, err)
+		}
+	}
+}
+
 func TestIsBinary(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -25,4 +25,12 @@ const (
 	TokenTypeSpacer TokenType = "spacer"
 	// TokenTypeBinary represents a line containing binary or non-text content
 	TokenTypeBinary TokenType = "binary"
+	// TokenTypeCodeStart represents the opening fence of a code block (e.g., "```python")
+	TokenTypeCodeStart TokenType = "code_start"
+	// TokenTypeCode represents a line inside a code block, kept verbatim
+	TokenTypeCode TokenType = "code"
+	// TokenTypeCodeEnd represents the closing fence of a code block (e.g., "```")
+	TokenTypeCodeEnd TokenType = "code_end"
+	// TokenTypeDirective represents a directive line (e.g. "@context: APExams")
+	TokenTypeDirective TokenType = "directive"
 )
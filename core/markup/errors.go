@@ -0,0 +1,36 @@
+package markup
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeUnclosedMarkup ErrorCode = "UNCLOSED_MARKUP"
+	CodeUnopenedMarkup ErrorCode = "UNOPENED_MARKUP"
+	CodeEmptyMarkup    ErrorCode = "EMPTY_MARKUP"
+)
+
+// MarkupError reports malformed inline markup. Column is the 1-based rune
+// position of the offending marker within the parsed text; the caller knows the line.
+type MarkupError struct {
+	Message string
+	Code    ErrorCode
+	Marker  string // The marker as written (e.g. "**", "$")
+	Column  int
+}
+
+// Error implements the error interface
+func (e *MarkupError) Error() string {
+	return fmt.Sprintf("%s (column: %d)", e.Message, e.Column)
+}
+
+// NewMarkupError creates a new markup error with the given code, message and marker position
+func NewMarkupError(code ErrorCode, message string, marker string, column int) *MarkupError {
+	return &MarkupError{
+		Message: message,
+		Code:    code,
+		Marker:  marker,
+		Column:  column,
+	}
+}
@@ -0,0 +1,303 @@
+// Package markup parses the inline rich-text markup used in prompts, answers,
+// Learn More lines and passages:
+//
+//	**bold**  *italic*  _italic_  `code`  H~2~O  x^2^  $E=mc^2$
+//
+// Code and math spans are literal: no markup is recognised inside them. As in
+// pandoc, "$" only opens math before a non-space and only closes it after a
+// non-space that is not followed by a digit; an unclosed "$" before a number is
+// read as currency. A backslash escapes the next marker character (e.g. "\$5").
+// Markup does not span lines. An unclosed marker is kept as literal text and
+// reported as a MarkupError, so malformed markup never loses any of the author's text.
+package markup
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NodeType identifies the kind of a rich-text node
+type NodeType string
+
+const (
+	NodeTypeText        NodeType = "text"
+	NodeTypeBold        NodeType = "bold"
+	NodeTypeItalic      NodeType = "italic"
+	NodeTypeCode        NodeType = "code"
+	NodeTypeMath        NodeType = "math"
+	NodeTypeSubscript   NodeType = "subscript"
+	NodeTypeSuperscript NodeType = "superscript"
+)
+
+// Node is a single node of the rich-text AST. Text, code and math nodes carry
+// Text; the other types carry Children.
+type Node struct {
+	Type     NodeType `json:"type"`
+	Text     string   `json:"text,omitempty"`
+	Children []*Node  `json:"children,omitempty"`
+}
+
+// Markers that may be escaped with a backslash
+const escapable = `\*_~^$` + "`"
+
+// Markers whose content is parsed for nested markup, longest first
+var containers = []struct {
+	marker   string
+	nodeType NodeType
+}{
+	{"**", NodeTypeBold},
+	{"*", NodeTypeItalic},
+	{"_", NodeTypeItalic},
+	{"~", NodeTypeSubscript},
+	{"^", NodeTypeSuperscript},
+}
+
+// Markers whose content is kept verbatim
+var literals = []struct {
+	marker   string
+	nodeType NodeType
+}{
+	{"`", NodeTypeCode},
+	{"$", NodeTypeMath},
+}
+
+// Parse parses the inline markup in text. It always returns the full text as
+// nodes; errors describe markers that were not closed or enclose nothing.
+func Parse(text string) ([]*Node, []*MarkupError) {
+	p := &inlineParser{runes: []rune(text)}
+	nodes, _ := p.parseUntil("")
+	return mergeText(nodes), p.errors
+}
+
+// PlainText renders nodes as plain text, dropping all markers
+func PlainText(nodes []*Node) string {
+	var sb strings.Builder
+	writePlain(&sb, nodes)
+	return sb.String()
+}
+
+// IsPlain reports whether nodes contain no formatting
+func IsPlain(nodes []*Node) bool {
+	for _, node := range nodes {
+		if node.Type != NodeTypeText {
+			return false
+		}
+	}
+	return true
+}
+
+func writePlain(sb *strings.Builder, nodes []*Node) {
+	for _, node := range nodes {
+		sb.WriteString(node.Text)
+		writePlain(sb, node.Children)
+	}
+}
+
+type inlineParser struct {
+	runes  []rune
+	pos    int
+	errors []*MarkupError
+}
+
+// parseUntil parses nodes until closer is found at the current position.
+// It reports whether the closer was found; an empty closer runs to the end.
+func (p *inlineParser) parseUntil(closer string) ([]*Node, bool) {
+	var nodes []*Node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, &Node{Type: NodeTypeText, Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.runes) {
+		if closer != "" && p.closesAt(closer) {
+			p.pos += len([]rune(closer))
+			flush()
+			return nodes, true
+		}
+
+		r := p.runes[p.pos]
+		if r == '\\' && p.pos+1 < len(p.runes) && strings.ContainsRune(escapable, p.runes[p.pos+1]) {
+			text.WriteRune(p.runes[p.pos+1])
+			p.pos += 2
+			continue
+		}
+
+		if node, ok := p.parseLiteral(); ok {
+			flush()
+			nodes = append(nodes, node...)
+			continue
+		}
+		if node, ok := p.parseContainer(); ok {
+			flush()
+			nodes = append(nodes, node...)
+			continue
+		}
+
+		text.WriteRune(r)
+		p.pos++
+	}
+
+	flush()
+	return nodes, closer == ""
+}
+
+// closesAt reports whether closer ends the current span at the current position
+func (p *inlineParser) closesAt(closer string) bool {
+	if !p.hasPrefix(closer) {
+		return false
+	}
+	// A single "*" does not close italics when it starts a "**"
+	if closer == "*" && p.hasPrefix("**") {
+		return false
+	}
+	// "_" only closes at the end of a word so that snake_case survives
+	if closer == "_" {
+		return !p.isWordRune(p.pos + 1)
+	}
+	return true
+}
+
+// parseLiteral parses a code or math span at the current position
+func (p *inlineParser) parseLiteral() ([]*Node, bool) {
+	for _, literal := range literals {
+		if !p.hasPrefix(literal.marker) {
+			continue
+		}
+		start := p.pos
+		if literal.nodeType == NodeTypeMath && !p.opensAt(literal.marker) {
+			// "$ 5" is a dollar sign, not math
+			p.pos++
+			return []*Node{{Type: NodeTypeText, Text: literal.marker}}, true
+		}
+		end := p.indexFrom(literal.marker, start+1)
+		if end < 0 && literal.nodeType == NodeTypeMath && p.isDigit(start+1) {
+			// An unclosed "$" before a number is a currency amount (e.g. "$10")
+			p.pos++
+			return []*Node{{Type: NodeTypeText, Text: literal.marker}}, true
+		}
+		if end < 0 {
+			p.errors = append(p.errors, NewMarkupError(CodeUnclosedMarkup,
+				fmt.Sprintf("unclosed %s %q", literal.nodeType, literal.marker), literal.marker, start+1))
+			p.pos++
+			return []*Node{{Type: NodeTypeText, Text: literal.marker}}, true
+		}
+		content := string(p.runes[start+1 : end])
+		p.pos = end + 1
+		if content == "" {
+			p.errors = append(p.errors, NewMarkupError(CodeEmptyMarkup,
+				fmt.Sprintf("empty %s %q", literal.nodeType, literal.marker), literal.marker, start+1))
+			return []*Node{{Type: NodeTypeText, Text: literal.marker + literal.marker}}, true
+		}
+		return []*Node{{Type: literal.nodeType, Text: content}}, true
+	}
+	return nil, false
+}
+
+// parseContainer parses a bold, italic, subscript or superscript span at the current position
+func (p *inlineParser) parseContainer() ([]*Node, bool) {
+	for _, container := range containers {
+		if !p.hasPrefix(container.marker) {
+			continue
+		}
+		start := p.pos
+		if !p.opensAt(container.marker) {
+			// A marker that ends a word but was never opened (e.g. "here**")
+			if p.endsWordAt(container.marker) {
+				p.errors = append(p.errors, NewMarkupError(CodeUnopenedMarkup,
+					fmt.Sprintf("closing %s %q without an opening marker", container.nodeType, container.marker), container.marker, start+1))
+			}
+			p.pos += len([]rune(container.marker))
+			return []*Node{{Type: NodeTypeText, Text: container.marker}}, true
+		}
+		p.pos += len([]rune(container.marker))
+		children, closed := p.parseUntil(container.marker)
+		if !closed {
+			p.errors = append(p.errors, NewMarkupError(CodeUnclosedMarkup,
+				fmt.Sprintf("unclosed %s %q", container.nodeType, container.marker), container.marker, start+1))
+			return append([]*Node{{Type: NodeTypeText, Text: container.marker}}, children...), true
+		}
+		if len(children) == 0 {
+			p.errors = append(p.errors, NewMarkupError(CodeEmptyMarkup,
+				fmt.Sprintf("empty %s %q", container.nodeType, container.marker), container.marker, start+1))
+			return []*Node{{Type: NodeTypeText, Text: container.marker + container.marker}}, true
+		}
+		return []*Node{{Type: container.nodeType, Children: mergeText(children)}}, true
+	}
+	return nil, false
+}
+
+// opensAt reports whether marker at the current position starts a span.
+// A marker followed by whitespace is literal (e.g. "2 * 3"), and "_" only
+// opens at the start of a word.
+func (p *inlineParser) opensAt(marker string) bool {
+	next := p.pos + len([]rune(marker))
+	if next >= len(p.runes) || unicode.IsSpace(p.runes[next]) {
+		return false
+	}
+	if marker == "_" {
+		return !p.isWordRune(p.pos - 1)
+	}
+	return true
+}
+
+// endsWordAt reports whether marker at the current position directly follows
+// text and is followed by whitespace or the end of the line
+func (p *inlineParser) endsWordAt(marker string) bool {
+	if p.pos == 0 || unicode.IsSpace(p.runes[p.pos-1]) {
+		return false
+	}
+	next := p.pos + len([]rune(marker))
+	return next >= len(p.runes) || unicode.IsSpace(p.runes[next])
+}
+
+func (p *inlineParser) hasPrefix(marker string) bool {
+	return strings.HasPrefix(string(p.runes[p.pos:]), marker)
+}
+
+func (p *inlineParser) indexFrom(marker string, from int) int {
+	target := []rune(marker)[0]
+	for i := from; i < len(p.runes); i++ {
+		if p.runes[i] == '\\' {
+			i++
+			continue
+		}
+		if p.runes[i] != target {
+			continue
+		}
+		// Like pandoc, a closing "$" follows a non-space and is not followed by a digit
+		if marker == "$" && (unicode.IsSpace(p.runes[i-1]) || p.isDigit(i+1)) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func (p *inlineParser) isDigit(i int) bool {
+	return i >= 0 && i < len(p.runes) && unicode.IsDigit(p.runes[i])
+}
+
+func (p *inlineParser) isWordRune(i int) bool {
+	if i < 0 || i >= len(p.runes) {
+		return false
+	}
+	r := p.runes[i]
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// mergeText joins adjacent text nodes
+func mergeText(nodes []*Node) []*Node {
+	var merged []*Node
+	for _, node := range nodes {
+		if last := len(merged) - 1; last >= 0 && node.Type == NodeTypeText && merged[last].Type == NodeTypeText {
+			merged[last] = &Node{Type: NodeTypeText, Text: merged[last].Text + node.Text}
+			continue
+		}
+		merged = append(merged, node)
+	}
+	return merged
+}
@@ -0,0 +1,98 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+// render writes nodes in a compact form, e.g. "text(a) bold(text(b))"
+func render(nodes []*Node) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		if node.Children != nil {
+			parts[i] = string(node.Type) + "(" + render(node.Children) + ")"
+		} else {
+			parts[i] = string(node.Type) + "(" + node.Text + ")"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		plain string
+	}{
+		{"Plain text", "text(Plain text)", "Plain text"},
+		{"A **bold** claim", "text(A ) bold(text(bold)) text( claim)", "A bold claim"},
+		{"*italic* and _also italic_", "italic(text(italic)) text( and ) italic(text(also italic))", "italic and also italic"},
+		{"**bold with *italic* inside**", "bold(text(bold with ) italic(text(italic)) text( inside))", "bold with italic inside"},
+		{"Call `fmt.Println(*x)`", "text(Call ) code(fmt.Println(*x))", "Call fmt.Println(*x)"},
+		{"Energy is $E=mc^2$", "text(Energy is ) math(E=mc^2)", "Energy is E=mc^2"},
+		{"H~2~O and x^2^", "text(H) subscript(text(2)) text(O and x) superscript(text(2))", "H2O and x2"},
+		{"It costs \\$5", "text(It costs $5)", "It costs $5"},
+		{"Tim had $10 and gave Mike $5", "text(Tim had $10 and gave Mike $5)", "Tim had $10 and gave Mike $5"},
+		{"$x$ costs $5", "math(x) text( costs $5)", "x costs $5"},
+		{"snake_case_name stays", "text(snake_case_name stays)", "snake_case_name stays"},
+		{"2 * 3 * 4", "text(2 * 3 * 4)", "2 * 3 * 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			nodes, errs := Parse(tt.input)
+			if len(errs) > 0 {
+				t.Fatalf("Parse() unexpected errors: %v", errs)
+			}
+			if got := render(nodes); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+			if got := PlainText(nodes); got != tt.plain {
+				t.Errorf("PlainText() = %q, want %q", got, tt.plain)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		input  string
+		code   ErrorCode
+		marker string
+		column int
+	}{
+		{"Energy is $E=mc2", CodeUnclosedMarkup, "$", 11},
+		{"A **bold claim", CodeUnclosedMarkup, "**", 3},
+		{"Run `go test", CodeUnclosedMarkup, "`", 5},
+		{"Nothing ** here**", CodeUnopenedMarkup, "**", 16},
+		{"Empty $$ math", CodeEmptyMarkup, "$", 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			nodes, errs := Parse(tt.input)
+			if len(errs) != 1 {
+				t.Fatalf("Parse() errors = %v, want 1 error", errs)
+			}
+			err := errs[0]
+			if err.Code != tt.code || err.Marker != tt.marker || err.Column != tt.column {
+				t.Errorf("Parse() error = %+v, want code %s marker %q column %d", err, tt.code, tt.marker, tt.column)
+			}
+			// Malformed markup keeps every character of the input
+			if got := PlainText(nodes); got != tt.input {
+				t.Errorf("PlainText() = %q, want %q", got, tt.input)
+			}
+		})
+	}
+}
+
+func TestIsPlain(t *testing.T) {
+	plain, _ := Parse("No markup")
+	if !IsPlain(plain) {
+		t.Error("IsPlain() = false for text without markup")
+	}
+	rich, _ := Parse("Some **markup**")
+	if IsPlain(rich) {
+		t.Error("IsPlain() = true for text with markup")
+	}
+}
@@ -1,5 +1,7 @@
 package ontology
 
+import "strings"
+
 // FindTagOntology finds the appropriate TagOntology entry for a given context and depth
 func FindTagOntology(contextType ContextType, depth int) *TagOntology {
 	for _, ontology := range tagOntology {
@@ -20,3 +22,33 @@ func AssignTagType(tag interface{}, contextType ContextType, depth int) {
 		// This is just a helper function to find the ontology
 	}
 }
+
+// ParseContextType returns the known context type matching name, ignoring case
+func ParseContextType(name string) (ContextType, bool) {
+	for _, contextType := range ContextTypes {
+		if strings.EqualFold(string(contextType), name) {
+			return contextType, true
+		}
+	}
+	return "", false
+}
+
+// ParseBloomLevel returns the level of Bloom's taxonomy matching name, ignoring case
+func ParseBloomLevel(name string) (BloomLevel, bool) {
+	for _, level := range BloomLevels {
+		if strings.EqualFold(string(level), name) {
+			return level, true
+		}
+	}
+	return "", false
+}
+
+// ParseContentRating returns the known content rating matching name, ignoring case
+func ParseContentRating(name string) (ContentRatingType, bool) {
+	for _, rating := range ContentRatings {
+		if strings.EqualFold(string(rating), name) {
+			return rating, true
+		}
+	}
+	return "", false
+}
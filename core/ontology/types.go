@@ -65,3 +65,100 @@ const (
 	ContentRatingAdultsOnly    ContentRatingType = "AdultsOnly"
 	ContentRatingRatingPending ContentRatingType = "RatingPending"
 )
+
+// QuestionType identifies how a question is answered
+type QuestionType string
+
+const (
+	QuestionTypeShortAnswer QuestionType = "ShortAnswer"
+	QuestionTypeTrueFalse   QuestionType = "TrueFalse"
+	QuestionTypeMultiSelect QuestionType = "MultiSelect"
+	QuestionTypeOrdering    QuestionType = "Ordering"
+	QuestionTypeMatching    QuestionType = "Matching"
+	QuestionTypeCloze       QuestionType = "Cloze"
+)
+
+// AnswerType says what kind of value an answer holds, whatever the question type
+type AnswerType string
+
+const (
+	AnswerTypeNumeric    AnswerType = "Numeric"    // A number, with or without a unit (e.g. "9.8 m/s^2")
+	AnswerTypeDate       AnswerType = "Date"       // A calendar date or year (e.g. "July 4, 1776")
+	AnswerTypeBoolean    AnswerType = "Boolean"    // True or false, yes or no
+	AnswerTypeList       AnswerType = "List"       // Several items (e.g. "red, green and blue")
+	AnswerTypeProperNoun AnswerType = "ProperNoun" // A name (e.g. "Albert Einstein")
+	AnswerTypeFreeText   AnswerType = "FreeText"   // Anything else
+)
+
+// BloomLevel is the level of Bloom's taxonomy a question exercises, from recalling facts
+// to producing new work
+type BloomLevel string
+
+const (
+	BloomLevelRemember   BloomLevel = "Remember"
+	BloomLevelUnderstand BloomLevel = "Understand"
+	BloomLevelApply      BloomLevel = "Apply"
+	BloomLevelAnalyze    BloomLevel = "Analyze"
+	BloomLevelEvaluate   BloomLevel = "Evaluate"
+	BloomLevelCreate     BloomLevel = "Create"
+)
+
+// The range of question difficulties, from easiest to hardest
+const (
+	MinDifficulty = 1
+	MaxDifficulty = 5
+)
+
+// ContextTypes lists every known context type
+var ContextTypes = []ContextType{
+	ContextTypeCollege,
+	ContextTypeCertifications,
+	ContextTypeEntranceExams,
+	ContextTypeAPExams,
+	ContextTypeUserGeneratedContent,
+	ContextTypeDoD,
+	ContextTypeEncyclopedia,
+	ContextTypeGeneral,
+	ContextTypeHighSchool,
+	ContextTypeNone,
+}
+
+// ContentRatings lists every known content rating
+var ContentRatings = []ContentRatingType{
+	ContentRatingEveryone,
+	ContentRatingEveryone10,
+	ContentRatingTeen,
+	ContentRatingMature,
+	ContentRatingAdultsOnly,
+	ContentRatingRatingPending,
+}
+
+// QuestionTypes lists every known question type
+var QuestionTypes = []QuestionType{
+	QuestionTypeShortAnswer,
+	QuestionTypeTrueFalse,
+	QuestionTypeMultiSelect,
+	QuestionTypeOrdering,
+	QuestionTypeMatching,
+	QuestionTypeCloze,
+}
+
+// AnswerTypes lists every known answer type
+var AnswerTypes = []AnswerType{
+	AnswerTypeNumeric,
+	AnswerTypeDate,
+	AnswerTypeBoolean,
+	AnswerTypeList,
+	AnswerTypeProperNoun,
+	AnswerTypeFreeText,
+}
+
+// BloomLevels lists every level of Bloom's taxonomy, from the lowest to the highest
+var BloomLevels = []BloomLevel{
+	BloomLevelRemember,
+	BloomLevelUnderstand,
+	BloomLevelApply,
+	BloomLevelAnalyze,
+	BloomLevelEvaluate,
+	BloomLevelCreate,
+}
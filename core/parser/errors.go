@@ -12,6 +12,10 @@ type ErrorCode string
 const (
 	CodeValidation ErrorCode = "VALIDATION"
 	CodeProcessing ErrorCode = "PROCESSING"
+
+	// CodeStrayContent marks content after a question outside any passage. It is a
+	// warning: the content is kept as notes of the header.
+	CodeStrayContent ErrorCode = "STRAY_CONTENT"
 )
 
 // ParserError represents a parsing error with context
@@ -5,14 +5,25 @@ import (
 	"time"
 
 	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
 	"github.com/studyguides-com/study-guides-parser/core/lexer"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
 	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
 )
 
 type Parser struct {
 	Root    *Node // The root node representing the entire tree (now it will be the file header)
 	Current *Node // The current "open" node (e.g., a header or passage)
 	Lines   []preparser.ParsedLineInfo
+	// Warnings are problems that do not stop parsing, such as stray content after a question
+	Warnings []*ParserError
+
+	keepComments  bool                     // Whether comments are kept as author notes
+	pendingNotes  []string                 // Comments waiting for the next node
+	pendingParams []templates.Param        // Parameters waiting for the next question
+	paramLine     preparser.ParsedLineInfo // The first "@param" line of pendingParams
+	last          *Node                    // The most recently created node
 }
 
 func NewParser(lines []preparser.ParsedLineInfo) *Parser {
@@ -21,6 +32,27 @@ func NewParser(lines []preparser.ParsedLineInfo) *Parser {
 	}
 }
 
+// WithComments keeps comments as author notes on the node that follows them.
+// Comments after the last node are attached to that node.
+func (p *Parser) WithComments(keep bool) *Parser {
+	p.keepComments = keep
+	return p
+}
+
+// newNode creates a node for line under parent, attaching any pending author notes
+func (p *Parser) newNode(line preparser.ParsedLineInfo, parent *Node) *Node {
+	node := &Node{
+		Type:        line.Type,
+		Data:        line.ParsedValue,
+		Children:    []*Node{},
+		Parent:      parent,
+		AuthorNotes: p.pendingNotes,
+	}
+	p.pendingNotes = nil
+	p.last = node
+	return node
+}
+
 // Helper to add node under current node if current is of expected type
 func (p *Parser) addUnderCurrent(expected lexer.TokenType, line preparser.ParsedLineInfo) *ParserError {
 	if p.Current == nil {
@@ -29,12 +61,7 @@ func (p *Parser) addUnderCurrent(expected lexer.TokenType, line preparser.Parsed
 	if p.Current.Type != expected {
 		return NewParserError(CodeValidation, fmt.Sprintf(" unexpected %s under %s", line.Type, p.Current.Type), line)
 	}
-	node := &Node{
-		Type:     line.Type,
-		Data:     line.ParsedValue,
-		Children: []*Node{},
-		Parent:   p.Current,
-	}
+	node := p.newNode(line, p.Current)
 	p.Current.Children = append(p.Current.Children, node)
 	return nil
 }
@@ -81,20 +108,22 @@ func (p *Parser) Parse(metadata *config.Metadata) (*AbstractSyntaxTree, *ParserE
 		Children: []*Node{},
 	}
 	p.Current = p.Root
+	p.last = p.Root
 
 	// Process the remaining lines
 	for _, line := range p.Lines[1:] {
+		if len(p.pendingParams) > 0 && line.Type != lexer.TokenTypeQuestion && line.Type != lexer.TokenTypeDirective &&
+			line.Type != lexer.TokenTypeComment && line.Type != lexer.TokenTypeEmpty {
+			return nil, NewParserError(CodeValidation,
+				fmt.Sprintf("@param must be followed by a %s, found %s", lexer.TokenTypeQuestion, line.Type), line)
+		}
+
 		switch line.Type {
 
 		// Header
 		case lexer.TokenTypeHeader:
-			node := &Node{
-				Type:     lexer.TokenTypeHeader,
-				Data:     line.ParsedValue,
-				Children: []*Node{},
-			}
+			node := p.newNode(line, p.Root)
 			p.Root.Children = append(p.Root.Children, node)
-			node.Parent = p.Root
 			p.Current = node
 
 		// Passage
@@ -104,12 +133,7 @@ func (p *Parser) Parse(metadata *config.Metadata) (*AbstractSyntaxTree, *ParserE
 			if parent == nil {
 				return nil, NewParserError(CodeValidation, fmt.Sprintf("%s without parent %s", line.Type, lexer.TokenTypeHeader), line)
 			}
-			node := &Node{
-				Type:     lexer.TokenTypePassage,
-				Data:     line.ParsedValue,
-				Children: []*Node{},
-				Parent:   parent,
-			}
+			node := p.newNode(line, parent)
 			parent.Children = append(parent.Children, node)
 			p.Current = node
 
@@ -130,32 +154,27 @@ func (p *Parser) Parse(metadata *config.Metadata) (*AbstractSyntaxTree, *ParserE
 
 				if isInsidePassage {
 					// We're inside a passage, add content as child of the passage
-					node := &Node{
-						Type:     lexer.TokenTypeContent,
-						Data:     line.ParsedValue,
-						Children: []*Node{},
-						Parent:   passageParent,
-					}
+					node := p.newNode(line, passageParent)
 					passageParent.Children = append(passageParent.Children, node)
 				} else {
 					// We're not inside a passage, add content as child of the current context
-					node := &Node{
-						Type:     lexer.TokenTypeContent,
-						Data:     line.ParsedValue,
-						Children: []*Node{},
-						Parent:   p.Current,
-					}
+					node := p.newNode(line, p.Current)
 					p.Current.Children = append(p.Current.Children, node)
 				}
 			} else {
-				// No passage found, add to current context
-				node := &Node{
-					Type:     lexer.TokenTypeContent,
-					Data:     line.ParsedValue,
-					Children: []*Node{},
-					Parent:   p.Current,
+				// No passage found, add to the header as notes. Content after a question
+				// probably belongs to a passage whose "Passage:" line is missing.
+				parent := p.findNearest(lexer.TokenTypeHeader)
+				if parent == nil {
+					parent = p.Current
 				}
-				p.Current.Children = append(p.Current.Children, node)
+				if p.Current.Type != lexer.TokenTypeHeader && parent.Type == lexer.TokenTypeHeader {
+					p.Warnings = append(p.Warnings, NewParserError(CodeStrayContent,
+						fmt.Sprintf("%s after a %s is kept as notes of the %s; add a 'Passage:' line above it if it belongs to a passage",
+							line.Type, p.Current.Type, lexer.TokenTypeHeader), line))
+				}
+				node := p.newNode(line, parent)
+				parent.Children = append(parent.Children, node)
 			}
 
 		// Question
@@ -168,26 +187,130 @@ func (p *Parser) Parse(metadata *config.Metadata) (*AbstractSyntaxTree, *ParserE
 			if parent == nil {
 				return nil, NewParserError(CodeValidation, "question without valid parent", line)
 			}
-			node := &Node{
-				Type:     lexer.TokenTypeQuestion,
-				Data:     line.ParsedValue,
-				Children: []*Node{},
-				Parent:   parent,
+			node := p.newNode(line, parent)
+			if err := p.attachTemplate(node, line); err != nil {
+				return nil, err
 			}
 			parent.Children = append(parent.Children, node)
 			p.Current = node
 
+		// Empty lines inside a passage mark paragraph breaks
+		case lexer.TokenTypeEmpty:
+			if p.Current != nil && p.Current.Type == lexer.TokenTypePassage {
+				node := p.newNode(line, p.Current)
+				p.Current.Children = append(p.Current.Children, node)
+			}
+
 		// LearnMore
 		case lexer.TokenTypeLearnMore:
-			// Add the learn more under the current question
-			if err := p.addUnderCurrent(lexer.TokenTypeQuestion, line); err != nil {
+			// Add the learn more under the current question, passage or header
+			if p.Current == nil || (p.Current.Type != lexer.TokenTypeQuestion &&
+				p.Current.Type != lexer.TokenTypePassage && p.Current.Type != lexer.TokenTypeHeader) {
+				return nil, NewParserError(CodeValidation, fmt.Sprintf("%s without parent %s, %s or %s",
+					line.Type, lexer.TokenTypeQuestion, lexer.TokenTypePassage, lexer.TokenTypeHeader), line)
+			}
+			if err := p.addUnderCurrent(p.Current.Type, line); err != nil {
+				return nil, err
+			}
+
+		// Code blocks belong to the question or passage they follow
+		case lexer.TokenTypeCodeStart:
+			if p.Current == nil || (p.Current.Type != lexer.TokenTypeQuestion && p.Current.Type != lexer.TokenTypePassage) {
+				return nil, NewParserError(CodeValidation, fmt.Sprintf("%s without parent %s or %s", line.Type, lexer.TokenTypeQuestion, lexer.TokenTypePassage), line)
+			}
+			node := p.newNode(line, p.Current)
+			p.Current.Children = append(p.Current.Children, node)
+			p.Current = node
+
+		case lexer.TokenTypeCode:
+			if err := p.addUnderCurrent(lexer.TokenTypeCodeStart, line); err != nil {
 				return nil, err
 			}
 
+		case lexer.TokenTypeCodeEnd:
+			if p.Current == nil || p.Current.Type != lexer.TokenTypeCodeStart {
+				return nil, NewParserError(CodeValidation, fmt.Sprintf("%s without open %s", line.Type, lexer.TokenTypeCodeStart), line)
+			}
+			p.Current = p.Current.Parent
+
+		// Directives apply to the headers that follow them, so they belong to the file
+		// header and leave the current node open. Parameters belong to the next question
+		// and passage metadata to the passage it follows.
+		case lexer.TokenTypeDirective:
+			if directive := line.ParsedValue.GetDirective(); directive != nil && directive.Param != nil {
+				if len(p.pendingParams) == 0 {
+					p.paramLine = line
+				}
+				p.pendingParams = append(p.pendingParams, *directive.Param)
+				continue
+			}
+			if directive := line.ParsedValue.GetDirective(); directive != nil && directive.IsPassageMetadata() {
+				if err := p.addPassageMetadata(directive, line); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			node := p.newNode(line, p.Root)
+			p.Root.Children = append(p.Root.Children, node)
+
+		// Comments are kept for the next node when requested
+		case lexer.TokenTypeComment:
+			if comment := line.ParsedValue.GetComment(); comment != nil && p.keepComments {
+				p.pendingNotes = append(p.pendingNotes, comment.Text)
+			}
+
 		default:
 			continue
 		}
 	}
+	if len(p.pendingNotes) > 0 {
+		p.last.AuthorNotes = append(p.last.AuthorNotes, p.pendingNotes...)
+		p.pendingNotes = nil
+	}
+	if len(p.pendingParams) > 0 {
+		return nil, NewParserError(CodeValidation,
+			fmt.Sprintf("@param must be followed by a %s", lexer.TokenTypeQuestion), p.paramLine)
+	}
 
 	return p.finalize(metadata)
 }
+
+// attachTemplate makes a question preceded by "@param" directives a template question.
+// Only short-answer questions can be templates.
+func (p *Parser) attachTemplate(node *Node, line preparser.ParsedLineInfo) *ParserError {
+	if len(p.pendingParams) == 0 {
+		return nil
+	}
+	params := p.pendingParams
+	p.pendingParams = nil
+	question := line.ParsedValue.GetQuestion()
+	if question == nil {
+		return nil
+	}
+	if question.Type != "" && question.Type != ontology.QuestionTypeShortAnswer {
+		return NewParserError(CodeValidation, "only short-answer questions can be templates", line)
+	}
+	texts := append([]string{question.QuestionText, question.AnswerText}, question.Alternatives...)
+	template, err := templates.Compile(params, texts...)
+	if err != nil {
+		return NewParserError(ErrorCode(err.Code), err.Message, line)
+	}
+	node.Template = template
+	return nil
+}
+
+// addPassageMetadata adds a passage metadata directive to the open passage. The directive
+// must come before the passage's questions, and each kind may be given once per passage.
+func (p *Parser) addPassageMetadata(directive *preparser.DirectiveResult, line preparser.ParsedLineInfo) *ParserError {
+	if p.Current == nil || p.Current.Type != lexer.TokenTypePassage {
+		return NewParserError(CodeValidation, fmt.Sprintf("%s%s must follow a %s line, before its questions",
+			constants.DirectivePrefix, directive.Name, lexer.TokenTypePassage), line)
+	}
+	for _, child := range p.Current.Children {
+		if previous := child.Data.GetDirective(); previous != nil && previous.Name == directive.Name {
+			return NewParserError(CodeValidation, fmt.Sprintf("%s%s is already given for this %s",
+				constants.DirectivePrefix, directive.Name, lexer.TokenTypePassage), line)
+		}
+	}
+	return p.addUnderCurrent(lexer.TokenTypePassage, line)
+}
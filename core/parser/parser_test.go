@@ -9,6 +9,7 @@ import (
 
 	"github.com/studyguides-com/study-guides-parser/core/config"
 	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
 )
 
 func TestParserWithContentInPassage(t *testing.T) {
@@ -216,3 +217,250 @@ func TestParserWithContentInPassage(t *testing.T) {
 		t.Errorf("Expected 3 question children in first passage, got %d", questionCount)
 	}
 }
+
+func TestParserKeepsHeaderContent(t *testing.T) {
+	content := func(text string) preparser.ParsedLineInfo {
+		return preparser.ParsedLineInfo{
+			Type:        preparser.TokenTypeContent,
+			ParsedValue: preparser.ParsedValue{Content: &preparser.ContentResult{Text: text}},
+		}
+	}
+	lines := []preparser.ParsedLineInfo{
+		{
+			Type:        preparser.TokenTypeFileHeader,
+			ParsedValue: preparser.ParsedValue{FileHeader: &preparser.FileHeaderResult{Title: "TestFile"}},
+		},
+		{
+			Type:        preparser.TokenTypeHeader,
+			ParsedValue: preparser.ParsedValue{Header: &preparser.HeaderResult{Parts: []string{"TagA", "TagB", "TagC", "TagD"}}},
+		},
+		content("An introduction to the topic"),
+		{
+			Type:        preparser.TokenTypeQuestion,
+			ParsedValue: preparser.ParsedValue{Question: &preparser.QuestionResult{QuestionText: "What is 1 + 1?", AnswerText: "2"}},
+		},
+		content("Text that belongs to a passage"),
+	}
+	lines[4].Number = 5
+
+	p := NewParser(lines)
+	ast, err := p.Parse(config.NewMetadata("test"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	header := ast.Root.Children[0]
+	var types []preparser.TokenType
+	for _, child := range header.Children {
+		types = append(types, child.Type)
+	}
+	want := []preparser.TokenType{preparser.TokenTypeContent, preparser.TokenTypeQuestion, preparser.TokenTypeContent}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("header children = %v, want %v", types, want)
+	}
+
+	if len(p.Warnings) != 1 {
+		t.Fatalf("Warnings = %v, want 1 warning", p.Warnings)
+	}
+	if p.Warnings[0].Code != CodeStrayContent || p.Warnings[0].LineInfo.Number != 5 {
+		t.Errorf("warning = %s on line %d, want %s on line 5", p.Warnings[0].Code, p.Warnings[0].LineInfo.Number, CodeStrayContent)
+	}
+}
+
+func TestParserKeepsComments(t *testing.T) {
+	comment := func(text string) preparser.ParsedLineInfo {
+		return preparser.ParsedLineInfo{
+			Type:        preparser.TokenTypeComment,
+			ParsedValue: preparser.ParsedValue{Comment: &preparser.CommentResult{Text: text}},
+		}
+	}
+	lines := []preparser.ParsedLineInfo{
+		{
+			Type:        preparser.TokenTypeFileHeader,
+			ParsedValue: preparser.ParsedValue{FileHeader: &preparser.FileHeaderResult{Title: "TestFile"}},
+		},
+		comment("Reviewed by the math department"),
+		{
+			Type:        preparser.TokenTypeHeader,
+			ParsedValue: preparser.ParsedValue{Header: &preparser.HeaderResult{Parts: []string{"TagA", "TagB", "TagC", "TagD"}}},
+		},
+		comment("TODO: add a harder question"),
+		comment("Ask about carrying"),
+		{
+			Type:        preparser.TokenTypeQuestion,
+			ParsedValue: preparser.ParsedValue{Question: &preparser.QuestionResult{QuestionText: "What is 1 + 1?", AnswerText: "2"}},
+		},
+		comment("End of section"),
+	}
+
+	ast, err := NewParser(lines).Parse(config.NewMetadata("test"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if ast.Root.AuthorNotes != nil {
+		t.Errorf("comments are dropped by default, got %v", ast.Root.AuthorNotes)
+	}
+
+	ast, err = NewParser(lines).WithComments(true).Parse(config.NewMetadata("test"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	header := ast.Root.Children[0]
+	question := header.Children[0]
+	if got := fmt.Sprint(header.AuthorNotes); got != "[Reviewed by the math department]" {
+		t.Errorf("header AuthorNotes = %s", got)
+	}
+	if got := fmt.Sprint(question.AuthorNotes); got != "[TODO: add a harder question Ask about carrying End of section]" {
+		t.Errorf("question AuthorNotes = %s", got)
+	}
+}
+
+func TestParserKeepsDirectivesOnTheRoot(t *testing.T) {
+	directive := preparser.ParsedLineInfo{
+		Type:        preparser.TokenTypeDirective,
+		ParsedValue: preparser.ParsedValue{Directive: &preparser.DirectiveResult{Name: "context", Value: "APExams"}},
+	}
+	lines := []preparser.ParsedLineInfo{
+		{
+			Type:        preparser.TokenTypeFileHeader,
+			ParsedValue: preparser.ParsedValue{FileHeader: &preparser.FileHeaderResult{Title: "TestFile"}},
+		},
+		{
+			Type:        preparser.TokenTypeHeader,
+			ParsedValue: preparser.ParsedValue{Header: &preparser.HeaderResult{Parts: []string{"TagA", "TagB", "TagC", "TagD"}}},
+		},
+		{
+			Type:        preparser.TokenTypePassage,
+			ParsedValue: preparser.ParsedValue{Passage: &preparser.PassageResult{Text: "Passage title"}},
+		},
+		directive,
+		{
+			Type:        preparser.TokenTypeContent,
+			ParsedValue: preparser.ParsedValue{Content: &preparser.ContentResult{Text: "Still in the passage"}},
+		},
+	}
+
+	ast, err := NewParser(lines).Parse(config.NewMetadata("test"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(ast.Root.Children) != 2 || ast.Root.Children[1].Type != preparser.TokenTypeDirective {
+		t.Fatalf("root children = %v, want the header and the directive", ast.Root.Children)
+	}
+	passage := ast.Root.Children[0].Children[0]
+	if len(passage.Children) != 1 || passage.Children[0].Type != preparser.TokenTypeContent {
+		t.Errorf("passage children = %v, want the content after the directive", passage.Children)
+	}
+}
+
+func TestParserAttachesTemplatesToQuestions(t *testing.T) {
+	param := func(name string) preparser.ParsedLineInfo {
+		return preparser.ParsedLineInfo{
+			Number: 3,
+			Type:   preparser.TokenTypeDirective,
+			ParsedValue: preparser.ParsedValue{Directive: &preparser.DirectiveResult{
+				Name: "param", Value: name + " in 1..9", Param: &templates.Param{Name: name, Min: 1, Max: 9},
+			}},
+		}
+	}
+	question := func(prompt, answer string) preparser.ParsedLineInfo {
+		return preparser.ParsedLineInfo{
+			Type:        preparser.TokenTypeQuestion,
+			ParsedValue: preparser.ParsedValue{Question: &preparser.QuestionResult{QuestionText: prompt, AnswerText: answer}},
+		}
+	}
+	guide := func(lines ...preparser.ParsedLineInfo) []preparser.ParsedLineInfo {
+		return append([]preparser.ParsedLineInfo{
+			{
+				Type:        preparser.TokenTypeFileHeader,
+				ParsedValue: preparser.ParsedValue{FileHeader: &preparser.FileHeaderResult{Title: "TestFile"}},
+			},
+			{
+				Type:        preparser.TokenTypeHeader,
+				ParsedValue: preparser.ParsedValue{Header: &preparser.HeaderResult{Parts: []string{"TagA", "TagB", "TagC", "TagD"}}},
+			},
+		}, lines...)
+	}
+
+	ast, err := NewParser(guide(param("a"), param("b"), question("What is {a} + {b}?", "{a+b}"), question("Plain?", "Yes"))).Parse(config.NewMetadata("test"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	header := ast.Root.Children[0]
+	if len(ast.Root.Children) != 1 || len(header.Children) != 2 {
+		t.Fatalf("root children = %v, header children = %v, want the parameters on the question only", ast.Root.Children, header.Children)
+	}
+	if template := header.Children[0].Template; template == nil || len(template.Params) != 2 {
+		t.Errorf("Template = %+v, want a template with parameters a and b", template)
+	}
+	if header.Children[1].Template != nil {
+		t.Error("parameters should only apply to the question that follows them")
+	}
+
+	tests := map[string][]preparser.ParsedLineInfo{
+		"no question":       guide(param("a")),
+		"header in between": guide(param("a"), guide()[1], question("What is {a}?", "{a}")),
+		"unknown parameter": guide(param("a"), question("What is {a} + {b}?", "{a+b}")),
+	}
+	for name, lines := range tests {
+		if _, err := NewParser(lines).Parse(config.NewMetadata("test")); err == nil {
+			t.Errorf("%s: Parse() expected an error", name)
+		}
+	}
+}
+
+func TestParserAddsPassageMetadata(t *testing.T) {
+	directive := func(name, value string) preparser.ParsedLineInfo {
+		return preparser.ParsedLineInfo{
+			Type:        preparser.TokenTypeDirective,
+			ParsedValue: preparser.ParsedValue{Directive: &preparser.DirectiveResult{Name: name, Value: value}},
+		}
+	}
+	guide := func(lines ...preparser.ParsedLineInfo) []preparser.ParsedLineInfo {
+		return append([]preparser.ParsedLineInfo{
+			{
+				Type:        preparser.TokenTypeFileHeader,
+				ParsedValue: preparser.ParsedValue{FileHeader: &preparser.FileHeaderResult{Title: "TestFile"}},
+			},
+			{
+				Type:        preparser.TokenTypeHeader,
+				ParsedValue: preparser.ParsedValue{Header: &preparser.HeaderResult{Parts: []string{"TagA", "TagB", "TagC", "TagD"}}},
+			},
+		}, lines...)
+	}
+	passage := preparser.ParsedLineInfo{
+		Type:        preparser.TokenTypePassage,
+		ParsedValue: preparser.ParsedValue{Passage: &preparser.PassageResult{Text: "The River"}},
+	}
+	content := preparser.ParsedLineInfo{
+		Type:        preparser.TokenTypeContent,
+		ParsedValue: preparser.ParsedValue{Content: &preparser.ContentResult{Text: "The river was wide."}},
+	}
+	question := preparser.ParsedLineInfo{
+		Type:        preparser.TokenTypeQuestion,
+		ParsedValue: preparser.ParsedValue{Question: &preparser.QuestionResult{QuestionText: "How wide?", AnswerText: "Very"}},
+	}
+
+	ast, err := NewParser(guide(passage, directive("author", "Mark Twain"), content, directive("year", "1883"), question)).Parse(config.NewMetadata("test"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(ast.Root.Children) != 1 {
+		t.Errorf("root children = %v, want the metadata on the passage only", ast.Root.Children)
+	}
+	passageNode := ast.Root.Children[0].Children[0]
+	if len(passageNode.Children) != 4 || passageNode.Children[0].Data.GetDirective().Name != "author" {
+		t.Errorf("passage children = %v, want the author, content, year and question", passageNode.Children)
+	}
+
+	tests := map[string][]preparser.ParsedLineInfo{
+		"no passage":      guide(directive("author", "Mark Twain")),
+		"after questions": guide(passage, question, directive("author", "Mark Twain")),
+		"given twice":     guide(passage, directive("author", "Mark Twain"), directive("author", "Twain")),
+	}
+	for name, lines := range tests {
+		if _, err := NewParser(lines).Parse(config.NewMetadata("test")); err == nil {
+			t.Errorf("%s: Parse() expected an error", name)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/studyguides-com/study-guides-parser/core/config"
 	"github.com/studyguides-com/study-guides-parser/core/lexer"
 	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
 )
 
 // Node represents a single node in the parser syntax tree
@@ -12,6 +13,12 @@ type Node struct {
 	Data     preparser.ParsedValue `json:"data,omitempty"` // nullable
 	Children []*Node               `json:"children,omitempty"`
 	Parent   *Node                 `json:"-"` // already nullable
+
+	// Comments written before the node's line, kept only when the parser is asked to keep comments
+	AuthorNotes []string `json:"author_notes,omitempty"`
+
+	// The template of a question preceded by "@param" directives, which the builder expands
+	Template *templates.Template `json:"template,omitempty"`
 }
 
 // AbstractSyntaxTree represents the output of a parser tree
@@ -19,4 +26,7 @@ type AbstractSyntaxTree struct {
 	Metadata  *config.Metadata `json:"metadata"`
 	Timestamp string           `json:"timestamp"`
 	Root      *Node            `json:"root"`
+	// RemovedQuestions holds the numbers of the questions that "@if" blocks removed before
+	// parsing, which leave gaps in the numbering of the others
+	RemovedQuestions []int `json:"removed_questions,omitempty"`
 }
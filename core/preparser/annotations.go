@@ -0,0 +1,109 @@
+package preparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// Annotation keys accepted inside the braces at the end of a question line
+const (
+	annotationDifficulty = "difficulty"
+	annotationBloom      = "bloom"
+	annotationKeywords   = "keywords"
+)
+
+// splitAnnotations removes the annotation from the end of the rest of a question line and
+// parses it. Lines without an annotation are returned unchanged with nil annotations.
+func splitAnnotations(rest string, lineInfo LineInfo) (string, *Annotations, *PreParsingError) {
+	loc := regexes.AnnotationRegex.FindStringSubmatchIndex(rest)
+	if loc == nil {
+		return rest, nil, nil
+	}
+	annotations := &Annotations{}
+	seen := map[string]bool{}
+	for _, entry := range splitAnnotationEntries(rest[loc[2]:loc[3]]) {
+		key, value, found := strings.Cut(entry, ":")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !found || key == "" {
+			return "", nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("annotation %q must be written \"key: value\"", strings.TrimSpace(entry)), lineInfo)
+		}
+		if seen[key] {
+			return "", nil, NewPreParsingError(CodeValidation, fmt.Sprintf("annotation %q is given more than once", key), lineInfo)
+		}
+		seen[key] = true
+
+		switch key {
+		case annotationDifficulty:
+			difficulty, err := strconv.Atoi(value)
+			if err != nil || difficulty < ontology.MinDifficulty || difficulty > ontology.MaxDifficulty {
+				return "", nil, NewPreParsingError(CodeValidation,
+					fmt.Sprintf("difficulty %q must be a number from %d to %d", value, ontology.MinDifficulty, ontology.MaxDifficulty), lineInfo)
+			}
+			annotations.Difficulty = difficulty
+		case annotationBloom:
+			level, ok := ontology.ParseBloomLevel(value)
+			if !ok {
+				return "", nil, NewPreParsingError(CodeValidation,
+					fmt.Sprintf("unknown Bloom level %q, expected one of %s", value, bloomLevelNames()), lineInfo)
+			}
+			annotations.Bloom = level
+		case annotationKeywords:
+			keywords := parseKeywords(value)
+			if len(keywords) == 0 {
+				return "", nil, NewPreParsingError(CodeValidation, "keywords must not be empty", lineInfo)
+			}
+			annotations.Keywords = keywords
+		default:
+			return "", nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("unknown annotation %q, expected %s, %s or %s", key, annotationDifficulty, annotationBloom, annotationKeywords), lineInfo)
+		}
+	}
+	return strings.TrimSpace(rest[:loc[0]]), annotations, nil
+}
+
+// splitAnnotationEntries splits the text inside the braces on the commas outside brackets
+func splitAnnotationEntries(text string) []string {
+	var entries []string
+	depth, start := 0, 0
+	for i, r := range text {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				entries = append(entries, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(entries, text[start:])
+}
+
+// parseKeywords reads a keyword list, written "[kinematics, velocity]" or as a single keyword
+func parseKeywords(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var keywords []string
+	for _, keyword := range strings.Split(value, ",") {
+		if keyword = cleanstring.New(keyword).Clean(); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// bloomLevelNames lists the Bloom levels for error messages
+func bloomLevelNames() string {
+	names := make([]string, len(ontology.BloomLevels))
+	for i, level := range ontology.BloomLevels {
+		names[i] = strings.ToLower(string(level))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,54 @@
+package preparser
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// parseCloze parses a cloze question such as "1. The capital of {{c1::France}} is {{c2::Paris}}".
+// The whole sentence, including the deletions, is kept as the question text; a " - " is part
+// of the sentence rather than an answer delimiter.
+func parseCloze(match regexes.ListItemMatch, lineInfo LineInfo, version format.Version) (*QuestionResult, *PreParsingError) {
+	questionType, sentence := splitQuestionType(cleanstring.New(match.Rest).Clean(), version)
+	if questionType != ontology.QuestionTypeShortAnswer {
+		return nil, NewPreParsingError(CodeValidation,
+			fmt.Sprintf("cloze deletions cannot be used in a %s question", questionType), lineInfo)
+	}
+
+	deletions := regexes.ClozeRegex.FindAllStringSubmatch(sentence, -1)
+	if len(deletions) != len(regexes.ClozeOpenRegex.FindAllString(sentence, -1)) {
+		return nil, NewPreParsingError(CodeValidation, "malformed cloze deletion, expected {{c1::text}}", lineInfo)
+	}
+
+	clozes := make([]ClozeDeletion, 0, len(deletions))
+	for _, deletion := range deletions {
+		index, err := strconv.Atoi(deletion[1])
+		if err != nil || index < 1 {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("cloze index in %q must be a number from 1", deletion[0]), lineInfo)
+		}
+		text := cleanstring.New(deletion[2]).Clean()
+		if text == "" {
+			return nil, NewPreParsingError(CodeValidation, fmt.Sprintf("cloze deletion %q is empty", deletion[0]), lineInfo)
+		}
+		clozes = append(clozes, ClozeDeletion{
+			Index: index,
+			Text:  text,
+			Hint:  cleanstring.New(deletion[3]).Clean(),
+		})
+	}
+
+	return &QuestionResult{
+		QuestionText: sentence,
+		Prefix:       match.Raw,
+		Label:        match.Label,
+		Number:       match.Number,
+		Type:         ontology.QuestionTypeCloze,
+		Clozes:       clozes,
+	}, nil
+}
@@ -1,5 +1,7 @@
 package preparser
 
+import "github.com/studyguides-com/study-guides-parser/core/reference"
+
 // ParsedValue represents all possible parsed result types
 type ParsedValue struct {
 	Question   *QuestionResult   `json:"question,omitempty"`
@@ -11,6 +13,10 @@ type ParsedValue struct {
 	LearnMore  *LearnMoreResult  `json:"learn_more,omitempty"`
 	Content    *ContentResult    `json:"content,omitempty"`
 	Binary     *BinaryResult     `json:"binary,omitempty"`
+	CodeStart  *CodeStartResult  `json:"code_start,omitempty"`
+	Code       *CodeResult       `json:"code,omitempty"`
+	CodeEnd    *CodeEndResult    `json:"code_end,omitempty"`
+	Directive  *DirectiveResult  `json:"directive,omitempty"`
 }
 
 // GetQuestion returns the QuestionResult if this is a question, nil otherwise
@@ -48,6 +54,19 @@ func (pv ParsedValue) GetLearnMore() *LearnMoreResult {
 	return pv.LearnMore
 }
 
+// GetReferences returns the references found in a passage, Learn More or content line, nil otherwise
+func (pv ParsedValue) GetReferences() []reference.Reference {
+	switch {
+	case pv.Passage != nil:
+		return pv.Passage.References
+	case pv.LearnMore != nil:
+		return pv.LearnMore.References
+	case pv.Content != nil:
+		return pv.Content.References
+	}
+	return nil
+}
+
 // GetContent returns the ContentResult if this is content, nil otherwise
 func (pv ParsedValue) GetContent() *ContentResult {
 	return pv.Content
@@ -58,6 +77,26 @@ func (pv ParsedValue) GetBinary() *BinaryResult {
 	return pv.Binary
 }
 
+// GetCodeStart returns the CodeStartResult if this is a code block's opening fence, nil otherwise
+func (pv ParsedValue) GetCodeStart() *CodeStartResult {
+	return pv.CodeStart
+}
+
+// GetCode returns the CodeResult if this is a line inside a code block, nil otherwise
+func (pv ParsedValue) GetCode() *CodeResult {
+	return pv.Code
+}
+
+// GetCodeEnd returns the CodeEndResult if this is a code block's closing fence, nil otherwise
+func (pv ParsedValue) GetCodeEnd() *CodeEndResult {
+	return pv.CodeEnd
+}
+
+// GetDirective returns the DirectiveResult if this is a directive, nil otherwise
+func (pv ParsedValue) GetDirective() *DirectiveResult {
+	return pv.Directive
+}
+
 // IsQuestion returns true if this contains a QuestionResult
 func (pv ParsedValue) IsQuestion() bool {
 	return pv.Question != nil
@@ -103,6 +142,26 @@ func (pv ParsedValue) IsBinary() bool {
 	return pv.Binary != nil
 }
 
+// IsCodeStart returns true if this contains a CodeStartResult
+func (pv ParsedValue) IsCodeStart() bool {
+	return pv.CodeStart != nil
+}
+
+// IsCode returns true if this contains a CodeResult
+func (pv ParsedValue) IsCode() bool {
+	return pv.Code != nil
+}
+
+// IsCodeEnd returns true if this contains a CodeEndResult
+func (pv ParsedValue) IsCodeEnd() bool {
+	return pv.CodeEnd != nil
+}
+
+// IsDirective returns true if this contains a DirectiveResult
+func (pv ParsedValue) IsDirective() bool {
+	return pv.Directive != nil
+}
+
 type ParsedLineInfo struct {
 	Number      int         `json:"number"`       // Line number in the file
 	Text        string      `json:"text"`         // The actual text content
@@ -2,11 +2,19 @@ package preparser
 
 import (
 	"fmt"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
 )
 
 type Preparser struct {
-	ParserType string
-	Lines      []LineInfo
+	ParserType       string
+	Lines            []LineInfo
+	ListItemPrefixes []regexes.ListItemPrefix
+	Format           format.Version
+
+	listStyle regexes.NumberingStyle // The numbering style of the previous question of the current list
 }
 
 // NewPreparser creates a new Preparser instance with the given lines and parser type.
@@ -14,11 +22,27 @@ type Preparser struct {
 // parsed results with semantic information.
 func NewPreparser(lines []LineInfo, parserType string) *Preparser {
 	return &Preparser{
-		Lines:      lines,
-		ParserType: parserType,
+		Lines:            lines,
+		ParserType:       parserType,
+		ListItemPrefixes: regexes.DefaultListItemPrefixes,
+		Format:           format.Default,
 	}
 }
 
+// WithListItemPrefixes sets the list item prefixes accepted at the start of question lines.
+// They should match the prefixes the lexer used to classify the lines.
+func (p *Preparser) WithListItemPrefixes(prefixes []regexes.ListItemPrefix) *Preparser {
+	p.ListItemPrefixes = prefixes
+	return p
+}
+
+// WithFormat sets the version of the input format, which decides the directives accepted.
+// It should be the version the lexer read the lines as.
+func (p *Preparser) WithFormat(version format.Version) *Preparser {
+	p.Format = version
+	return p
+}
+
 // Parse processes all lines in the preparser and returns parsed line information.
 // Each line is processed according to its detected type (question, header, content, etc.)
 // and the result contains both the original line info and the parsed semantic data.
@@ -29,6 +53,7 @@ func NewPreparser(lines []LineInfo, parserType string) *Preparser {
 func (p *Preparser) Parse() ([]ParsedLineInfo, []*PreParsingError) {
 	parsedLines := make([]ParsedLineInfo, 0, len(p.Lines))
 	var allErrors []*PreParsingError
+	p.listStyle = ""
 
 	for _, line := range p.Lines {
 		result, err := p.parseLine(line)
@@ -54,13 +79,20 @@ func (p *Preparser) Parse() ([]ParsedLineInfo, []*PreParsingError) {
 func (p *Preparser) parseLine(line LineInfo) (ParsedValue, *PreParsingError) {
 	switch line.Type {
 	case TokenTypeQuestion:
-		result, err := ParseQuestion(line)
+		// "(i)" continues a lettered list as a letter and starts or continues any other list as a roman numeral
+		match, ok := regexes.MatchListItemPrefixAfter(cleanstring.New(line.Text).Clean(), p.ListItemPrefixes, p.listStyle)
+		if !ok {
+			return ParsedValue{}, NewPreParsingError(CodeValidation, "question must start with a number or bullet point", line)
+		}
+		p.listStyle = match.Prefix.Style
+		result, err := parseQuestionMatch(line, match, p.Format)
 		if err != nil {
 			return ParsedValue{}, err
 		}
 		return ParsedValue{Question: result}, nil
 
 	case TokenTypeHeader:
+		p.listStyle = ""
 		result, err := ParseHeader(line)
 		if err != nil {
 			return ParsedValue{}, err
@@ -89,6 +121,7 @@ func (p *Preparser) parseLine(line LineInfo) (ParsedValue, *PreParsingError) {
 		return ParsedValue{FileHeader: result}, nil
 
 	case TokenTypePassage:
+		p.listStyle = ""
 		result, err := ParsePassage(line)
 		if err != nil {
 			return ParsedValue{}, err
@@ -116,6 +149,26 @@ func (p *Preparser) parseLine(line LineInfo) (ParsedValue, *PreParsingError) {
 		}
 		return ParsedValue{Binary: result}, nil
 
+	case TokenTypeCodeStart:
+		result, err := ParseCodeStart(line)
+		if err != nil {
+			return ParsedValue{}, err
+		}
+		return ParsedValue{CodeStart: result}, nil
+
+	case TokenTypeCode:
+		return ParsedValue{Code: ParseCode(line)}, nil
+
+	case TokenTypeCodeEnd:
+		return ParsedValue{CodeEnd: &CodeEndResult{}}, nil
+
+	case TokenTypeDirective:
+		result, err := ParseDirectiveWithFormat(line, p.Format)
+		if err != nil {
+			return ParsedValue{}, err
+		}
+		return ParsedValue{Directive: result}, nil
+
 	default:
 		return ParsedValue{}, NewPreParsingError(CodeValidation, fmt.Sprintf("unknown line type: %v", line.Type), line)
 	}
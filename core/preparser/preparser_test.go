@@ -4,6 +4,8 @@ package preparser
 
 import (
 	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
 )
 
 func TestPreparser(t *testing.T) {
@@ -152,6 +154,33 @@ func TestPreparser(t *testing.T) {
 	}
 }
 
+func TestPreparserWrappedLabels(t *testing.T) {
+	lines := []LineInfo{
+		{Number: 1, Type: TokenTypeFileHeader, Text: "Study Guide"},
+		{Number: 2, Type: TokenTypeHeader, Text: "Section: Chapter 1: Letters"},
+		{Number: 3, Type: TokenTypeQuestion, Text: "(h) Eighth letter? - h"},
+		{Number: 4, Type: TokenTypeQuestion, Text: "(i) Ninth letter? - i"},
+		{Number: 5, Type: TokenTypeHeader, Text: "Section: Chapter 2: Numerals"},
+		{Number: 6, Type: TokenTypeQuestion, Text: "(i) One? - 1"},
+		{Number: 7, Type: TokenTypeQuestion, Text: "(iv) Four? - 4"},
+		{Number: 8, Type: TokenTypeQuestion, Text: "(v) Five? - 5"},
+	}
+
+	parsed, errs := NewPreparser(lines, "test").WithListItemPrefixes(regexes.ExtendedListItemPrefixes).Parse()
+	if len(errs) != 0 {
+		t.Fatalf("Parse() errors: %v", errs)
+	}
+	want := map[int]int{3: 8, 4: 9, 6: 1, 7: 4, 8: 5}
+	for _, line := range parsed {
+		if line.Type != TokenTypeQuestion {
+			continue
+		}
+		if got := line.ParsedValue.Question.Number; got != want[line.Number] {
+			t.Errorf("line %d Number = %d, want %d", line.Number, got, want[line.Number])
+		}
+	}
+}
+
 func TestPreparserParseLineBinary(t *testing.T) {
 	parser := NewPreparser([]LineInfo{}, "test")
 
@@ -0,0 +1,180 @@
+package preparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// questionTypeTags maps the tags accepted at the start of a prompt to question types
+var questionTypeTags = map[string]ontology.QuestionType{
+	"short":        ontology.QuestionTypeShortAnswer,
+	"short-answer": ontology.QuestionTypeShortAnswer,
+	"tf":           ontology.QuestionTypeTrueFalse,
+	"true/false":   ontology.QuestionTypeTrueFalse,
+	"true-false":   ontology.QuestionTypeTrueFalse,
+	"multi":        ontology.QuestionTypeMultiSelect,
+	"multi-select": ontology.QuestionTypeMultiSelect,
+	"order":        ontology.QuestionTypeOrdering,
+	"ordering":     ontology.QuestionTypeOrdering,
+	"match":        ontology.QuestionTypeMatching,
+	"matching":     ontology.QuestionTypeMatching,
+}
+
+// splitQuestionType removes a question type tag such as "[tf]" from the start of a prompt.
+// A bracketed word that is not a known tag, or any tag in a format without question types,
+// is left in the prompt.
+func splitQuestionType(prompt string, version format.Version) (ontology.QuestionType, string) {
+	if !version.Supports(format.FeatureQuestionTypes) {
+		return ontology.QuestionTypeShortAnswer, prompt
+	}
+	match := regexes.QuestionTypeTagRegex.FindStringSubmatch(prompt)
+	if match == nil {
+		return ontology.QuestionTypeShortAnswer, prompt
+	}
+	questionType, ok := questionTypeTags[strings.ToLower(strings.TrimSpace(match[1]))]
+	if !ok {
+		return ontology.QuestionTypeShortAnswer, prompt
+	}
+	return questionType, prompt[len(match[0]):]
+}
+
+// parseStructuredAnswer validates the answer of a typed question and fills in its structured
+// form. Short answers have acceptable answers and tolerances only in formats that support them.
+func parseStructuredAnswer(result *QuestionResult, lineInfo LineInfo, version format.Version) *PreParsingError {
+	switch result.Type {
+	case ontology.QuestionTypeShortAnswer:
+		if !version.Supports(format.FeatureAcceptableAnswers) {
+			return nil
+		}
+		return parseAcceptableAnswers(result, lineInfo)
+
+	case ontology.QuestionTypeTrueFalse:
+		var truth bool
+		switch strings.ToLower(result.AnswerText) {
+		case "true", "t":
+			truth = true
+		case "false", "f":
+			truth = false
+		default:
+			return NewPreParsingError(CodeValidation, "true/false answer must be 'True' or 'False'", lineInfo)
+		}
+		result.Truth = &truth
+
+	case ontology.QuestionTypeMultiSelect:
+		items, err := splitAnswerList(result.AnswerText, 1, "multi-select", lineInfo)
+		if err != nil {
+			return err
+		}
+		result.Items = items
+
+	case ontology.QuestionTypeOrdering:
+		items, err := splitAnswerList(result.AnswerText, 2, "ordering", lineInfo)
+		if err != nil {
+			return err
+		}
+		result.Items = items
+
+	case ontology.QuestionTypeMatching:
+		items, err := splitAnswerList(result.AnswerText, 2, "matching", lineInfo)
+		if err != nil {
+			return err
+		}
+		pairs, err := parseMatchPairs(items, lineInfo)
+		if err != nil {
+			return err
+		}
+		result.Pairs = pairs
+	}
+	return nil
+}
+
+// splitAnswerList splits an answer on ';' and checks that it has at least min distinct, non-empty items
+func splitAnswerList(answer string, min int, kind string, lineInfo LineInfo) ([]string, *PreParsingError) {
+	parts := strings.Split(answer, constants.AnswerListDelimiter)
+	items := make([]string, 0, len(parts))
+	seen := map[string]bool{}
+	for _, part := range parts {
+		item := cleanstring.New(part).Clean()
+		if item == "" {
+			return nil, NewPreParsingError(CodeValidation, fmt.Sprintf("%s answer must not contain empty items", kind), lineInfo)
+		}
+		key := strings.ToLower(item)
+		if seen[key] {
+			return nil, NewPreParsingError(CodeValidation, fmt.Sprintf("%s answer repeats %q", kind, item), lineInfo)
+		}
+		seen[key] = true
+		items = append(items, item)
+	}
+	if len(items) < min {
+		return nil, NewPreParsingError(CodeValidation,
+			fmt.Sprintf("%s answer must list at least %d items separated by '%s'", kind, min, constants.AnswerListDelimiter), lineInfo)
+	}
+	return items, nil
+}
+
+// parseMatchPairs splits each item of a matching answer into its two sides
+func parseMatchPairs(items []string, lineInfo LineInfo) ([]MatchPair, *PreParsingError) {
+	pairs := make([]MatchPair, 0, len(items))
+	rights := map[string]bool{}
+	for _, item := range items {
+		sides := strings.SplitN(item, constants.MatchPairDelimiter, 2)
+		if len(sides) != 2 {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("matching pair %q must be written 'left %s right'", item, constants.MatchPairDelimiter), lineInfo)
+		}
+		pair := MatchPair{
+			Left:  cleanstring.New(sides[0]).Clean(),
+			Right: cleanstring.New(sides[1]).Clean(),
+		}
+		if pair.Left == "" || pair.Right == "" {
+			return nil, NewPreParsingError(CodeValidation, fmt.Sprintf("matching pair %q must have text on both sides", item), lineInfo)
+		}
+		// Left sides are distinct because the whole items are; right sides must be too
+		key := strings.ToLower(pair.Right)
+		if rights[key] {
+			return nil, NewPreParsingError(CodeValidation, fmt.Sprintf("matching answer repeats %q", pair.Right), lineInfo)
+		}
+		rights[key] = true
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// parseAcceptableAnswers splits a short answer into the answer shown and its alternatives,
+// and reads a numeric tolerance from the shown answer, which keeps it as written
+func parseAcceptableAnswers(result *QuestionResult, lineInfo LineInfo) *PreParsingError {
+	if strings.Contains(result.AnswerText, constants.AlternativeAnswerDelimiter) {
+		parts := strings.Split(result.AnswerText, constants.AlternativeAnswerDelimiter)
+		seen := map[string]bool{}
+		for i, part := range parts {
+			answer := cleanstring.New(part).Clean()
+			if answer == "" {
+				return NewPreParsingError(CodeValidation, "acceptable answers must not be empty", lineInfo)
+			}
+			key := strings.ToLower(answer)
+			if seen[key] {
+				return NewPreParsingError(CodeValidation, fmt.Sprintf("acceptable answers repeat %q", answer), lineInfo)
+			}
+			seen[key] = true
+			if i == 0 {
+				result.AnswerText = answer
+			} else {
+				result.Alternatives = append(result.Alternatives, answer)
+			}
+		}
+	}
+
+	if match := regexes.ToleranceRegex.FindStringSubmatch(result.AnswerText); match != nil {
+		// Both groups are validated by the regex
+		tolerance, _ := strconv.ParseFloat(match[2], 64)
+		result.Tolerance = tolerance
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+//go:build !prod
+
+package preparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+func TestParseQuestionTypes(t *testing.T) {
+	truth := true
+	tests := []struct {
+		name       string
+		text       string
+		wantType   ontology.QuestionType
+		wantPrompt string
+		wantTruth  *bool
+		wantItems  []string
+		wantPairs  []MatchPair
+		wantErr    bool
+	}{
+		{
+			name:       "untagged question is a short answer",
+			text:       "1. What is Go? - A language",
+			wantType:   ontology.QuestionTypeShortAnswer,
+			wantPrompt: "What is Go?",
+		},
+		{
+			name:       "unknown tag stays in the prompt",
+			text:       "1. [Unit 2] What is Go? - A language",
+			wantType:   ontology.QuestionTypeShortAnswer,
+			wantPrompt: "[Unit 2] What is Go?",
+		},
+		{
+			name:       "true/false",
+			text:       "1. [TF] The sun is a star. - True",
+			wantType:   ontology.QuestionTypeTrueFalse,
+			wantPrompt: "The sun is a star.",
+			wantTruth:  &truth,
+		},
+		{
+			name:    "true/false with another answer",
+			text:    "1. [tf] The sun is a star. - Yes",
+			wantErr: true,
+		},
+		{
+			name:       "multi-select",
+			text:       "2. [multi] Which numbers are prime? - 2; 3; 5",
+			wantType:   ontology.QuestionTypeMultiSelect,
+			wantPrompt: "Which numbers are prime?",
+			wantItems:  []string{"2", "3", "5"},
+		},
+		{
+			name:    "multi-select with an empty item",
+			text:    "2. [multi] Which numbers are prime? - 2; ; 5",
+			wantErr: true,
+		},
+		{
+			name:       "ordering",
+			text:       "3. [order] Order the planets from the sun. - Mercury; Venus; Earth",
+			wantType:   ontology.QuestionTypeOrdering,
+			wantPrompt: "Order the planets from the sun.",
+			wantItems:  []string{"Mercury", "Venus", "Earth"},
+		},
+		{
+			name:    "ordering with one item",
+			text:    "3. [order] Order the planets from the sun. - Mercury",
+			wantErr: true,
+		},
+		{
+			name:    "ordering with a repeated item",
+			text:    "3. [order] Order the planets from the sun. - Mercury; Venus; mercury",
+			wantErr: true,
+		},
+		{
+			name:       "matching",
+			text:       "4. [match] Match each country to its capital. - France = Paris; Spain = Madrid",
+			wantType:   ontology.QuestionTypeMatching,
+			wantPrompt: "Match each country to its capital.",
+			wantPairs:  []MatchPair{{"France", "Paris"}, {"Spain", "Madrid"}},
+		},
+		{
+			name:    "matching pair without a delimiter",
+			text:    "4. [match] Match each country to its capital. - France = Paris; Spain",
+			wantErr: true,
+		},
+		{
+			name:    "matching with a repeated right side",
+			text:    "4. [match] Match each city to its country. - Paris = France; Lyon = France",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuestionWithFormat(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: tt.text},
+				regexes.DefaultListItemPrefixes, format.Version2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuestionWithFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", got.Type, tt.wantType)
+			}
+			if got.QuestionText != tt.wantPrompt {
+				t.Errorf("QuestionText = %q, want %q", got.QuestionText, tt.wantPrompt)
+			}
+			if !reflect.DeepEqual(got.Truth, tt.wantTruth) {
+				t.Errorf("Truth = %v, want %v", got.Truth, tt.wantTruth)
+			}
+			if !reflect.DeepEqual(got.Items, tt.wantItems) {
+				t.Errorf("Items = %q, want %q", got.Items, tt.wantItems)
+			}
+			if !reflect.DeepEqual(got.Pairs, tt.wantPairs) {
+				t.Errorf("Pairs = %+v, want %+v", got.Pairs, tt.wantPairs)
+			}
+		})
+	}
+}
+
+func TestParseAcceptableAnswers(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		wantAnswer       string
+		wantAlternatives []string
+		wantTolerance    float64
+		wantErr          bool
+	}{
+		{
+			name:       "single answer",
+			text:       "1. Capital of France? - Paris",
+			wantAnswer: "Paris",
+		},
+		{
+			name:             "alternatives",
+			text:             "1. Capital of France? - Paris | City of Light |  Lutetia ",
+			wantAnswer:       "Paris",
+			wantAlternatives: []string{"City of Light", "Lutetia"},
+		},
+		{
+			name:       "pipe without spaces is part of the answer",
+			text:       "1. Absolute value of -3? - |-3|",
+			wantAnswer: "|-3|",
+		},
+		{
+			name:    "empty alternative",
+			text:    "1. Capital of France? - Paris |  | Lutetia",
+			wantErr: true,
+		},
+		{
+			name:    "repeated alternative",
+			text:    "1. Capital of France? - Paris | paris",
+			wantErr: true,
+		},
+		{
+			name:          "tolerance",
+			text:          "1. Acceleration of gravity in m/s²? - 9.8 ± 0.1",
+			wantAnswer:    "9.8 ± 0.1",
+			wantTolerance: 0.1,
+		},
+		{
+			name:             "ascii tolerance with an alternative",
+			text:             "1. Boiling point of water in °F? - 212 +/- 1 | 100 °C",
+			wantAnswer:       "212 +/- 1",
+			wantAlternatives: []string{"100 °C"},
+			wantTolerance:    1,
+		},
+		{
+			name:       "structured types ignore alternatives",
+			text:       "1. [multi] Pick the shapes - circle | round; square",
+			wantAnswer: "circle | round; square",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuestionWithFormat(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: tt.text}, regexes.DefaultListItemPrefixes, format.Version2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuestionWithFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.AnswerText != tt.wantAnswer {
+				t.Errorf("AnswerText = %q, want %q", got.AnswerText, tt.wantAnswer)
+			}
+			if !reflect.DeepEqual(got.Alternatives, tt.wantAlternatives) {
+				t.Errorf("Alternatives = %q, want %q", got.Alternatives, tt.wantAlternatives)
+			}
+			if got.Tolerance != tt.wantTolerance {
+				t.Errorf("Tolerance = %v, want %v", got.Tolerance, tt.wantTolerance)
+			}
+		})
+	}
+}
+
+func TestParseAcceptableAnswersNeedFormat2(t *testing.T) {
+	for _, text := range []string{"1. Capital of France? - Paris | City of Light", "1. Acceleration of gravity in m/s²? - 9.8 ± 0.1"} {
+		got, err := ParseQuestion(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: text})
+		if err != nil {
+			t.Fatalf("ParseQuestion(%q) unexpected error: %v", text, err)
+		}
+		if want := strings.SplitN(text, " - ", 2)[1]; got.AnswerText != want || got.Alternatives != nil || got.Tolerance != 0 {
+			t.Errorf("ParseQuestion(%q) = %q, %q, %v, want the answer as written", text, got.AnswerText, got.Alternatives, got.Tolerance)
+		}
+	}
+}
+
+func TestParseQuestionTypesNeedFormat2(t *testing.T) {
+	for _, text := range []string{"1. [tf] The sun is a star - true", "1. Primary colors - red; yellow; blue"} {
+		got, err := ParseQuestion(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: text})
+		if err != nil {
+			t.Fatalf("ParseQuestion(%q) unexpected error: %v", text, err)
+		}
+		parts := strings.SplitN(strings.TrimPrefix(text, "1. "), " - ", 2)
+		if got.Type != ontology.QuestionTypeShortAnswer || got.QuestionText != parts[0] || got.AnswerText != parts[1] {
+			t.Errorf("ParseQuestion(%q) = %q, %q, %q, want a short answer as written", text, got.Type, got.QuestionText, got.AnswerText)
+		}
+	}
+}
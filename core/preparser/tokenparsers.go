@@ -1,42 +1,90 @@
 package preparser
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/conditions"
 	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
 	"github.com/studyguides-com/study-guides-parser/core/regexes"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
+	"github.com/studyguides-com/study-guides-parser/core/variables"
 )
 
-// ParseQuestion parses question lines
+// ParseQuestion parses question lines using the default list item prefixes
 func ParseQuestion(lineInfo LineInfo) (*QuestionResult, *PreParsingError) {
+	return ParseQuestionWithPrefixes(lineInfo, regexes.DefaultListItemPrefixes)
+}
+
+// ParseQuestionWithPrefixes parses question lines that start with one of the given
+// list item prefixes, using the default format version
+func ParseQuestionWithPrefixes(lineInfo LineInfo, prefixes []regexes.ListItemPrefix) (*QuestionResult, *PreParsingError) {
+	return ParseQuestionWithFormat(lineInfo, prefixes, format.Default)
+}
+
+// ParseQuestionWithFormat parses question lines that start with one of the given list
+// item prefixes, reading the answer syntax of the given format version. The author's
+// label and number are kept on the result.
+func ParseQuestionWithFormat(lineInfo LineInfo, prefixes []regexes.ListItemPrefix, version format.Version) (*QuestionResult, *PreParsingError) {
 	// Use cleanstring for consistent text normalization
 	cleanedLine := cleanstring.New(lineInfo.Text).Clean()
-	if !regexes.ListItemPrefixRegex.MatchString(cleanedLine) {
+	match, ok := regexes.MatchListItemPrefix(cleanedLine, prefixes)
+	if !ok {
 		return nil, NewPreParsingError(CodeValidation, "question must start with a number or bullet point", lineInfo)
 	}
+	return parseQuestionMatch(lineInfo, match, version)
+}
+
+// parseQuestionMatch parses a question line whose list item prefix has been matched
+func parseQuestionMatch(lineInfo LineInfo, match regexes.ListItemMatch, version format.Version) (*QuestionResult, *PreParsingError) {
+	var annotations *Annotations
+	if version.Supports(format.FeatureAnnotations) {
+		rest, parsed, err := splitAnnotations(match.Rest, lineInfo)
+		if err != nil {
+			return nil, err
+		}
+		match.Rest, annotations = rest, parsed
+	}
+	if version.Supports(format.FeatureCloze) && regexes.ClozeOpenRegex.MatchString(match.Rest) {
+		result, err := parseCloze(match, lineInfo, version)
+		if result != nil {
+			result.Annotations = annotations
+		}
+		return result, err
+	}
 	if !strings.Contains(lineInfo.Text, constants.AnswerDelimiter) {
 		return nil, NewPreParsingError(CodeValidation, "question must contain answer delimiter ' - '", lineInfo)
 	}
 
-	// Split into question and answer using the first occurrence of ' - '
-	parts := strings.SplitN(lineInfo.Text, constants.AnswerDelimiter, constants.QuestionAnswerParts)
+	// Split into question and answer using the first occurrence of ' - ' after the prefix
+	parts := strings.SplitN(match.Rest, constants.AnswerDelimiter, constants.QuestionAnswerParts)
 	if len(parts) != constants.QuestionAnswerParts {
 		return nil, NewPreParsingError(CodeValidation, "invalid question format", lineInfo)
 	}
 
-	// Remove the prefix from the question using cleaned text
-	questionText := cleanstring.New(regexes.ListItemPrefixRegex.ReplaceAllString(parts[0], "")).Clean()
-	answerText := cleanstring.New(parts[1]).Clean()
-
 	// Sanitize both texts
-	questionText = cleanstring.New(questionText).Clean()
-	answerText = cleanstring.New(answerText).Clean()
+	questionType, prompt := splitQuestionType(cleanstring.New(parts[0]).Clean(), version)
+	questionText := cleanstring.New(prompt).Clean()
+	answerText := cleanstring.New(parts[1]).Clean()
 
-	return &QuestionResult{
+	result := &QuestionResult{
 		QuestionText: questionText,
 		AnswerText:   answerText,
-	}, nil
+		Prefix:       match.Raw,
+		Label:        match.Label,
+		Number:       match.Number,
+		Type:         questionType,
+		Annotations:  annotations,
+	}
+	if err := parseStructuredAnswer(result, lineInfo, version); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // ParseHeader parses header lines
@@ -65,9 +113,13 @@ func ParseComment(lineInfo LineInfo) (*CommentResult, *PreParsingError) {
 	}
 	// Remove the # and sanitize
 	text := cleanstring.New(strings.TrimPrefix(lineInfo.Text, constants.CommentPrefix)).Clean()
-	return &CommentResult{
+	result := &CommentResult{
 		Text: text,
-	}, nil
+	}
+	if match := regexes.EditorialMarkerRegex.FindStringSubmatch(text); match != nil {
+		result.Marker = match[1]
+	}
+	return result, nil
 }
 
 // ParseEmptyLine parses empty lines
@@ -111,7 +163,8 @@ func ParsePassage(lineInfo LineInfo) (*PassageResult, *PreParsingError) {
 		return nil, NewPreParsingError(CodeValidation, "passage must contain text after 'Passage:'", lineInfo)
 	}
 	return &PassageResult{
-		Text: text,
+		Text:       text,
+		References: reference.Extract(text),
 	}, nil
 }
 
@@ -130,7 +183,8 @@ func ParseLearnMore(lineInfo LineInfo) (*LearnMoreResult, *PreParsingError) {
 		return nil, NewPreParsingError(CodeValidation, "learn more line must contain text after 'Learn More:'", lineInfo)
 	}
 	return &LearnMoreResult{
-		Text: text,
+		Text:       text,
+		References: reference.Extract(text),
 	}, nil
 }
 
@@ -142,9 +196,39 @@ func ParseContent(lineInfo LineInfo) (*ContentResult, *PreParsingError) {
 	if text == "" {
 		return nil, NewPreParsingError(CodeValidation, "content line must not be empty or whitespace only", lineInfo)
 	}
-	return &ContentResult{
-		Text: text,
-	}, nil
+	result := parseContentStructure(text)
+	result.References = reference.Extract(text)
+	return result, nil
+}
+
+// parseContentStructure detects whether a content line is a list item, table row or quote
+func parseContentStructure(text string) *ContentResult {
+	result := &ContentResult{Text: text, Kind: ContentKindText}
+
+	switch {
+	case regexes.PassageListItemRegex.MatchString(text):
+		result.Kind = ContentKindListItem
+		result.Value = cleanstring.New(regexes.PassageListItemRegex.ReplaceAllString(text, "")).Clean()
+	case len(text) > 1 && regexes.TableRowRegex.MatchString(text):
+		cells := strings.Split(text[1:len(text)-1], "|")
+		separator := true
+		for i, cell := range cells {
+			cells[i] = cleanstring.New(cell).Clean()
+			if !regexes.TableSeparatorCellRegex.MatchString(cells[i]) {
+				separator = false
+			}
+		}
+		result.Kind = ContentKindTableRow
+		if separator {
+			result.Kind = ContentKindTableSeparator
+		}
+		result.Cells = cells
+	case regexes.QuoteRegex.MatchString(text):
+		result.Kind = ContentKindQuote
+		result.Value = cleanstring.New(regexes.QuoteRegex.ReplaceAllString(text, "")).Clean()
+	}
+
+	return result
 }
 
 // ParseBinary parses binary lines
@@ -155,3 +239,127 @@ func ParseBinary(lineInfo LineInfo) (*BinaryResult, *PreParsingError) {
 		Text: lineInfo.Text,
 	}, nil
 }
+
+// ParseCodeStart parses the opening fence of a code block (e.g. "```python" or "```python answer")
+func ParseCodeStart(lineInfo LineInfo) (*CodeStartResult, *PreParsingError) {
+	match := regexes.CodeFenceRegex.FindStringSubmatch(lineInfo.Clean())
+	if match == nil {
+		return nil, NewPreParsingError(CodeValidation, "code block must start with ``` or ~~~", lineInfo)
+	}
+	target := CodeTarget(strings.ToLower(match[3]))
+	switch target {
+	case "":
+		target = CodeTargetQuestion
+	case CodeTargetQuestion, CodeTargetAnswer:
+	default:
+		return nil, NewPreParsingError(CodeValidation,
+			fmt.Sprintf("unknown code block target %q, expected %q or %q", match[3], CodeTargetQuestion, CodeTargetAnswer), lineInfo)
+	}
+	return &CodeStartResult{
+		Language: strings.ToLower(match[2]),
+		Target:   target,
+	}, nil
+}
+
+// ParseCode parses a line inside a code block. The text is kept verbatim apart
+// from a trailing carriage return.
+func ParseCode(lineInfo LineInfo) *CodeResult {
+	return &CodeResult{
+		Text: strings.TrimRight(lineInfo.Text, "\r"),
+	}
+}
+
+const (
+	maxPassageYear  = 9999 // The latest year a "@year" directive accepts
+	maxPassageGrade = 12   // The highest school grade a "@grade" directive accepts
+)
+
+// ParseDirective parses directive lines (e.g. "@context: APExams") using the default format version
+func ParseDirective(lineInfo LineInfo) (*DirectiveResult, *PreParsingError) {
+	return ParseDirectiveWithFormat(lineInfo, format.Default)
+}
+
+// ParseDirectiveWithFormat parses directive lines, accepting the directives of the given
+// format version. The value of a "@context" directive must name a known context type, in
+// any case, that of a "@format" directive a known version, and that of a "@define"
+// directive read "name = value". Passage metadata needs a value, and "@year" and "@grade"
+// a whole number.
+func ParseDirectiveWithFormat(lineInfo LineInfo, version format.Version) (*DirectiveResult, *PreParsingError) {
+	match := regexes.DirectiveRegex.FindStringSubmatch(lineInfo.Clean())
+	if match == nil {
+		return nil, NewPreParsingError(CodeValidation, "directive must start with @ and a name", lineInfo)
+	}
+	result := &DirectiveResult{
+		Name:  strings.ToLower(match[1]),
+		Value: cleanstring.New(match[2]).Clean(),
+	}
+	since, ok := format.Directive(result.Name)
+	if !ok {
+		return nil, NewPreParsingError(CodeValidation,
+			fmt.Sprintf("unknown directive %s%s", constants.DirectivePrefix, result.Name), lineInfo)
+	}
+	if since > version {
+		return nil, NewPreParsingError(CodeValidation,
+			fmt.Sprintf("%s%s needs format %s; declare it with \"%s%s: %s\" after the file header",
+				constants.DirectivePrefix, result.Name, since, constants.DirectivePrefix, constants.FormatDirective, since), lineInfo)
+	}
+	switch result.Name {
+	case constants.ContextDirective:
+		contextType, ok := ontology.ParseContextType(result.Value)
+		if !ok {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("unknown context type %q in %s%s directive", result.Value, constants.DirectivePrefix, result.Name), lineInfo)
+		}
+		result.Value = string(contextType)
+	case constants.FormatDirective:
+		formatVersion, err := format.Parse(result.Value)
+		if err != nil || result.Value == "" {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("invalid version %q in %s%s directive", result.Value, constants.DirectivePrefix, result.Name), lineInfo)
+		}
+		result.Value = formatVersion.String()
+	case constants.DefineDirective:
+		name, value, err := variables.ParseDefinition(result.Value)
+		if err != nil {
+			return nil, NewPreParsingError(CodeValidation, err.Message, lineInfo)
+		}
+		result.Variable, result.Value = name, value
+	case constants.IfDirective:
+		condition, err := conditions.Parse(result.Value)
+		if err != nil {
+			return nil, NewPreParsingError(CodeValidation, err.Message, lineInfo)
+		}
+		result.Condition = condition
+	case constants.ParamDirective:
+		param, err := templates.ParseParam(result.Value)
+		if err != nil {
+			return nil, NewPreParsingError(CodeValidation, err.Message, lineInfo)
+		}
+		result.Param = param
+	case constants.EndDirective:
+		if result.Value != "" {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("%s%s takes no value, found %q", constants.DirectivePrefix, result.Name, result.Value), lineInfo)
+		}
+	case constants.AuthorDirective, constants.SourceDirective:
+		if result.Value == "" {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("%s%s needs a value", constants.DirectivePrefix, result.Name), lineInfo)
+		}
+	case constants.YearDirective:
+		year, err := strconv.Atoi(result.Value)
+		if err != nil || year < 1 || year > maxPassageYear {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("invalid year %q in %s%s directive, expected a year from 1 to %d", result.Value, constants.DirectivePrefix, result.Name, maxPassageYear), lineInfo)
+		}
+		result.Number = year
+	case constants.GradeDirective:
+		grade, err := strconv.Atoi(result.Value)
+		if err != nil || grade < 1 || grade > maxPassageGrade {
+			return nil, NewPreParsingError(CodeValidation,
+				fmt.Sprintf("invalid grade %q in %s%s directive, expected a grade from 1 to %d", result.Value, constants.DirectivePrefix, result.Name, maxPassageGrade), lineInfo)
+		}
+		result.Number = grade
+	}
+	return result, nil
+}
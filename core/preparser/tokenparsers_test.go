@@ -3,7 +3,14 @@
 package preparser
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/conditions"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
 )
 
 func TestLineQuestionParser(t *testing.T) {
@@ -205,6 +212,31 @@ func TestLineCommentParser(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "comment with an editorial marker",
+			lineInfo: LineInfo{
+				Number: 1,
+				Type:   TokenTypeComment,
+				Text:   "# TODO: check the date",
+			},
+			want: &CommentResult{
+				Text:   "TODO: check the date",
+				Marker: "TODO",
+			},
+			wantErr: false,
+		},
+		{
+			name: "marker must be a whole uppercase word",
+			lineInfo: LineInfo{
+				Number: 1,
+				Type:   TokenTypeComment,
+				Text:   "# todo list and TODOS are not markers",
+			},
+			want: &CommentResult{
+				Text: "todo list and TODOS are not markers",
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid comment with double hash",
 			lineInfo: LineInfo{
@@ -236,6 +268,9 @@ func TestLineCommentParser(t *testing.T) {
 				if got.Text != tt.want.Text {
 					t.Errorf("ParseComment() text = %v, want %v", got.Text, tt.want.Text)
 				}
+				if got.Marker != tt.want.Marker {
+					t.Errorf("ParseComment() marker = %v, want %v", got.Marker, tt.want.Marker)
+				}
 			}
 		})
 	}
@@ -717,3 +752,265 @@ func TestLineBinaryParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQuestionWithPrefixes(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantPrompt string
+		wantAnswer string
+		wantPrefix string
+		wantLabel  string
+		wantNumber int
+		wantErr    bool
+	}{
+		{"number paren", "3) What is Go? - A language", "What is Go?", "A language", "3)", "3", 3, false},
+		{"letter dot", "b. What is Go? - A language", "What is Go?", "A language", "b.", "b", 2, false},
+		{"roman wrapped", "(iv) What is Go? - A language", "What is Go?", "A language", "(iv)", "iv", 4, false},
+		{"q number", "Q12: What is Go? - A language", "What is Go?", "A language", "Q12:", "12", 12, false},
+		{"bullet", "- What is Go? - A language", "What is Go?", "A language", "-", "", 0, false},
+		{"no delimiter", "Q1: What is Go?", "", "", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lineInfo := LineInfo{Number: 2, Type: TokenTypeQuestion, Text: tt.text}
+			got, err := ParseQuestionWithPrefixes(lineInfo, regexes.ExtendedListItemPrefixes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuestionWithPrefixes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.QuestionText != tt.wantPrompt || got.AnswerText != tt.wantAnswer {
+				t.Errorf("got %q / %q, want %q / %q", got.QuestionText, got.AnswerText, tt.wantPrompt, tt.wantAnswer)
+			}
+			if got.Prefix != tt.wantPrefix || got.Label != tt.wantLabel || got.Number != tt.wantNumber {
+				t.Errorf("got prefix=%q label=%q number=%d, want %q %q %d", got.Prefix, got.Label, got.Number, tt.wantPrefix, tt.wantLabel, tt.wantNumber)
+			}
+		})
+	}
+
+	// The default prefixes still reject the extended forms
+	if _, err := ParseQuestion(LineInfo{Number: 2, Text: "3) What is Go? - A language"}); err == nil {
+		t.Error("ParseQuestion() accepted '3)' with default prefixes")
+	}
+}
+
+func TestContentStructure(t *testing.T) {
+	tests := []struct {
+		text  string
+		kind  ContentKind
+		value string
+		cells []string
+	}{
+		{"Plain prose", ContentKindText, "", nil},
+		{"• First point", ContentKindListItem, "First point", nil},
+		{"+ Second point", ContentKindListItem, "Second point", nil},
+		{"| Name | Age |", ContentKindTableRow, "", []string{"Name", "Age"}},
+		{"| --- | :---: |", ContentKindTableSeparator, "", []string{"---", ":---:"}},
+		{"> To be or not to be", ContentKindQuote, "To be or not to be", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseContent(LineInfo{Number: 1, Type: TokenTypeContent, Text: tt.text})
+			if err != nil {
+				t.Fatalf("ParseContent() error = %v", err)
+			}
+			if got.Text != tt.text {
+				t.Errorf("Text = %q, want %q", got.Text, tt.text)
+			}
+			if got.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", got.Kind, tt.kind)
+			}
+			if got.Value != tt.value {
+				t.Errorf("Value = %q, want %q", got.Value, tt.value)
+			}
+			if len(got.Cells) != len(tt.cells) {
+				t.Fatalf("Cells = %v, want %v", got.Cells, tt.cells)
+			}
+			for i := range tt.cells {
+				if got.Cells[i] != tt.cells[i] {
+					t.Errorf("Cells[%d] = %q, want %q", i, got.Cells[i], tt.cells[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCodeStart(t *testing.T) {
+	tests := []struct {
+		text         string
+		wantLanguage string
+		wantTarget   CodeTarget
+		wantErr      bool
+	}{
+		{"```python", "python", CodeTargetQuestion, false},
+		{"```", "", CodeTargetQuestion, false},
+		{"~~~ Java answer", "java", CodeTargetAnswer, false},
+		{"```python question", "python", CodeTargetQuestion, false},
+		{"```python explanation", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseCodeStart(LineInfo{Number: 3, Type: TokenTypeCodeStart, Text: tt.text})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCodeStart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Language != tt.wantLanguage || got.Target != tt.wantTarget {
+				t.Errorf("ParseCodeStart() = %+v, want language %q target %q", got, tt.wantLanguage, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestParseCodeKeepsIndentation(t *testing.T) {
+	got := ParseCode(LineInfo{Number: 4, Type: TokenTypeCode, Text: "    return a + b  \r"})
+	if got.Text != "    return a + b  " {
+		t.Errorf("ParseCode() text = %q, want indentation and trailing spaces kept", got.Text)
+	}
+}
+
+func TestParseClozeQuestion(t *testing.T) {
+	got, err := ParseQuestionWithFormat(LineInfo{Number: 2, Type: TokenTypeQuestion,
+		Text: "1. The capital of {{c1::France}} is {{c2::Paris::a city}} - on the Seine"},
+		regexes.DefaultListItemPrefixes, format.Version2)
+	if err != nil {
+		t.Fatalf("ParseQuestionWithFormat() unexpected error: %v", err)
+	}
+	if got.Type != ontology.QuestionTypeCloze {
+		t.Errorf("Type = %q, want %q", got.Type, ontology.QuestionTypeCloze)
+	}
+	// The answer delimiter is part of a cloze sentence
+	if got.QuestionText != "The capital of {{c1::France}} is {{c2::Paris::a city}} - on the Seine" || got.AnswerText != "" {
+		t.Errorf("QuestionText = %q, AnswerText = %q", got.QuestionText, got.AnswerText)
+	}
+	want := []ClozeDeletion{{Index: 1, Text: "France"}, {Index: 2, Text: "Paris", Hint: "a city"}}
+	if !reflect.DeepEqual(got.Clozes, want) {
+		t.Errorf("Clozes = %+v, want %+v", got.Clozes, want)
+	}
+
+	for _, text := range []string{
+		"1. The capital of {{c1::France is Paris",
+		"1. The capital of {{c0::France}} is Paris",
+		"1. [tf] The capital of {{c1::France}} is Paris",
+	} {
+		_, err := ParseQuestionWithFormat(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: text},
+			regexes.DefaultListItemPrefixes, format.Version2)
+		if err == nil {
+			t.Errorf("ParseQuestionWithFormat(%q) expected an error", text)
+		}
+	}
+}
+
+func TestParseClozeQuestionInFormat1(t *testing.T) {
+	// Format 1 has no cloze syntax, so the braces are plain question text
+	got, err := ParseQuestion(LineInfo{Number: 2, Type: TokenTypeQuestion,
+		Text: "1. The capital of {{c1::France}} - Paris"})
+	if err != nil {
+		t.Fatalf("ParseQuestion() unexpected error: %v", err)
+	}
+	if got.Type != ontology.QuestionTypeShortAnswer || len(got.Clozes) != 0 {
+		t.Errorf("Type = %q, Clozes = %+v, want a short answer question", got.Type, got.Clozes)
+	}
+	if got.QuestionText != "The capital of {{c1::France}}" || got.AnswerText != "Paris" {
+		t.Errorf("QuestionText = %q, AnswerText = %q", got.QuestionText, got.AnswerText)
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	got, err := ParseDirective(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@Context: apexams "})
+	if err != nil {
+		t.Fatalf("ParseDirective() unexpected error: %v", err)
+	}
+	want := &DirectiveResult{Name: "context", Value: string(ontology.ContextTypeAPExams)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDirective() = %+v, want %+v", got, want)
+	}
+
+	got, err = ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@format 2"}, format.Version2)
+	if err != nil || got.Name != "format" || got.Value != "2" {
+		t.Errorf("ParseDirectiveWithFormat() = %+v, %v, want format 2", got, err)
+	}
+
+	got, err = ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@define course = BIO 101"}, format.Version2)
+	if err != nil || got.Variable != "course" || got.Value != "BIO 101" {
+		t.Errorf("ParseDirectiveWithFormat() = %+v, %v, want course = BIO 101", got, err)
+	}
+	if _, err := ParseDirective(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@define course = BIO 101"}); err == nil {
+		t.Error("ParseDirective() expected @define to need format 2")
+	}
+
+	got, err = ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: `@if edition == "teacher"`}, format.Version2)
+	wantCondition := &conditions.Condition{Option: "edition", Operator: conditions.OperatorEqual, Value: "teacher"}
+	if err != nil || !reflect.DeepEqual(got.Condition, wantCondition) {
+		t.Errorf("ParseDirectiveWithFormat() = %+v, %v, want condition %+v", got, err, wantCondition)
+	}
+
+	got, err = ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@param a in -5..20"}, format.Version2)
+	if err != nil || !reflect.DeepEqual(got.Param, &templates.Param{Name: "a", Min: -5, Max: 20}) {
+		t.Errorf("ParseDirectiveWithFormat() = %+v, %v, want parameter a in -5..20", got, err)
+	}
+
+	got, err = ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@year: 1883"}, format.Version2)
+	if err != nil || got.Number != 1883 || !got.IsPassageMetadata() {
+		t.Errorf("ParseDirectiveWithFormat() = %+v, %v, want passage year 1883", got, err)
+	}
+	got, err = ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: "@Author: Mark Twain"}, format.Version2)
+	if err != nil || got.Name != "author" || got.Value != "Mark Twain" || !got.IsPassageMetadata() {
+		t.Errorf("ParseDirectiveWithFormat() = %+v, %v, want author Mark Twain", got, err)
+	}
+
+	for _, text := range []string{"@context: Kindergarten", "@context", "@teacher: Smith", "@format: 9", "@format", "@define course", "@if edition", "@end edition", "@param a in 20..1",
+		"@author", "@source:", "@year: 1883 AD", "@year: 0", "@grade: 13", "@grade: eighth"} {
+		if _, err := ParseDirectiveWithFormat(LineInfo{Number: 2, Type: TokenTypeDirective, Text: text}, format.Latest); err == nil {
+			t.Errorf("ParseDirectiveWithFormat(%q) expected an error", text)
+		}
+	}
+}
+
+func TestParseQuestionAnnotations(t *testing.T) {
+	parse := func(text string) (*QuestionResult, *PreParsingError) {
+		return ParseQuestionWithFormat(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: text}, regexes.DefaultListItemPrefixes, format.Version2)
+	}
+
+	got, err := parse("1. What is velocity? - Speed with direction {Difficulty: 3, bloom: Apply, keywords: [kinematics, vectors]}")
+	if err != nil {
+		t.Fatalf("ParseQuestion() unexpected error: %v", err)
+	}
+	want := &Annotations{Difficulty: 3, Bloom: ontology.BloomLevelApply, Keywords: []string{"kinematics", "vectors"}}
+	if got.AnswerText != "Speed with direction" || !reflect.DeepEqual(got.Annotations, want) {
+		t.Errorf("ParseQuestion() = %q, %+v, want %q, %+v", got.AnswerText, got.Annotations, "Speed with direction", want)
+	}
+
+	got, err = parse("1. The capital of {{c1::France}} is Paris {keywords: geography}")
+	if err != nil || got.QuestionText != "The capital of {{c1::France}} is Paris" || !reflect.DeepEqual(got.Annotations.Keywords, []string{"geography"}) {
+		t.Errorf("ParseQuestion() = %+v, %v, want a cloze with the keyword geography", got, err)
+	}
+
+	// Braces without a known annotation key are left in the answer
+	for _, answer := range []string{"{a+b}", "{x : x > 0}", "{level: 2}"} {
+		got, err = parse("1. What is it? - " + answer)
+		if err != nil || got.AnswerText != answer || got.Annotations != nil {
+			t.Errorf("ParseQuestion() = %+v, %v, want the answer %q kept", got, err, answer)
+		}
+	}
+
+	for _, annotation := range []string{"{difficulty: 6}", "{difficulty: hard}", "{bloom: memorise}", "{keywords: []}",
+		"{difficulty: 2, level: 2}", "{difficulty: 2, difficulty: 3}", "{difficulty: 2, apply}"} {
+		if _, err := parse("1. What? - That " + annotation); err == nil {
+			t.Errorf("ParseQuestion(%q) expected an error", annotation)
+		}
+	}
+
+	// Format 1 guides have no annotations, so the braces are part of the answer
+	got, err = ParseQuestion(LineInfo{Number: 2, Type: TokenTypeQuestion, Text: "1. Write a dict mapping a to 1 - {a: 1}"})
+	if err != nil || got.AnswerText != "{a: 1}" || got.Annotations != nil {
+		t.Errorf("ParseQuestion() = %+v, %v, want the answer {a: 1} in format 1", got, err)
+	}
+}
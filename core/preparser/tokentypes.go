@@ -1,6 +1,13 @@
 package preparser
 
-import "github.com/studyguides-com/study-guides-parser/core/lexer"
+import (
+	"github.com/studyguides-com/study-guides-parser/core/conditions"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/lexer"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
+)
 
 // TokenType is imported from the lexer package
 type TokenType = lexer.TokenType
@@ -18,6 +25,10 @@ const (
 	TokenTypeLearnMore  = lexer.TokenTypeLearnMore
 	TokenTypeSpacer     = lexer.TokenTypeSpacer
 	TokenTypeBinary     = lexer.TokenTypeBinary
+	TokenTypeCodeStart  = lexer.TokenTypeCodeStart
+	TokenTypeCode       = lexer.TokenTypeCode
+	TokenTypeCodeEnd    = lexer.TokenTypeCodeEnd
+	TokenTypeDirective  = lexer.TokenTypeDirective
 )
 
 // HeaderResult represents the parsed result of a header line
@@ -29,6 +40,42 @@ type HeaderResult struct {
 type QuestionResult struct {
 	QuestionText string
 	AnswerText   string
+	Prefix       string // The list item prefix as written (e.g. "1.", "b)", "(iv)")
+	Label        string // The author's label inside the prefix (e.g. "1", "b", "iv"), empty for bullets
+	Number       int    // The author's label as an ordinal, 0 for bullets
+
+	Type  ontology.QuestionType // ShortAnswer unless the prompt starts with a type tag such as "[tf]"
+	Truth *bool                 `json:",omitempty"` // The answer of a true/false question
+	Items []string              `json:",omitempty"` // The correct answers of a multi-select question, or the sequence of an ordering question
+	Pairs []MatchPair           `json:",omitempty"` // The pairs of a matching question
+
+	Alternatives []string `json:",omitempty"` // Further acceptable answers of a short-answer question, written "Answer | Other"
+	Tolerance    float64  `json:",omitempty"` // How far a numeric answer may be off, written "9.8 ± 0.1"
+
+	Clozes []ClozeDeletion `json:",omitempty"` // The deletions of a cloze question, in the order they appear
+
+	Annotations *Annotations `json:",omitempty"` // The annotation at the end of the line, e.g. "{difficulty: 3}"
+}
+
+// Annotations are the pedagogical details an author gives a question at the end of its
+// line, written "{difficulty: 3, bloom: apply, keywords: [kinematics, velocity]}"
+type Annotations struct {
+	Difficulty int                 `json:",omitempty"` // From ontology.MinDifficulty to ontology.MaxDifficulty, 0 when not given
+	Bloom      ontology.BloomLevel `json:",omitempty"`
+	Keywords   []string            `json:",omitempty"`
+}
+
+// ClozeDeletion is one deletion of a cloze question, written "{{c1::Text}}" or "{{c1::Text::Hint}}"
+type ClozeDeletion struct {
+	Index int
+	Text  string
+	Hint  string `json:",omitempty"`
+}
+
+// MatchPair is one pair of a matching question, written "Left = Right"
+type MatchPair struct {
+	Left  string
+	Right string
 }
 
 // EmptyLineResult represents the parsed result of an empty line
@@ -41,25 +88,93 @@ type FileHeaderResult struct {
 
 // PassageResult represents the parsed result of a passage line
 type PassageResult struct {
-	Text string
+	Text       string
+	References []reference.Reference `json:",omitempty"` // URLs, DOIs, ISBNs and citations in the title
 }
 
 // LearnMoreResult represents the parsed result of a learn more line
 type LearnMoreResult struct {
-	Text string
+	Text       string
+	References []reference.Reference `json:",omitempty"` // URLs, DOIs, ISBNs and citations in the text
 }
 
+// ContentKind identifies the block structure a content line belongs to
+type ContentKind string
+
+const (
+	// ContentKindText is a line of plain text; consecutive text lines form a paragraph
+	ContentKindText ContentKind = "text"
+	// ContentKindListItem is a bulleted list item (e.g. "• Mitochondria")
+	ContentKindListItem ContentKind = "list_item"
+	// ContentKindTableRow is a pipe-delimited table row (e.g. "| 1776 | Independence |")
+	ContentKindTableRow ContentKind = "table_row"
+	// ContentKindTableSeparator is the row separating a table header from its body (e.g. "|---|---|")
+	ContentKindTableSeparator ContentKind = "table_separator"
+	// ContentKindQuote is a block quote line (e.g. "> Four score and seven years ago")
+	ContentKindQuote ContentKind = "quote"
+)
+
 // ContentResult represents the parsed result of a content line
 type ContentResult struct {
-	Text string
+	Text  string
+	Kind  ContentKind `json:",omitempty"` // Empty is treated as ContentKindText
+	Value string      `json:",omitempty"` // List item or quote text without its marker
+	Cells []string    `json:",omitempty"` // Table cells for table rows
+
+	References []reference.Reference `json:",omitempty"` // URLs, DOIs, ISBNs and citations in the line
 }
 
 // CommentResult represents the parsed result of a comment line
 type CommentResult struct {
-	Text string
+	Text   string
+	Marker string `json:",omitempty"` // TODO, FIXME or REVIEW when the comment contains one of them
 }
 
 // BinaryResult represents the parsed result of a binary line
 type BinaryResult struct {
 	Text string
 }
+
+// DirectiveResult represents the parsed result of a directive line (e.g. "@context: APExams")
+type DirectiveResult struct {
+	Name      string                // The directive name in lowercase, without the "@"
+	Value     string                // The directive's value; for "@context" the context type as spelled in the ontology
+	Variable  string                `json:",omitempty"` // The variable a "@define" directive defines; Value is its value
+	Condition *conditions.Condition `json:",omitempty"` // The condition of an "@if" directive
+	Param     *templates.Param      `json:",omitempty"` // The parameter a "@param" directive declares
+	Number    int                   `json:",omitempty"` // The year of a "@year" directive or the grade of a "@grade" directive
+}
+
+// IsPassageMetadata reports whether the directive describes the passage it follows
+func (d *DirectiveResult) IsPassageMetadata() bool {
+	switch d.Name {
+	case constants.AuthorDirective, constants.SourceDirective, constants.YearDirective, constants.GradeDirective:
+		return true
+	}
+	return false
+}
+
+// CodeTarget names the part of a question a code block belongs to
+type CodeTarget string
+
+const (
+	// CodeTargetQuestion attaches a code block to the question prompt (the default)
+	CodeTargetQuestion CodeTarget = "question"
+	// CodeTargetAnswer attaches a code block to the answer (e.g. "```python answer")
+	CodeTargetAnswer CodeTarget = "answer"
+)
+
+// CodeStartResult represents the parsed result of a code block's opening fence
+type CodeStartResult struct {
+	Language string     // The language after the fence (e.g. "python"), empty if not given
+	Target   CodeTarget // What a code block after a question is attached to
+}
+
+// CodeResult represents the parsed result of a line inside a code block.
+// Text is the line exactly as written, including indentation.
+type CodeResult struct {
+	Text string
+}
+
+// CodeEndResult represents the parsed result of a code block's closing fence
+type CodeEndResult struct{}
@@ -1,76 +1,169 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/studyguides-com/study-guides-parser/core/builder"
+	"github.com/studyguides-com/study-guides-parser/core/charset"
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/conditions"
 	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/frontmatter"
+	"github.com/studyguides-com/study-guides-parser/core/include"
 	"github.com/studyguides-com/study-guides-parser/core/lexer"
+	"github.com/studyguides-com/study-guides-parser/core/markup"
 	"github.com/studyguides-com/study-guides-parser/core/parser"
 	"github.com/studyguides-com/study-guides-parser/core/preparser"
+	"github.com/studyguides-com/study-guides-parser/core/readability"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
 	"github.com/studyguides-com/study-guides-parser/core/schema"
+	"github.com/studyguides-com/study-guides-parser/core/source"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
 	"github.com/studyguides-com/study-guides-parser/core/tree"
+	"github.com/studyguides-com/study-guides-parser/core/variables"
 )
 
 // ProcessingError represents a structured error with line information
 type ProcessingError struct {
+	File       string `json:"file,omitempty"`
 	LineNumber int    `json:"line_number"`
 	Message    string `json:"message"`
 	Code       string `json:"code"`
 	Text       string `json:"text,omitempty"`
 	Type       string `json:"type,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"` // A corrected line, for near misses
 }
 
+// EditorialMarker is a TODO, FIXME or REVIEW marker found in a comment
+type EditorialMarker struct {
+	File       string `json:"file,omitempty"`
+	LineNumber int    `json:"line_number"`
+	Marker     string `json:"marker"`
+	Text       string `json:"text"`
+}
+
+// LexerOutput holds the lexer tokens. When the guide has front matter, Metadata is the
+// caller's metadata merged with the front matter. Token line numbers count from the
+// file header of the assembled guide; errors always refer to the original file and line.
+// Warnings list content lines that nearly match a directive, with suggested corrections.
 type LexerOutput struct {
 	SchemaType    schema.SchemaType `json:"schema_type"`
 	SchemaVersion string            `json:"schema_version"`
+	FormatVersion format.Version    `json:"format_version"` // The version of the input format the guide was read as
 	Metadata      *config.Metadata  `json:"metadata"`
 	Tokens        []lexer.LineInfo  `json:"tokens"`
 	Errors        []ProcessingError `json:"errors"`
+	Warnings      []ProcessingError `json:"warnings,omitempty"`
 	Success       bool              `json:"success"`
+	sources       *source.Map       // Maps token line numbers to original files and lines
+}
+
+// ExplainOutput holds the lexer's explanation of each line, in the order of the lines
+// after any front matter. Lines inside code blocks are not classified.
+type ExplainOutput struct {
+	SchemaType    schema.SchemaType   `json:"schema_type"`
+	SchemaVersion string              `json:"schema_version"`
+	FormatVersion format.Version      `json:"format_version"`
+	Metadata      *config.Metadata    `json:"metadata"`
+	Lines         []lexer.Explanation `json:"lines"`
+	Errors        []ProcessingError   `json:"errors"`
+	Warnings      []ProcessingError   `json:"warnings,omitempty"`
+	Success       bool                `json:"success"`
 }
 
+// PreparserOutput holds the preparsed tokens. Warnings report problems that do not
+// stop processing, such as malformed inline markup, and Markers lists the TODO, FIXME
+// and REVIEW comments; both are passed on to later stages.
 type PreparserOutput struct {
 	SchemaType    schema.SchemaType          `json:"schema_type"`
 	SchemaVersion string                     `json:"schema_version"`
+	FormatVersion format.Version             `json:"format_version"`
 	Metadata      *config.Metadata           `json:"metadata"`
 	Tokens        []preparser.ParsedLineInfo `json:"tokens"`
 	Errors        []ProcessingError          `json:"errors"`
+	Warnings      []ProcessingError          `json:"warnings,omitempty"`
+	Markers       []EditorialMarker          `json:"markers,omitempty"`
 	Success       bool                       `json:"success"`
+	// RemovedQuestions holds the numbers of the questions in "@if" blocks whose condition
+	// failed, where the numbering of the remaining ones may have gaps
+	RemovedQuestions []int       `json:"removed_questions,omitempty"`
+	sources          *source.Map // Maps token line numbers to original files and lines
 }
 
 // ParserOutput represents the result of parsing with structured errors
 type ParserOutput struct {
 	SchemaType    schema.SchemaType          `json:"schema_type"`
 	SchemaVersion string                     `json:"schema_version"`
+	FormatVersion format.Version             `json:"format_version"`
 	AST           *parser.AbstractSyntaxTree `json:"ast,omitempty"`
 	Errors        []ProcessingError          `json:"errors,omitempty"`
+	Warnings      []ProcessingError          `json:"warnings,omitempty"`
+	Markers       []EditorialMarker          `json:"markers,omitempty"`
 	Success       bool                       `json:"success"`
 }
 
 type BuilderOutput struct {
 	SchemaType    schema.SchemaType `json:"schema_type"`
 	SchemaVersion string            `json:"schema_version"`
+	FormatVersion format.Version    `json:"format_version"`
 	Tree          *tree.Tree        `json:"tree,omitempty"`
 	Errors        []ProcessingError `json:"errors,omitempty"`
+	Warnings      []ProcessingError `json:"warnings,omitempty"`
+	Markers       []EditorialMarker `json:"markers,omitempty"`
 	Success       bool              `json:"success"`
 }
 
-// ParseFile reads a file and parses it into an Abstract Syntax Tree
+// ParseFile reads a file and parses it into an Abstract Syntax Tree.
+// @include directives are resolved relative to the file's directory.
 func ParseFile(filename string, metadata *config.Metadata) (*ParserOutput, error) {
-	content, err := os.ReadFile(filename)
+	return ParseFS(os.DirFS(filepath.Dir(filename)), filepath.Base(filename), metadata)
+}
+
+// ParseFS reads the named file from fsys, resolves its @include directives and parses
+// the assembled guide into an Abstract Syntax Tree
+func ParseFS(fsys fs.FS, name string, metadata *config.Metadata) (*ParserOutput, error) {
+	lines, sources, encoding, includeErrors, err := resolveIncludes(fsys, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+		return nil, err
 	}
-	lines := strings.Split(string(content), "\n")
-	return Parse(lines, metadata)
+	if includeErrors != nil {
+		return &ParserOutput{
+			SchemaType:    schema.SchemaTypeParser,
+			SchemaVersion: schema.Version,
+			FormatVersion: format.Default,
+			Errors:        includeErrors,
+			Success:       false,
+		}, nil
+	}
+	return parseSource(lines, sources, withEncoding(metadata, encoding))
+}
+
+// ParseReader reads a guide from r, decoding it to UTF-8, and parses it into an
+// Abstract Syntax Tree. @include directives are not resolved.
+func ParseReader(r io.Reader, metadata *config.Metadata) (*ParserOutput, error) {
+	lines, encoding, err := charset.ReadLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guide: %w", err)
+	}
+	return parseSource(lines, nil, withEncoding(metadata, encoding))
 }
 
 // Parse parses a slice of strings into an Abstract Syntax Tree
 func Parse(lines []string, metadata *config.Metadata) (*ParserOutput, error) {
-	preOut, err := Preparse(lines, metadata)
+	return parseSource(lines, nil, metadata)
+}
+
+func parseSource(lines []string, sources *source.Map, metadata *config.Metadata) (*ParserOutput, error) {
+	preOut, err := preparseSource(lines, sources, metadata)
 	if err != nil {
 		return nil, fmt.Errorf("preparser error: %w", err)
 	}
@@ -78,12 +171,13 @@ func Parse(lines []string, metadata *config.Metadata) (*ParserOutput, error) {
 		return &ParserOutput{
 			SchemaType:    schema.SchemaTypeParser,
 			SchemaVersion: schema.Version,
+			FormatVersion: preOut.FormatVersion,
 			Errors:        preOut.Errors,
 			Success:       false,
 		}, nil
 	}
 
-	return ParseFromPreparse(preOut, metadata)
+	return ParseFromPreparse(preOut, effectiveMetadata(preOut, metadata))
 }
 
 // ParseFromPreparse takes preparser output and runs the parser on it
@@ -93,79 +187,174 @@ func ParseFromPreparse(preOut PreparserOutput, metadata *config.Metadata) (*Pars
 		return &ParserOutput{
 			SchemaType:    schema.SchemaTypeParser,
 			SchemaVersion: schema.Version,
+			FormatVersion: preOut.FormatVersion,
 			Errors:        preOut.Errors,
 			Success:       false,
 		}, nil
 	}
 
-	p := parser.NewParser(preOut.Tokens)
+	p := parser.NewParser(preOut.Tokens).WithComments(keepComments(metadata))
 	ast, parserErr := p.Parse(metadata)
 	if parserErr != nil {
 		// Convert parser error to ProcessingError format
-		parserError := ProcessingError{
-			LineNumber: parserErr.LineInfo.Number,
-			Message:    parserErr.Message,
-			Code:       string(parserErr.Code),
-			Text:       parserErr.LineInfo.Text,
-			Type:       string(parserErr.LineInfo.Type),
-		}
+		parserError := sourceError(preOut.sources, parserErr.LineInfo.Number,
+			parserErr.Message, string(parserErr.Code), parserErr.LineInfo.Text, string(parserErr.LineInfo.Type))
 		return &ParserOutput{
 			SchemaType:    schema.SchemaTypeParser,
 			SchemaVersion: schema.Version,
+			FormatVersion: preOut.FormatVersion,
 			Errors:        []ProcessingError{parserError},
 			Success:       false,
 		}, nil
 	}
+	ast.RemovedQuestions = preOut.RemovedQuestions
+	warnings := append([]ProcessingError{}, preOut.Warnings...)
+	for _, warning := range p.Warnings {
+		warnings = append(warnings, sourceError(preOut.sources, warning.LineInfo.Number,
+			warning.Message, string(warning.Code), warning.LineInfo.Text, string(warning.LineInfo.Type)))
+	}
+	if len(warnings) == 0 {
+		warnings = nil
+	}
 	return &ParserOutput{
 		SchemaType:    schema.SchemaTypeParser,
 		SchemaVersion: schema.Version,
+		FormatVersion: preOut.FormatVersion,
 		AST:           ast,
+		Warnings:      warnings,
+		Markers:       preOut.Markers,
 		Success:       true,
 	}, nil
 }
 
 func Lex(lines []string, metadata *config.Metadata) (LexerOutput, error) {
-	lex := lexer.NewLexer()
+	return lexSource(lines, nil, metadata)
+}
+
+// Explain runs the lexer in explain mode and reports, for each line, every classifier
+// consulted, what each returned and which one decided the line's type
+func Explain(lines []string, metadata *config.Metadata) (ExplainOutput, error) {
+	lexOut, explanations, err := runLexer(lines, nil, metadata, true)
+	if err != nil {
+		return ExplainOutput{}, err
+	}
+	return ExplainOutput{
+		SchemaType:    schema.SchemaTypeExplain,
+		SchemaVersion: schema.Version,
+		FormatVersion: lexOut.FormatVersion,
+		Metadata:      lexOut.Metadata,
+		Lines:         explanations,
+		Errors:        lexOut.Errors,
+		Warnings:      lexOut.Warnings,
+		Success:       lexOut.Success,
+	}, nil
+}
+
+func lexSource(lines []string, sources *source.Map, metadata *config.Metadata) (LexerOutput, error) {
+	lexOut, _, err := runLexer(lines, sources, metadata, false)
+	return lexOut, err
+}
+
+// runLexer lexes the lines after any front matter, explaining each line when explain is set
+func runLexer(lines []string, sources *source.Map, metadata *config.Metadata, explain bool) (LexerOutput, []lexer.Explanation, error) {
+	// Separate any front matter from the guide and merge it into the metadata
+	doc, fmErr := frontmatter.Split(lines)
+	if fmErr != nil {
+		return LexerOutput{
+			SchemaType:    schema.SchemaTypeLexer,
+			SchemaVersion: schema.Version,
+			FormatVersion: format.Default,
+			Metadata:      metadata,
+			Errors:        []ProcessingError{sourceError(sources, fmErr.Line, fmErr.Message, string(fmErr.Code), fmErr.Text, "")},
+			Success:       false,
+		}, nil, nil
+	}
+	metadata = doc.FrontMatter.Apply(metadata)
+	sources = sources.Skip(doc.LineOffset())
+
+	prefixes, err := listItemPrefixes(metadata)
+	if err != nil {
+		return LexerOutput{}, nil, err
+	}
+	policy, err := normalizationPolicy(metadata)
+	if err != nil {
+		return LexerOutput{}, nil, err
+	}
+	if err := checkBuilderOptions(metadata); err != nil {
+		return LexerOutput{}, nil, err
+	}
+	lex := lexer.NewLexer().WithListItemPrefixes(prefixes).WithPolicy(policy)
+	if metadata != nil && metadata.FormatVersion != "" {
+		version, err := format.Parse(metadata.FormatVersion)
+		if err != nil {
+			return LexerOutput{}, nil, err
+		}
+		lex.WithFormat(version)
+	}
 	var tokens []lexer.LineInfo
 	var errors []*lexer.LexerError
+	var explanations []lexer.Explanation
 
-	for i, line := range lines {
-		lineInfo, err := lex.ProcessLine(line, i+1)
+	for i, line := range doc.Body {
+		var lineInfo lexer.LineInfo
+		var err *lexer.LexerError
+		if explain {
+			var explanation lexer.Explanation
+			explanation, err = lex.Explain(line, i+1)
+			explanations = append(explanations, explanation)
+			lineInfo = explanation.Line
+		} else {
+			lineInfo, err = lex.ProcessLine(line, i+1)
+		}
 		if err != nil {
 			errors = append(errors, err)
 		}
 		tokens = append(tokens, lineInfo)
 	}
+	if err := lex.Finish(); err != nil {
+		errors = append(errors, err)
+	}
 
 	// Convert lexer errors to ProcessingError structs for JSON serialization
 	processingErrors := make([]ProcessingError, len(errors))
 	for i, err := range errors {
-		processingErrors[i] = ProcessingError{
-			LineNumber: err.LineInfo.Number,
-			Message:    err.Message,
-			Code:       string(err.Code),
-			Text:       err.LineInfo.Text,
-			Type:       string(err.LineInfo.Type),
-		}
+		processingErrors[i] = sourceError(sources, err.LineInfo.Number,
+			err.Message, string(err.Code), err.LineInfo.Text, string(err.LineInfo.Type))
+	}
+	var warnings []ProcessingError
+	for _, warning := range lex.Warnings() {
+		processingWarning := sourceError(sources, warning.LineInfo.Number,
+			warning.Message, string(warning.Code), warning.LineInfo.Text, string(warning.LineInfo.Type))
+		processingWarning.Suggestion = warning.Metadata[lexer.MetadataSuggestion]
+		warnings = append(warnings, processingWarning)
 	}
 
 	return LexerOutput{
 		SchemaType:    schema.SchemaTypeLexer,
 		SchemaVersion: schema.Version,
+		FormatVersion: lex.Format(),
+		Metadata:      metadata,
 		Tokens:        tokens,
 		Errors:        processingErrors,
+		Warnings:      warnings,
 		Success:       len(errors) == 0,
-	}, nil
+		sources:       sources,
+	}, explanations, nil
 }
 
 func Preparse(lines []string, metadata *config.Metadata) (PreparserOutput, error) {
+	return preparseSource(lines, nil, metadata)
+}
+
+func preparseSource(lines []string, sources *source.Map, metadata *config.Metadata) (PreparserOutput, error) {
 	// Step 1: Run lexer and collect all lexer errors
-	lexOut, err := Lex(lines, metadata)
+	lexOut, err := lexSource(lines, sources, metadata)
 	if err != nil {
 		// If there's a critical error with the lexer itself, return it
 		return PreparserOutput{
 			SchemaType:    schema.SchemaTypePreparser,
 			SchemaVersion: schema.Version,
+			FormatVersion: format.Default,
 			Metadata:      metadata,
 			Errors:        []ProcessingError{{LineNumber: 0, Message: err.Error(), Code: "CRITICAL_ERROR"}},
 			Success:       false,
@@ -177,7 +366,8 @@ func Preparse(lines []string, metadata *config.Metadata) (PreparserOutput, error
 		return PreparserOutput{
 			SchemaType:    schema.SchemaTypePreparser,
 			SchemaVersion: schema.Version,
-			Metadata:      metadata,
+			FormatVersion: lexOut.FormatVersion,
+			Metadata:      lexOut.Metadata,
 			Tokens:        nil,
 			Errors:        lexOut.Errors,
 			Success:       false,
@@ -195,39 +385,94 @@ func PreparseFromLex(lexOut LexerOutput) (PreparserOutput, error) {
 		return PreparserOutput{
 			SchemaType:    schema.SchemaTypePreparser,
 			SchemaVersion: schema.Version,
+			FormatVersion: lexOut.FormatVersion,
+			Metadata:      lexOut.Metadata,
 			Tokens:        nil,
 			Errors:        lexOut.Errors,
 			Success:       false,
 		}, nil
 	}
 
+	prefixes, err := listItemPrefixes(lexOut.Metadata)
+	if err != nil {
+		return PreparserOutput{}, err
+	}
+
+	// Drop the lines of the "@if" blocks whose condition fails, then expand variables before
+	// preparsing, so that their values are parsed like the text around them
+	tokens := lexOut.Tokens
+	var allErrors, variableWarnings []ProcessingError
+	var removedQuestions []int
+	if lexOut.FormatVersion.Supports(format.FeatureConditionals) {
+		tokens, removedQuestions, allErrors = applyConditions(tokens, lexOut, prefixes)
+	}
+	if lexOut.FormatVersion.Supports(format.FeatureVariables) {
+		var variableErrors []ProcessingError
+		tokens, variableErrors, variableWarnings = expandVariables(tokens, lexOut)
+		allErrors = append(allErrors, variableErrors...)
+	}
+
 	// Run preparser on the lexer tokens
-	pre := preparser.NewPreparser(lexOut.Tokens, "")
+	pre := preparser.NewPreparser(tokens, "").WithListItemPrefixes(prefixes).WithFormat(lexOut.FormatVersion)
 	parsed, prepErrors := pre.Parse()
 
 	// Add all preparser errors if any, including line numbers
-	var allErrors []ProcessingError
 	for _, prepErr := range prepErrors {
-		allErrors = append(allErrors, ProcessingError{
-			LineNumber: prepErr.LineInfo.Number,
-			Message:    prepErr.Message,
-			Code:       string(prepErr.Code),
-			Text:       prepErr.LineInfo.Text,
-			Type:       string(prepErr.LineInfo.Type),
-		})
+		allErrors = append(allErrors, sourceError(lexOut.sources, prepErr.LineInfo.Number,
+			prepErr.Message, string(prepErr.Code), prepErr.LineInfo.Text, string(prepErr.LineInfo.Type)))
 	}
 
 	return PreparserOutput{
 		SchemaType:    schema.SchemaTypePreparser,
 		SchemaVersion: schema.Version,
+		FormatVersion: lexOut.FormatVersion,
+		Metadata:      lexOut.Metadata,
 		Tokens:        parsed,
 		Errors:        allErrors,
+		Warnings:      preparseWarnings(lexOut, parsed, variableWarnings),
+		Markers:       editorialMarkers(parsed, lexOut.sources),
 		Success:       len(allErrors) == 0,
+
+		RemovedQuestions: removedQuestions,
+		sources:          lexOut.sources,
 	}, nil
 }
 
 func Build(lines []string, metadata *config.Metadata) (*BuilderOutput, error) {
-	preOut, err := Preparse(lines, metadata)
+	return buildSource(lines, nil, metadata)
+}
+
+// BuildFS reads the named file from fsys, resolves its @include directives and runs
+// the full build pipeline on the assembled guide
+func BuildFS(fsys fs.FS, name string, metadata *config.Metadata) (*BuilderOutput, error) {
+	lines, sources, encoding, includeErrors, err := resolveIncludes(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if includeErrors != nil {
+		return &BuilderOutput{
+			SchemaType:    schema.SchemaTypeBuilder,
+			SchemaVersion: schema.Version,
+			FormatVersion: format.Default,
+			Errors:        includeErrors,
+			Success:       false,
+		}, nil
+	}
+	return buildSource(lines, sources, withEncoding(metadata, encoding))
+}
+
+// BuildReader reads a guide from r, decoding it to UTF-8, and runs the full build
+// pipeline on it. @include directives are not resolved.
+func BuildReader(r io.Reader, metadata *config.Metadata) (*BuilderOutput, error) {
+	lines, encoding, err := charset.ReadLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guide: %w", err)
+	}
+	return buildSource(lines, nil, withEncoding(metadata, encoding))
+}
+
+func buildSource(lines []string, sources *source.Map, metadata *config.Metadata) (*BuilderOutput, error) {
+	preOut, err := preparseSource(lines, sources, metadata)
 	if err != nil {
 		return nil, fmt.Errorf("preparser error: %w", err)
 	}
@@ -235,12 +480,13 @@ func Build(lines []string, metadata *config.Metadata) (*BuilderOutput, error) {
 		return &BuilderOutput{
 			SchemaType:    schema.SchemaTypeBuilder,
 			SchemaVersion: schema.Version,
+			FormatVersion: preOut.FormatVersion,
 			Errors:        preOut.Errors,
 			Success:       false,
 		}, nil
 	}
 
-	return BuildFromPreparse(preOut, metadata)
+	return BuildFromPreparse(preOut, effectiveMetadata(preOut, metadata))
 }
 
 // BuildFromPreparse takes preparser output and runs the full build pipeline
@@ -250,6 +496,7 @@ func BuildFromPreparse(preOut PreparserOutput, metadata *config.Metadata) (*Buil
 		return &BuilderOutput{
 			SchemaType:    schema.SchemaTypeBuilder,
 			SchemaVersion: schema.Version,
+			FormatVersion: preOut.FormatVersion,
 			Errors:        preOut.Errors,
 			Success:       false,
 		}, nil
@@ -263,6 +510,7 @@ func BuildFromPreparse(preOut PreparserOutput, metadata *config.Metadata) (*Buil
 		return &BuilderOutput{
 			SchemaType:    schema.SchemaTypeBuilder,
 			SchemaVersion: schema.Version,
+			FormatVersion: parserOut.FormatVersion,
 			Errors:        parserOut.Errors,
 			Success:       false,
 		}, nil
@@ -276,7 +524,293 @@ func BuildFromParse(p *ParserOutput, metadata *config.Metadata) (*BuilderOutput,
 	return &BuilderOutput{
 		SchemaType:    schema.SchemaTypeBuilder,
 		SchemaVersion: schema.Version,
+		FormatVersion: p.FormatVersion,
 		Tree:          tree,
+		Warnings:      p.Warnings,
+		Markers:       p.Markers,
 		Success:       true,
 	}, nil
 }
+
+// listItemPrefixes resolves the question list item prefixes selected in the metadata options
+func listItemPrefixes(metadata *config.Metadata) ([]regexes.ListItemPrefix, error) {
+	if metadata == nil {
+		return regexes.DefaultListItemPrefixes, nil
+	}
+	return regexes.ListItemPrefixesFromOption(metadata.Options[constants.OptionListItemPrefixes])
+}
+
+// normalizationPolicy resolves the normalisation policy selected in the metadata options
+func normalizationPolicy(metadata *config.Metadata) (cleanstring.Policy, error) {
+	if metadata == nil {
+		return cleanstring.LegacyPolicy, nil
+	}
+	return cleanstring.ParsePolicy(metadata.Options[constants.OptionNormalization])
+}
+
+// checkBuilderOptions rejects invalid template and reading speed options before the builder reads them
+func checkBuilderOptions(metadata *config.Metadata) error {
+	if metadata == nil {
+		return nil
+	}
+	if _, err := templates.ParseCount(metadata.Options[constants.OptionTemplateInstances]); err != nil {
+		return err
+	}
+	if _, err := templates.ParseSeed(metadata.Options[constants.OptionTemplateSeed]); err != nil {
+		return err
+	}
+	_, err := readability.ParseReadingSpeed(metadata.Options[constants.OptionReadingSpeed])
+	return err
+}
+
+// keepComments reports whether the metadata options ask for comments to be kept as author
+// notes, or for author notes to be exported
+func keepComments(metadata *config.Metadata) bool {
+	return metadata != nil && (metadata.Options[constants.OptionKeepComments] == "true" ||
+		metadata.Options[constants.OptionExportAuthorNotes] == "true")
+}
+
+// effectiveMetadata returns the metadata produced by the preparser, which includes any
+// front matter, falling back to the caller's metadata
+func effectiveMetadata(preOut PreparserOutput, metadata *config.Metadata) *config.Metadata {
+	if preOut.Metadata != nil {
+		return preOut.Metadata
+	}
+	return metadata
+}
+
+// resolveIncludes reads the named file and expands its @include directives.
+// Problems with a directive are returned as processing errors; failing to read
+// the named file itself is returned as an error.
+func resolveIncludes(fsys fs.FS, name string) ([]string, *source.Map, charset.Encoding, []ProcessingError, error) {
+	resolver := include.NewResolver(fsys)
+	lines, sources, err := resolver.Resolve(name)
+	if err != nil {
+		var includeErr *include.IncludeError
+		if errors.As(err, &includeErr) {
+			return nil, nil, "", []ProcessingError{{
+				File:       includeErr.File,
+				LineNumber: includeErr.Line,
+				Message:    includeErr.Message,
+				Code:       string(includeErr.Code),
+				Text:       includeErr.Text,
+			}}, nil
+		}
+		return nil, nil, "", nil, fmt.Errorf("failed to read file %s: %w", name, err)
+	}
+	return lines, sources, resolver.Encoding(), nil, nil
+}
+
+// withEncoding returns a copy of the metadata that records the detected encoding of the guide
+func withEncoding(metadata *config.Metadata, encoding charset.Encoding) *config.Metadata {
+	if metadata == nil {
+		metadata = config.NewMetadata("")
+	} else {
+		metadata = metadata.Clone()
+	}
+	metadata.Encoding = string(encoding)
+	return metadata
+}
+
+// applyConditions drops the "@if" and "@end" directives and the lines of the blocks whose
+// condition fails for the metadata options. Nested blocks are kept only when every
+// enclosing block is kept, so the remaining lines read as if the others were never
+// written. It also returns the numbers of the dropped questions, read with the given list
+// item prefixes. Malformed conditions and unbalanced blocks are returned as errors.
+func applyConditions(tokens []lexer.LineInfo, lexOut LexerOutput, prefixes []regexes.ListItemPrefix) ([]lexer.LineInfo, []int, []ProcessingError) {
+	var options map[string]string
+	if lexOut.Metadata != nil {
+		options = lexOut.Metadata.Options
+	}
+	stack := conditions.NewStack(options)
+
+	var errs []ProcessingError
+	report := func(token lexer.LineInfo, err *conditions.ConditionError) ProcessingError {
+		return sourceError(lexOut.sources, token.Number, err.Message, string(err.Code), token.Text, string(token.Type))
+	}
+	ifTokens := make(map[int]lexer.LineInfo) // The "@if" tokens by line number
+	kept := make([]lexer.LineInfo, 0, len(tokens))
+	var removedQuestions []int
+	for _, token := range tokens {
+		var name, value string
+		if token.Type == lexer.TokenTypeDirective {
+			if match := regexes.DirectiveRegex.FindStringSubmatch(cleanstring.New(token.Text).Clean()); match != nil {
+				name, value = strings.ToLower(match[1]), match[2]
+			}
+		}
+		switch name {
+		case constants.IfDirective:
+			condition, err := conditions.Parse(value)
+			if err != nil {
+				errs = append(errs, report(token, err))
+			}
+			ifTokens[token.Number] = token
+			stack.If(condition, token.Number)
+		case constants.EndDirective:
+			if err := stack.End(token.Number); err != nil {
+				errs = append(errs, report(token, err))
+			}
+		default:
+			if stack.Included() {
+				kept = append(kept, token)
+			} else if token.Type == lexer.TokenTypeQuestion {
+				if match, ok := regexes.MatchListItemPrefix(cleanstring.New(token.Text).Clean(), prefixes); ok && match.Number > 0 {
+					removedQuestions = append(removedQuestions, match.Number)
+				}
+			}
+		}
+	}
+	for _, err := range stack.Unclosed() {
+		errs = append(errs, report(ifTokens[err.Line], err))
+	}
+	return kept, removedQuestions, errs
+}
+
+// expandVariables defines the variables of the "@define" directives and replaces the
+// "{{name}}" references in the text of the other tokens, except code and comments. It
+// returns the expanded copy of the tokens, the undefined and redefined variables as
+// errors and the unused ones as warnings. Malformed definitions are left to the preparser.
+func expandVariables(tokens []lexer.LineInfo, lexOut LexerOutput) ([]lexer.LineInfo, []ProcessingError, []ProcessingError) {
+	var values map[string]string
+	if lexOut.Metadata != nil {
+		values = lexOut.Metadata.Variables
+	}
+	table := variables.NewTable(values)
+
+	var errs, warnings []ProcessingError
+	definitions := make(map[string]lexer.LineInfo) // The "@define" tokens by variable name
+	report := func(token lexer.LineInfo, err *variables.VariableError) ProcessingError {
+		return sourceError(lexOut.sources, token.Number, err.Message, string(err.Code), token.Text, string(token.Type))
+	}
+	for _, token := range tokens {
+		if token.Type != lexer.TokenTypeDirective {
+			continue
+		}
+		match := regexes.DirectiveRegex.FindStringSubmatch(cleanstring.New(token.Text).Clean())
+		if match == nil || !strings.EqualFold(match[1], constants.DefineDirective) {
+			continue
+		}
+		if name, value, err := variables.ParseDefinition(match[2]); err == nil {
+			if err := table.Define(name, value, lexOut.sources.Position(token.Number)); err != nil {
+				errs = append(errs, report(token, err))
+			} else {
+				definitions[name] = token
+			}
+		}
+	}
+
+	expanded := make([]lexer.LineInfo, len(tokens))
+	for i, token := range tokens {
+		expanded[i] = token
+		switch token.Type {
+		case lexer.TokenTypeFileHeader, lexer.TokenTypeHeader, lexer.TokenTypeQuestion,
+			lexer.TokenTypePassage, lexer.TokenTypeLearnMore, lexer.TokenTypeContent:
+			text, expandErrors := table.Expand(token.Text)
+			expanded[i].Text = text
+			for _, err := range expandErrors {
+				errs = append(errs, report(token, err))
+			}
+		}
+	}
+
+	for _, definition := range table.Unused() {
+		warnings = append(warnings, report(definitions[definition.Name], variables.NewVariableError(variables.CodeUnusedVariable,
+			fmt.Sprintf("variable %q is defined but never used", definition.Name), definition.Name)))
+	}
+	return expanded, errs, warnings
+}
+
+// preparseWarnings collects the lexer and variable warnings and the markup and reference problems in the parsed lines
+func preparseWarnings(lexOut LexerOutput, parsed []preparser.ParsedLineInfo, variableWarnings []ProcessingError) []ProcessingError {
+	warnings := append([]ProcessingError{}, lexOut.Warnings...)
+	warnings = append(warnings, variableWarnings...)
+	warnings = append(warnings, markupWarnings(parsed, lexOut.sources)...)
+	warnings = append(warnings, referenceWarnings(parsed, lexOut.Metadata, lexOut.sources)...)
+	if len(warnings) == 0 {
+		return nil
+	}
+	return warnings
+}
+
+// markupWarnings reports malformed inline markup in the text of each parsed line
+func markupWarnings(lines []preparser.ParsedLineInfo, sources *source.Map) []ProcessingError {
+	var warnings []ProcessingError
+	for _, line := range lines {
+		for _, text := range markupTexts(line.ParsedValue) {
+			_, markupErrors := markup.Parse(text)
+			for _, markupErr := range markupErrors {
+				message := fmt.Sprintf("%s in %q at column %d", markupErr.Message, text, markupErr.Column)
+				warnings = append(warnings, sourceError(sources, line.Number,
+					message, string(markupErr.Code), line.Text, string(line.Type)))
+			}
+		}
+	}
+	return warnings
+}
+
+// referenceWarnings reports references that fail validation. The builder leaves them out of the tree.
+func referenceWarnings(lines []preparser.ParsedLineInfo, metadata *config.Metadata, sources *source.Map) []ProcessingError {
+	var option string
+	if metadata != nil {
+		option = metadata.Options[constants.OptionAllowedURLSchemes]
+	}
+	allowed := reference.ParseSchemes(option)
+
+	var warnings []ProcessingError
+	for _, line := range lines {
+		for _, r := range line.ParsedValue.GetReferences() {
+			if referenceErr := reference.Validate(r, allowed); referenceErr != nil {
+				warnings = append(warnings, sourceError(sources, line.Number,
+					referenceErr.Message, string(referenceErr.Code), line.Text, string(line.Type)))
+			}
+		}
+	}
+	return warnings
+}
+
+// editorialMarkers lists the comments that contain a TODO, FIXME or REVIEW marker
+func editorialMarkers(lines []preparser.ParsedLineInfo, sources *source.Map) []EditorialMarker {
+	var markers []EditorialMarker
+	for _, line := range lines {
+		comment := line.ParsedValue.GetComment()
+		if comment == nil || comment.Marker == "" {
+			continue
+		}
+		position := sources.Position(line.Number)
+		markers = append(markers, EditorialMarker{
+			File:       position.File,
+			LineNumber: position.Line,
+			Marker:     comment.Marker,
+			Text:       comment.Text,
+		})
+	}
+	return markers
+}
+
+// markupTexts returns the parts of a parsed line that may contain inline markup
+func markupTexts(value preparser.ParsedValue) []string {
+	switch {
+	case value.Question != nil:
+		return []string{value.Question.QuestionText, value.Question.AnswerText}
+	case value.LearnMore != nil:
+		return []string{value.LearnMore.Text}
+	case value.Passage != nil:
+		return []string{value.Passage.Text}
+	case value.Content != nil:
+		return []string{value.Content.Text}
+	}
+	return nil
+}
+
+// sourceError builds a ProcessingError located at the original file and line of a processed line
+func sourceError(sources *source.Map, lineNumber int, message string, code string, text string, lineType string) ProcessingError {
+	position := sources.Position(lineNumber)
+	return ProcessingError{
+		File:       position.File,
+		LineNumber: position.Line,
+		Message:    message,
+		Code:       code,
+		Text:       text,
+		Type:       lineType,
+	}
+}
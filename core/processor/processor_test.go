@@ -2,12 +2,19 @@ package processor
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/format"
+	"github.com/studyguides-com/study-guides-parser/core/lexer"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
 	"github.com/studyguides-com/study-guides-parser/core/schema"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
 )
 
 func TestParse(t *testing.T) {
@@ -409,8 +416,8 @@ func TestSchemaFieldsInOutputs(t *testing.T) {
 		if output["schema_type"] != "builder" {
 			t.Errorf("JSON schema_type = %v, want builder", output["schema_type"])
 		}
-		if output["schema_version"] != "1.0.0" {
-			t.Errorf("JSON schema_version = %v, want 1.0.0", output["schema_version"])
+		if output["schema_version"] != "2.0.0" {
+			t.Errorf("JSON schema_version = %v, want 2.0.0", output["schema_version"])
 		}
 		if _, hasData := output["data"]; hasData {
 			t.Error("JSON should not have nested 'data' field")
@@ -451,3 +458,1324 @@ func TestParseFileWithLexerError(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildWithFrontMatter(t *testing.T) {
+	lines := []string{
+		"---",
+		"context_type: College",
+		"content_rating: Everyone",
+		"author: Jane Doe",
+		"---",
+		"Mathematics Study Guide",
+		"College: Mathematics: MATH 101: Linear Equations",
+		"1. What is x? - A variable",
+	}
+
+	result, err := Build(lines, config.NewMetadata("build"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed: %+v", result.Errors)
+	}
+	if result.Tree.Metadata.ContextType != ontology.ContextTypeCollege || result.Tree.Metadata.Author != "Jane Doe" {
+		t.Errorf("front matter not merged into metadata: %+v", result.Tree.Metadata)
+	}
+	category := result.Tree.Root.ChildTags[0]
+	if category.TagType != ontology.TagTypeCategory {
+		t.Errorf("TagType = %q, want %q", category.TagType, ontology.TagTypeCategory)
+	}
+	if category.ContentRating != ontology.ContentRatingEveryone {
+		t.Errorf("ContentRating = %q, want %q", category.ContentRating, ontology.ContentRatingEveryone)
+	}
+}
+
+func TestFrontMatterErrorsReportSourceLines(t *testing.T) {
+	lines := []string{
+		"---",
+		"language: en",
+		"---",
+		"Mathematics Study Guide",
+		"College: Mathematics: MATH 101: Linear Equations",
+		"Passage:",
+	}
+
+	result, err := Preparse(lines, config.NewMetadata("preparse"))
+	if err != nil {
+		t.Fatalf("Preparse() unexpected error: %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 {
+		t.Fatalf("expected one error, got %+v", result.Errors)
+	}
+	if result.Errors[0].LineNumber != 6 {
+		t.Errorf("LineNumber = %d, want 6", result.Errors[0].LineNumber)
+	}
+
+	lexOut, err := Lex([]string{"---", "context_type: Nope", "---", "Title"}, config.NewMetadata("lex"))
+	if err != nil {
+		t.Fatalf("Lex() unexpected error: %v", err)
+	}
+	if lexOut.Success || lexOut.Errors[0].LineNumber != 2 || lexOut.Errors[0].Code != "INVALID_FRONT_MATTER" {
+		t.Errorf("unexpected lexer output: %+v", lexOut.Errors)
+	}
+}
+
+func TestBuildFSWithIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"guide.txt":           {Data: []byte("---\ncontext_type: Certifications\n---\nSecurity+ Study Guide\n@include domains/threats.txt\n@include domains/ops.txt")},
+		"domains/threats.txt": {Data: []byte("Certifications: CompTIA: Security+: Threats\n1. What is phishing? - A social engineering attack")},
+		"domains/ops.txt":     {Data: []byte("Certifications: CompTIA: Security+: Operations\n\nPassage:\n")},
+	}
+
+	result, err := BuildFS(fsys, "guide.txt", config.NewMetadata("build"))
+	if err != nil {
+		t.Fatalf("BuildFS() unexpected error: %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 {
+		t.Fatalf("expected one error, got %+v", result.Errors)
+	}
+	if got := result.Errors[0]; got.File != "domains/ops.txt" || got.LineNumber != 3 {
+		t.Errorf("error at %s:%d, want domains/ops.txt:3", got.File, got.LineNumber)
+	}
+
+	fsys["domains/ops.txt"] = &fstest.MapFile{Data: []byte("Certifications: CompTIA: Security+: Operations\n1. What is a SIEM? - A log correlation system")}
+	result, err = BuildFS(fsys, "guide.txt", config.NewMetadata("build"))
+	if err != nil {
+		t.Fatalf("BuildFS() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("BuildFS() failed: %+v", result.Errors)
+	}
+	certification := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0]
+	if len(certification.ChildTags) != 2 {
+		t.Errorf("expected 2 domains from included files, got %d", len(certification.ChildTags))
+	}
+}
+
+func TestParseFSIncludeErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"guide.txt": {Data: []byte("Study Guide\n@include a.txt")},
+		"a.txt":     {Data: []byte("@include guide.txt")},
+	}
+
+	result, err := ParseFS(fsys, "guide.txt", config.NewMetadata("parse"))
+	if err != nil {
+		t.Fatalf("ParseFS() unexpected error: %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 || result.Errors[0].Code != "INCLUDE_CYCLE" {
+		t.Fatalf("expected include cycle error, got %+v", result.Errors)
+	}
+	if got := result.Errors[0]; got.File != "a.txt" || got.LineNumber != 1 {
+		t.Errorf("error at %s:%d, want a.txt:1", got.File, got.LineNumber)
+	}
+
+	if _, err := ParseFS(fsys, "missing.txt", config.NewMetadata("parse")); err == nil {
+		t.Error("ParseFS() expected error for a missing root file")
+	}
+}
+
+func TestBuildPassageBlocks(t *testing.T) {
+	lines := []string{
+		"TestFile",
+		"",
+		"TagA: TagB: TagC: TagD",
+		"",
+		"Passage: Apples",
+		"Tim had 5 apples",
+		"and gave Mike 3.",
+		"",
+		"• Tim keeps 2",
+		"• Mike gets 3",
+		"",
+		"| Name | Apples |",
+		"| --- | --- |",
+		"| Tim | 2 |",
+		"| Mike | 3 |",
+		"",
+		"> An apple a day",
+		"> keeps the doctor away",
+		"1. How many apples does Tim have? - 2",
+	}
+
+	result, err := Build(lines, config.NewMetadata("test_parser"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	passage := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Passages[0]
+	if !strings.HasPrefix(passage.Content, "Tim had 5 apples\nand gave Mike 3.\n") {
+		t.Errorf("Content = %q, want flat content joined by newlines", passage.Content)
+	}
+	if len(passage.Blocks) != 4 {
+		t.Fatalf("Expected 4 blocks, got %d: %+v", len(passage.Blocks), passage.Blocks)
+	}
+
+	paragraph, list, table, quote := passage.Blocks[0], passage.Blocks[1], passage.Blocks[2], passage.Blocks[3]
+	if paragraph.Type != tree.BlockTypeParagraph || paragraph.Text != "Tim had 5 apples and gave Mike 3." {
+		t.Errorf("paragraph = %+v", paragraph)
+	}
+	if list.Type != tree.BlockTypeList || len(list.Items) != 2 || list.Items[1] != "Mike gets 3" {
+		t.Errorf("list = %+v", list)
+	}
+	if table.Type != tree.BlockTypeTable || len(table.Header) != 2 || table.Header[0] != "Name" || len(table.Rows) != 2 {
+		t.Errorf("table = %+v", table)
+	}
+	if quote.Type != tree.BlockTypeQuote || quote.Text != "An apple a day keeps the doctor away" {
+		t.Errorf("quote = %+v", quote)
+	}
+	if len(passage.Questions) != 1 {
+		t.Errorf("Expected 1 passage question, got %d", len(passage.Questions))
+	}
+
+	// "-" and "*" lines without an answer are list items inside a passage, and still
+	// questions when they have one
+	lines = []string{
+		"TestFile",
+		"TagA: TagB: TagC: TagD",
+		"Passage: Cells",
+		"A cell contains:",
+		"- Mitochondria",
+		"* Ribosomes",
+		"- What makes proteins? - Ribosomes",
+	}
+	result, err = Build(lines, config.NewMetadata("test_parser"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	passage = result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Passages[0]
+	if len(passage.Blocks) != 2 || fmt.Sprint(passage.Blocks[1].Items) != "[Mitochondria Ribosomes]" {
+		t.Errorf("Blocks = %+v, want a paragraph and the list", passage.Blocks)
+	}
+	if len(passage.Questions) != 1 || passage.Questions[0].Answer != "Ribosomes" {
+		t.Errorf("Questions = %+v, want the bulleted question", passage.Questions)
+	}
+}
+
+func TestBuildInlineMarkup(t *testing.T) {
+	lines := []string{
+		"---",
+		"author: Jane Doe",
+		"---",
+		"Physics Study Guide",
+		"",
+		"College: Physics: PHYS 101: Relativity",
+		"",
+		"1. What does **Einstein's** equation $E=mc^2$ relate? - Mass and energy",
+		"2. What is the unit of $c? - Metres per second",
+	}
+
+	result, err := Build(lines, config.NewMetadata("test_parser"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	// Malformed markup is a warning located at the original line
+	if len(result.Warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %+v", len(result.Warnings), result.Warnings)
+	}
+	warning := result.Warnings[0]
+	if warning.LineNumber != 9 || warning.Code != "UNCLOSED_MARKUP" {
+		t.Errorf("warning = %+v, want UNCLOSED_MARKUP on line 9", warning)
+	}
+
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	question := tag.Questions[0]
+	if question.PromptRich == nil {
+		t.Fatal("Expected PromptRich to be set for a prompt with markup")
+	}
+	if question.PromptRich.Plain != "What does Einstein's equation E=mc^2 relate?" {
+		t.Errorf("PromptRich.Plain = %q", question.PromptRich.Plain)
+	}
+	if question.AnswerRich != nil {
+		t.Errorf("Expected AnswerRich to be nil for a plain answer, got %+v", question.AnswerRich)
+	}
+}
+
+func TestBuildCodeBlocks(t *testing.T) {
+	lines := []string{
+		"AP Computer Science Study Guide",
+		"@format: 2",
+		"AP: Computer Science: Unit 1: Functions",
+		"",
+		"1. What does this function return for add(1, 2)? - 3",
+		"```python",
+		"# add two numbers",
+		"def add(a, b):",
+		"    return a + b",
+		"```",
+		"```python answer",
+		"add(1, 2)  # 3",
+		"```",
+		"Learn More: Functions return the value of their return statement",
+		"",
+		"Passage: Loops",
+		"",
+		"A for loop repeats its body.",
+		"```go",
+		"for i := 0; i < 3; i++ {",
+		"\tfmt.Println(i)",
+		"}",
+		"```",
+		"1. How many times does the loop run? - 3",
+	}
+
+	result, err := Build(lines, config.NewMetadata("ap_exams"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	question := tag.Questions[0]
+	if len(question.PromptCode) != 1 || len(question.AnswerCode) != 1 {
+		t.Fatalf("Expected 1 prompt and 1 answer code block, got %+v and %+v", question.PromptCode, question.AnswerCode)
+	}
+	wantCode := "# add two numbers\ndef add(a, b):\n    return a + b"
+	if question.PromptCode[0].Language != "python" || question.PromptCode[0].Code != wantCode {
+		t.Errorf("PromptCode = %+v, want python code %q", question.PromptCode[0], wantCode)
+	}
+	if len(question.LearnMore) == 0 {
+		t.Error("Expected Learn More after the code blocks to stay on the question")
+	}
+
+	passage := tag.Passages[0]
+	if len(passage.Blocks) != 2 || passage.Blocks[1].Type != tree.BlockTypeCode {
+		t.Fatalf("Expected a paragraph and a code block, got %+v", passage.Blocks)
+	}
+	if passage.Blocks[1].Code.Code != "for i := 0; i < 3; i++ {\n\tfmt.Println(i)\n}" {
+		t.Errorf("passage code = %q", passage.Blocks[1].Code.Code)
+	}
+	if len(passage.Questions) != 1 {
+		t.Errorf("Expected 1 passage question, got %d", len(passage.Questions))
+	}
+}
+
+func TestLexUnclosedCodeBlock(t *testing.T) {
+	lines := []string{
+		"AP Computer Science Study Guide",
+		"@format: 2",
+		"AP: Computer Science: Unit 1: Functions",
+		"1. What does this print? - 3",
+		"```python",
+		"print(1 + 2)",
+	}
+
+	result, err := Lex(lines, config.NewMetadata("ap_exams"))
+	if err != nil {
+		t.Fatalf("Lex() unexpected error: %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %+v", result.Errors)
+	}
+	if result.Errors[0].Code != "UNCLOSED_CODE_BLOCK" || result.Errors[0].LineNumber != 5 {
+		t.Errorf("error = %+v, want UNCLOSED_CODE_BLOCK on line 5", result.Errors[0])
+	}
+}
+
+func TestBuildQuestionTypes(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"@format: 2",
+		"College: Geography: GEO 101: Europe",
+		"",
+		"1. What is the capital of France? - Paris",
+		"2. [tf] Madrid is the capital of Spain. - True",
+		"3. [match] Match each country to its capital. - France = Paris; Spain = Madrid",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	questions := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Questions
+	if questions[0].QuestionType != ontology.QuestionTypeShortAnswer || questions[0].StructuredAnswer != nil {
+		t.Errorf("question 1 = %s %+v, want a short answer", questions[0].QuestionType, questions[0].StructuredAnswer)
+	}
+	if questions[1].QuestionType != ontology.QuestionTypeTrueFalse || questions[1].Prompt != "Madrid is the capital of Spain." {
+		t.Errorf("question 2 = %s %q", questions[1].QuestionType, questions[1].Prompt)
+	}
+
+	data, err := json.Marshal(questions[2])
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	want := `"question_type":"Matching","structured_answer":{"pairs":[{"left":"France","right":"Paris"},{"left":"Spain","right":"Madrid"}]}`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("JSON = %s, want it to contain %s", data, want)
+	}
+}
+
+func TestBuildAcceptableAnswers(t *testing.T) {
+	lines := []string{
+		"Science Study Guide",
+		"@format: 2",
+		"College: Science: SCI 101: Physics",
+		"",
+		"1. Who proposed general relativity? - Albert Einstein | Einstein",
+		"2. Acceleration of gravity in m/s²? - 9.8 ± 0.1",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	questions := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Questions
+	if questions[0].Answer != "Albert Einstein" || len(questions[0].AcceptableAnswers) != 1 || questions[0].AcceptableAnswers[0] != "Einstein" {
+		t.Errorf("question 1 answer = %q, acceptable = %q", questions[0].Answer, questions[0].AcceptableAnswers)
+	}
+	if questions[1].Answer != "9.8 ± 0.1" || questions[1].Tolerance != 0.1 {
+		t.Errorf("question 2 answer = %q, tolerance = %v", questions[1].Answer, questions[1].Tolerance)
+	}
+	if value := questions[1].AnswerValue; value.Number == nil || *value.Number != 9.8 {
+		t.Errorf("question 2 answer value = %+v, want the number 9.8", value)
+	}
+}
+
+func TestBuildTagNotes(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"",
+		"College: Geography: GEO 101: Europe",
+		"",
+		"Europe is the second smallest continent.",
+		"It has **44** countries.",
+		"",
+		"1. What is the capital of France? - Paris",
+		"Paris lies on the Seine.",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	want := "Europe is the second smallest continent.\nIt has **44** countries.\nParis lies on the Seine."
+	if tag.Notes != want {
+		t.Errorf("Notes = %q, want %q", tag.Notes, want)
+	}
+	if tag.NotesRich == nil {
+		t.Error("NotesRich should be set for notes with markup")
+	}
+	if len(tag.Questions) != 1 {
+		t.Errorf("len(Questions) = %d, want 1", len(tag.Questions))
+	}
+
+	if len(result.Warnings) != 1 {
+		t.Fatalf("Warnings = %v, want 1 warning", result.Warnings)
+	}
+	if result.Warnings[0].Code != "STRAY_CONTENT" || result.Warnings[0].LineNumber != 9 {
+		t.Errorf("warning = %+v, want STRAY_CONTENT on line 9", result.Warnings[0])
+	}
+}
+
+func TestBuildLearnMore(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"",
+		"College: Geography: GEO 101: Europe",
+		"",
+		"Learn More: An atlas of Europe",
+		"",
+		"1. What is the capital of France? - Paris",
+		"Learn More: A history of Paris",
+		"Learn More: A *guide* to the Seine",
+		"",
+		"Passage: The Alps",
+		"",
+		"The Alps cross eight countries.",
+		"Learn More: A map of the Alps",
+		"",
+		"1. What is the highest peak? - Mont Blanc",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if strings.Join(tag.LearnMore, "|") != "An atlas of Europe" {
+		t.Errorf("tag LearnMore = %q", tag.LearnMore)
+	}
+
+	question := tag.Questions[0]
+	if strings.Join(question.LearnMore, "|") != "A history of Paris|A *guide* to the Seine" {
+		t.Errorf("question LearnMore = %q", question.LearnMore)
+	}
+	if len(question.LearnMoreRich) != 2 || question.LearnMoreRich[0] != nil || question.LearnMoreRich[1] == nil {
+		t.Errorf("question LearnMoreRich = %+v, want rich text for the second entry only", question.LearnMoreRich)
+	}
+
+	passage := tag.Passages[0]
+	if strings.Join(passage.LearnMore, "|") != "A map of the Alps" {
+		t.Errorf("passage LearnMore = %q", passage.LearnMore)
+	}
+	if len(passage.Questions) != 1 || len(passage.Questions[0].LearnMore) != 0 {
+		t.Errorf("passage questions = %+v, want one question without Learn More", passage.Questions)
+	}
+}
+
+func TestBuildReferences(t *testing.T) {
+	lines := []string{
+		"Biology Study Guide",
+		"",
+		"College: Biology: BIO 101: Cells",
+		"",
+		"Learn More: https://example.com/cells",
+		"",
+		"1. Who described the double helix? - Watson and Crick",
+		"Learn More: doi:10.1038/171737a0",
+		"Learn More: ftp://files.example.com/helix.pdf",
+		"",
+		"Passage: The Cell",
+		"",
+		"Cells are the basic unit of life.",
+		"Source: Alberts, Molecular Biology of the Cell, ISBN 978-0-8153-4432-2",
+		"",
+		"1. What is the basic unit of life? - The cell",
+		"Learn More: https://EXAMPLE.com/cells/",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if len(tag.References) != 1 || tag.References[0].Kind != reference.KindURL {
+		t.Errorf("tag References = %+v, want one URL", tag.References)
+	}
+	question := tag.Questions[0]
+	if len(question.References) != 1 || question.References[0].URL != "https://doi.org/10.1038/171737a0" {
+		t.Errorf("question References = %+v, want the DOI only", question.References)
+	}
+	passage := tag.Passages[0]
+	if len(passage.References) != 2 || passage.References[0].Kind != reference.KindSource || passage.References[1].Kind != reference.KindISBN {
+		t.Errorf("passage References = %+v, want a source and an ISBN", passage.References)
+	}
+
+	// The two spellings of the same URL are one entry cited by the tag and a question
+	bibliography := result.Tree.Bibliography
+	if len(bibliography) != 4 {
+		t.Fatalf("len(Bibliography) = %d, want 4: %+v", len(bibliography), bibliography)
+	}
+	if bibliography[0].Value != "https://example.com/cells" || len(bibliography[0].CitedBy) != 2 {
+		t.Errorf("Bibliography[0] = %+v, want the URL cited twice", bibliography[0])
+	}
+
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != string(reference.CodeDisallowedScheme) || result.Warnings[0].LineNumber != 9 {
+		t.Errorf("Warnings = %+v, want a disallowed scheme on line 9", result.Warnings)
+	}
+}
+
+func TestPreparseQuestionTypeErrors(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"@format: 2",
+		"College: Geography: GEO 101: Europe",
+		"1. [tf] Madrid is the capital of Spain. - Yes",
+	}
+
+	result, err := Preparse(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Preparse() unexpected error: %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 || result.Errors[0].LineNumber != 4 {
+		t.Errorf("Expected 1 error on line 4, got %+v", result.Errors)
+	}
+}
+
+func TestBuildClozeQuestions(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"@format: 2",
+		"College: Geography: GEO 101: Europe",
+		"",
+		"1. The capital of {{c1::France}} is {{c2::Paris::a city}}",
+		"2. What river runs through Paris? - The Seine",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if len(tag.Questions) != 3 {
+		t.Fatalf("Expected 3 questions, got %d", len(tag.Questions))
+	}
+	c1, c2 := tag.Questions[0], tag.Questions[1]
+	if c1.Prompt != "The capital of [...] is Paris" || c1.Answer != "France" || c1.ClozeIndex != 1 {
+		t.Errorf("c1 = %q / %q / %d", c1.Prompt, c1.Answer, c1.ClozeIndex)
+	}
+	if c2.Prompt != "The capital of France is [a city]" || c2.Answer != "Paris" || c2.ClozeIndex != 2 {
+		t.Errorf("c2 = %q / %q / %d", c2.Prompt, c2.Answer, c2.ClozeIndex)
+	}
+	if c1.Hash == c2.Hash {
+		t.Error("Expected each cloze question to have its own hash")
+	}
+	if tag.Questions[2].Order != 3 {
+		t.Errorf("Expected the next question to have order 3, got %d", tag.Questions[2].Order)
+	}
+
+	// Hashes depend only on the source sentence and index
+	again, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if again.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Questions[1].Hash != c2.Hash {
+		t.Error("Expected cloze hashes to be stable between builds")
+	}
+
+	// The numbering QA treats the cloze questions as the author's question 1
+	for _, qaResult := range result.Tree.GetQAResults().Results {
+		if strings.HasPrefix(qaResult.Name, "Question numbering") && !qaResult.Passed {
+			t.Errorf("%s: %v", qaResult.Name, qaResult.Warnings)
+		}
+	}
+}
+
+func TestLexClozeNeedsFormat2(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"College: Geography: GEO 101: Europe",
+		"1. The capital of {{c1::France}} is Paris",
+	}
+
+	result, err := Lex(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Lex() unexpected error: %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 || result.Errors[0].LineNumber != 3 {
+		t.Errorf("Expected 1 error on line 3, got %+v", result.Errors)
+	}
+}
+
+func TestBuildAuthorNotes(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"",
+		"# REVIEW: split this section by region",
+		"College: Geography: GEO 101: Europe",
+		"",
+		"# TODO: double-check the population figure",
+		"1. What is the capital of France? - Paris",
+		"",
+		"Passage: The Alps",
+		"# Sources are in the shared folder",
+		"The Alps span eight countries.",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if tag.Questions[0].AuthorNotes != nil {
+		t.Errorf("comments are dropped by default, got %v", tag.Questions[0].AuthorNotes)
+	}
+
+	// Markers are reported whether or not comments are kept
+	if len(result.Markers) != 2 {
+		t.Fatalf("Markers = %v, want 2 markers", result.Markers)
+	}
+	if marker := result.Markers[1]; marker.Marker != "TODO" || marker.LineNumber != 6 ||
+		marker.Text != "TODO: double-check the population figure" {
+		t.Errorf("Markers[1] = %+v, want the TODO on line 6", marker)
+	}
+
+	result, err = Build(lines, config.NewMetadata("college").WithOption("keep_comments", "true"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	tag = result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"tag", tag.AuthorNotes, "REVIEW: split this section by region"},
+		{"question", tag.Questions[0].AuthorNotes, "TODO: double-check the population figure"},
+		{"passage", tag.Passages[0].AuthorNotes, "Sources are in the shared folder"},
+	}
+	for _, tt := range tests {
+		if len(tt.got) != 1 || tt.got[0] != tt.want {
+			t.Errorf("%s AuthorNotes = %q, want [%q]", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Author notes stay out of the exported tree
+	data, err := json.Marshal(result.Tree)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	if strings.Contains(string(data), "population figure") {
+		t.Errorf("exported tree contains author notes: %s", data)
+	}
+
+	// A repeated header adds its notes to those of the tag
+	repeated := append(append([]string{}, lines...), "", "# Check the capitals again", "College: Geography: GEO 101: Europe",
+		"2. What is the capital of Spain? - Madrid")
+	result, err = Build(repeated, config.NewMetadata("college").WithOption("keep_comments", "true"))
+	if err != nil || !result.Success {
+		t.Fatalf("Build() = %v, %v", result.Errors, err)
+	}
+	tag = result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if want := "[REVIEW: split this section by region Check the capitals again]"; fmt.Sprint(tag.AuthorNotes) != want {
+		t.Errorf("tag AuthorNotes = %q, want %s", tag.AuthorNotes, want)
+	}
+
+	// Exporting the notes keeps comments and lists them by hash
+	result, err = Build(lines, config.NewMetadata("college").WithOption("export_author_notes", "true"))
+	if err != nil || !result.Success {
+		t.Fatalf("Build() = %v, %v", result.Errors, err)
+	}
+	tag = result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	notes := result.Tree.AuthorNotes
+	if len(notes) != 3 || notes[0].Hash != tag.Hash || notes[1].Hash != tag.Questions[0].Hash ||
+		fmt.Sprint(notes[1].Notes) != "[TODO: double-check the population figure]" || notes[2].Hash != tag.Passages[0].Hash {
+		t.Errorf("Tree.AuthorNotes = %+v, want the notes of the tag, question and passage", notes)
+	}
+	data, err = json.Marshal(result.Tree)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %v", err)
+	}
+	if !strings.Contains(string(data), `"author_notes":[{"hash":`) {
+		t.Errorf("exported tree has no author notes: %s", data)
+	}
+}
+
+func TestBuildNearMissWarnings(t *testing.T) {
+	lines := []string{
+		"Geography Study Guide",
+		"",
+		"College: Geography: GEO 101: Europe",
+		"",
+		"Pasage: The Alps",
+		"The Alps span eight countries.",
+		"1 Which is the highest peak? - Mont Blanc",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	want := []ProcessingError{
+		{LineNumber: 5, Code: "NEAR_MISS", Suggestion: "Passage: The Alps"},
+		{LineNumber: 7, Code: "NEAR_MISS", Suggestion: "1. Which is the highest peak? - Mont Blanc"},
+	}
+	// Other warnings, such as markup problems, are not checked here
+	var nearMisses []ProcessingError
+	for _, warning := range result.Warnings {
+		if warning.Code == "NEAR_MISS" {
+			nearMisses = append(nearMisses, warning)
+		}
+	}
+	if len(nearMisses) != len(want) {
+		t.Fatalf("near-miss warnings = %v, want %d", nearMisses, len(want))
+	}
+	for i, w := range want {
+		got := nearMisses[i]
+		if got.LineNumber != w.LineNumber || got.Suggestion != w.Suggestion {
+			t.Errorf("warning %d = %+v, want line %d suggesting %q", i, got, w.LineNumber, w.Suggestion)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	lines := []string{
+		"Math Study Guide",
+		"",
+		"College: Math: MATH 101: Ratios",
+		"1. Ratio: 1: 2? - Half",
+	}
+
+	result, err := Explain(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	if !result.Success || result.SchemaType != schema.SchemaTypeExplain {
+		t.Fatalf("Explain() = %+v, want a successful explain output", result)
+	}
+	if len(result.Lines) != len(lines) {
+		t.Fatalf("len(Lines) = %d, want %d", len(result.Lines), len(lines))
+	}
+	if got := result.Lines[3]; got.Winner != "question" || len(got.Matches) != 2 {
+		t.Errorf("Lines[3] = %+v, want a question that also matches the header pattern", got)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != "AMBIGUOUS_LINE" || result.Warnings[0].LineNumber != 4 {
+		t.Errorf("Warnings = %v, want AMBIGUOUS_LINE on line 4", result.Warnings)
+	}
+
+	// The same warning reaches the later stages
+	built, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if len(built.Warnings) != 1 || built.Warnings[0].Code != "AMBIGUOUS_LINE" {
+		t.Errorf("Build() warnings = %v, want AMBIGUOUS_LINE", built.Warnings)
+	}
+}
+
+func TestBuildNormalizationPolicy(t *testing.T) {
+	build := func(policy string, lines ...string) *tree.Tag {
+		t.Helper()
+		guide := append([]string{"Geography Study Guide", "@format: 2", "College: Geography: GEO 101: Cities", ""}, lines...)
+		result, err := Build(guide, config.NewMetadata("college").WithOption("normalization", policy))
+		if err != nil {
+			t.Fatalf("Build() unexpected error: %v", err)
+		}
+		if !result.Success {
+			t.Fatalf("Build() failed with errors: %v", result.Errors)
+		}
+		return result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	}
+
+	// By default a non-breaking space is removed, merging the words around it
+	legacy := build("", "1. Which city is called the Big Apple? - New\u00A0York")
+	if got := legacy.Questions[0].Answer; got != "NewYork" {
+		t.Errorf("legacy Answer = %q, want %q", got, "NewYork")
+	}
+
+	standard := build("standard", "1. Which city is called the Big Apple? - New\u00A0York")
+	if got := standard.Questions[0].Answer; got != "New York" {
+		t.Errorf("standard Answer = %q, want %q", got, "New York")
+	}
+
+	// Text typed in different editors hashes the same under the standard policy
+	composed := build("standard", "1. Where is the Café de Flore? - Paris")
+	decomposed := build("standard", "1. Where is the Cafe\u0301 de Flore?  \u2014  Paris")
+	if composed.Questions[0].Hash != decomposed.Questions[0].Hash {
+		t.Errorf("hashes differ: %q and %q", composed.Questions[0].Prompt, decomposed.Questions[0].Prompt)
+	}
+
+	// Policies without keep_case fold case in hashes, but keep the text as written
+	upper := build("nfc", "1. Capital of France? - PARIS")
+	lower := build("nfc", "1. capital of france? - paris")
+	if upper.Questions[0].Hash != lower.Questions[0].Hash || upper.Hash != lower.Hash {
+		t.Error("hashes should not depend on case without keep_case")
+	}
+	if upper.Questions[0].Answer != "PARIS" {
+		t.Errorf("Answer = %q, want %q", upper.Questions[0].Answer, "PARIS")
+	}
+	upperPassage := build("nfc", "Passage: The SEINE", "1. {{c1::PARIS}} is on the Seine")
+	lowerPassage := build("nfc", "Passage: the seine", "1. {{c1::paris}} is on the seine")
+	if upperPassage.Passages[0].Hash != lowerPassage.Passages[0].Hash ||
+		upperPassage.Passages[0].Questions[0].Hash != lowerPassage.Passages[0].Questions[0].Hash {
+		t.Error("passage and cloze hashes should not depend on case without keep_case")
+	}
+	if cased := build("nfc,keep_case", "1. Capital of France? - PARIS"); cased.Questions[0].Hash == lower.Questions[0].Hash {
+		t.Error("hashes should depend on case with keep_case")
+	}
+
+	if _, err := Build([]string{"Guide"}, config.NewMetadata("college").WithOption("normalization", "nfd")); err == nil {
+		t.Error("Build() should reject an unknown normalization setting")
+	}
+}
+
+func TestBuildFSEncodings(t *testing.T) {
+	// A UTF-16 guide with a byte order mark and CRLF line endings, as Word exports it,
+	// that includes a Windows-1252 file
+	guide := "Geography Study Guide\r\nCollege: Geography: GEO 101: Europe\r\n@include more.txt\r\n"
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range guide {
+		utf16 = append(utf16, byte(r), 0)
+	}
+	fsys := fstest.MapFS{
+		"guide.txt": {Data: utf16},
+		"more.txt":  {Data: []byte("1. What is the capital of France? - \x93Paris\x94\r\n")},
+	}
+
+	result, err := BuildFS(fsys, "guide.txt", config.NewMetadata("build"))
+	if err != nil {
+		t.Fatalf("BuildFS() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("BuildFS() failed with errors: %v", result.Errors)
+	}
+	if result.Tree.Root.Title != "Geography Study Guide" {
+		t.Errorf("Title = %q, want %q", result.Tree.Root.Title, "Geography Study Guide")
+	}
+	if got := result.Tree.Metadata.Encoding; got != "utf-16le" {
+		t.Errorf("Encoding = %q, want %q", got, "utf-16le")
+	}
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if got := tag.Questions[0].Answer; got != "“Paris”" {
+		t.Errorf("Answer = %q, want %q", got, "“Paris”")
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	metadata := config.NewMetadata("parse")
+	result, err := ParseReader(strings.NewReader("\uFEFFStudy Guide\r\nCollege: A: B: C\r\n1. Q? - A"), metadata)
+	if err != nil {
+		t.Fatalf("ParseReader() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("ParseReader() failed with errors: %v", result.Errors)
+	}
+	if got := result.AST.Metadata.Encoding; got != "utf-8-bom" {
+		t.Errorf("Encoding = %q, want %q", got, "utf-8-bom")
+	}
+	if metadata.Encoding != "" {
+		t.Error("ParseReader() should not modify the caller's metadata")
+	}
+	if title := result.AST.Root.Data.GetFileHeader().Title; title != "Study Guide" {
+		t.Errorf("Title = %q, want %q", title, "Study Guide")
+	}
+}
+
+func TestBuildContextDirective(t *testing.T) {
+	lines := []string{
+		"Biology Study Guide",
+		"College: Biology: BIO 101: Cells",
+		"1. What is the powerhouse of the cell? - Mitochondria",
+		"@context: APExams",
+		"AP Exams: AP Biology: Unit 1",
+		"1. What molecule carries genetic information? - DNA",
+	}
+	metadata := config.NewMetadata("college")
+	metadata.ContextType = ontology.ContextTypeCollege
+
+	result, err := Build(lines, metadata)
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+
+	college, ap := result.Tree.Root.ChildTags[0], result.Tree.Root.ChildTags[1]
+	if college.Context != ontology.ContextTypeCollege || college.ChildTags[0].TagType != ontology.TagTypeDepartment {
+		t.Errorf("college branch = %s/%s, want %s/%s", college.Context, college.ChildTags[0].TagType,
+			ontology.ContextTypeCollege, ontology.TagTypeDepartment)
+	}
+	if ap.DeclaredContext != ontology.ContextTypeAPExams {
+		t.Errorf("DeclaredContext = %q, want %q", ap.DeclaredContext, ontology.ContextTypeAPExams)
+	}
+	// The AP branch is three levels deep, although the guide is four
+	unit := ap.ChildTags[0].ChildTags[0]
+	if ap.ChildTags[0].TagType != ontology.TagTypeAPExam || unit.TagType != ontology.TagTypeTopic || unit.Context != ontology.ContextTypeAPExams {
+		t.Errorf("AP branch = %s, %s/%s", ap.ChildTags[0].TagType, unit.TagType, unit.Context)
+	}
+	for _, qaResult := range result.Tree.GetQAResults().Results {
+		if strings.HasPrefix(qaResult.Name, "Must have") && !qaResult.Passed {
+			t.Errorf("QA %q failed: %v", qaResult.Name, qaResult.Warnings)
+		}
+	}
+
+	preparsed, err := Preparse([]string{"Biology Study Guide", "@context: Kindergarten"}, metadata)
+	if err != nil {
+		t.Fatalf("Preparse() unexpected error: %v", err)
+	}
+	if preparsed.Success || len(preparsed.Errors) != 1 || preparsed.Errors[0].LineNumber != 2 {
+		t.Errorf("Expected 1 error on line 2 for an unknown context, got %+v", preparsed.Errors)
+	}
+}
+
+func TestFormatVersion(t *testing.T) {
+	guide := func(head ...string) []string {
+		return append(head, "College: Biology: BIO 101: Cells", "@teacher: Smith", "1. What is the powerhouse of the cell? - Mitochondria")
+	}
+
+	// Without a declaration the guide is read as format 1 and "@teacher" is content
+	result, err := Build(guide("Biology Study Guide"), config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success || result.FormatVersion != format.Version1 {
+		t.Fatalf("Build() = success %v, format %s, errors %v; want format 1", result.Success, result.FormatVersion, result.Errors)
+	}
+	jsonBytes, _ := json.Marshal(result)
+	if !strings.Contains(string(jsonBytes), `"format_version":1`) {
+		t.Errorf("JSON does not report the format version: %s", jsonBytes)
+	}
+
+	// In format 2 every "@name" line is a directive, so the unknown one is an error
+	for name, lines := range map[string][]string{
+		"directive":    guide("Biology Study Guide", "@format: 2"),
+		"front matter": guide("---", "format_version: 2", "---", "Biology Study Guide"),
+	} {
+		lexOut, err := Lex(lines, config.NewMetadata("college"))
+		if err != nil || lexOut.FormatVersion != format.Version2 {
+			t.Errorf("%s: Lex() format = %s (error %v), want 2", name, lexOut.FormatVersion, err)
+		}
+		preOut, err := Preparse(lines, config.NewMetadata("college"))
+		if err != nil {
+			t.Fatalf("%s: Preparse() unexpected error: %v", name, err)
+		}
+		if preOut.Success || preOut.FormatVersion != format.Version2 || !strings.Contains(preOut.Errors[0].Message, "unknown directive @teacher") {
+			t.Errorf("%s: Preparse() = format %s, errors %v; want format 2 and an unknown directive", name, preOut.FormatVersion, preOut.Errors)
+		}
+		parseOut, err := Parse(lines, config.NewMetadata("college"))
+		if err != nil || parseOut.FormatVersion != format.Version2 {
+			t.Errorf("%s: Parse() format = %s (error %v), want 2", name, parseOut.FormatVersion, err)
+		}
+	}
+
+	// Unknown versions are rejected
+	lexOut, err := Lex(guide("Biology Study Guide", "@format: 3"), config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Lex() unexpected error: %v", err)
+	}
+	if lexOut.Success || lexOut.Errors[0].Code != string(lexer.CodeInvalidFormat) || lexOut.Errors[0].LineNumber != 2 {
+		t.Errorf("Lex() errors = %+v, want %s on line 2", lexOut.Errors, lexer.CodeInvalidFormat)
+	}
+	metadata := config.NewMetadata("college")
+	metadata.FormatVersion = "3"
+	if _, err := Lex(guide("Biology Study Guide"), metadata); err == nil {
+		t.Error("Lex() expected an error for an unknown format version in the metadata")
+	}
+}
+
+func TestBuildVariables(t *testing.T) {
+	lines := []string{
+		"---",
+		"format_version: 2",
+		"variables:",
+		"  department: Biology",
+		"---",
+		"{{course}} Study Guide",
+		"@define course = BIO 101",
+		"@define term = Fall",
+		"College: {{department}}: {{course}}: Cells",
+		"1. Which course covers mitosis? - {{course}}",
+		"Passage: Notes for {{course}}",
+		"Cells divide by {{ department }} rules.",
+	}
+
+	result, err := Build(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	if result.Tree.Root.Title != "BIO 101 Study Guide" {
+		t.Errorf("Root.Title = %q", result.Tree.Root.Title)
+	}
+	course := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0]
+	if course.Title != "BIO 101" || result.Tree.Root.ChildTags[0].ChildTags[0].Title != "Biology" {
+		t.Errorf("course tag = %q, want BIO 101 under Biology", course.Title)
+	}
+	cells := course.ChildTags[0]
+	if cells.Questions[0].Answer != "BIO 101" || cells.Passages[0].Title != "Notes for BIO 101" {
+		t.Errorf("question answer = %q, passage title = %q", cells.Questions[0].Answer, cells.Passages[0].Title)
+	}
+	if want := "Cells divide by Biology rules."; cells.Passages[0].Content != want {
+		t.Errorf("passage content = %q, want %q", cells.Passages[0].Content, want)
+	}
+
+	// The unused variable is reported on its line in the original source
+	if len(result.Warnings) != 1 || result.Warnings[0].Code != "UNUSED_VARIABLE" || result.Warnings[0].LineNumber != 8 {
+		t.Errorf("Warnings = %+v, want UNUSED_VARIABLE on line 8", result.Warnings)
+	}
+
+	// Undefined and redefined variables are errors
+	lines = append(lines, "1. When is {{year}}? - Soon", "@define course = BIO 102")
+	preOut, err := Preparse(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Preparse() unexpected error: %v", err)
+	}
+	var got []string
+	for _, e := range preOut.Errors {
+		got = append(got, fmt.Sprintf("%s@%d", e.Code, e.LineNumber))
+	}
+	if want := "[REDEFINED_VARIABLE@14 UNDEFINED_VARIABLE@13]"; fmt.Sprint(got) != want {
+		t.Errorf("Errors = %v, want %s", got, want)
+	}
+
+	// Format 1 guides are not expanded, and "@define" lines are content there
+	result, err = Build([]string{"Biology Study Guide", "@define course = BIO 101", "College: Biology: {{course}}: Cells"}, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success || result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].Title != "{{course}}" {
+		t.Errorf("Build() = %+v, want the format 1 guide built as written", result.Errors)
+	}
+}
+
+func TestVariableRedefinedAfterFrontMatter(t *testing.T) {
+	lines := []string{
+		"---",
+		"format_version: 2",
+		"language: en",
+		"---",
+		"Biology Study Guide",
+		"@define course = BIO 101",
+		"@define course = BIO 102",
+		"College: Biology: {{course}}: Cells",
+	}
+
+	preOut, err := Preparse(lines, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Preparse() unexpected error: %v", err)
+	}
+	// Both lines are given in the original source, past the front matter
+	if len(preOut.Errors) != 1 || preOut.Errors[0].Code != "REDEFINED_VARIABLE" || preOut.Errors[0].LineNumber != 7 ||
+		!strings.Contains(preOut.Errors[0].Message, "already defined on line 6") {
+		t.Errorf("Errors = %+v, want REDEFINED_VARIABLE on line 7 naming line 6", preOut.Errors)
+	}
+}
+
+func TestBuildConditionals(t *testing.T) {
+	lines := []string{
+		"Biology Study Guide",
+		"@format: 2",
+		"College: Biology: BIO 101: Cells",
+		"1. What divides cells? - Mitosis",
+		`@if edition == "teacher"`,
+		"2. What do students confuse with mitosis? - Meiosis",
+		`@if tier == "premium"`,
+		"3. How long does mitosis take? - About an hour",
+		"@end",
+		"@define tip = Draw the phases",
+		"Learn More: {{tip}}",
+		"@end",
+		`@if edition != "teacher"`,
+		"2. What do cells copy before dividing? - DNA",
+		"@end",
+		"4. What holds chromosomes together? - Centromere",
+	}
+
+	prompts := func(result *BuilderOutput) []string {
+		var got []string
+		for _, q := range result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Questions {
+			got = append(got, fmt.Sprintf("%d:%s", q.Order, q.Answer))
+		}
+		return got
+	}
+	tests := []struct {
+		name    string
+		options map[string]string
+		want    string
+	}{
+		{"student", map[string]string{"edition": "student"}, "[1:Mitosis 2:DNA 3:Centromere]"},
+		{"teacher", map[string]string{"edition": "teacher"}, "[1:Mitosis 2:Meiosis 3:Centromere]"},
+		{"premium teacher", map[string]string{"edition": "teacher", "tier": "premium"}, "[1:Mitosis 2:Meiosis 3:About an hour 4:Centromere]"},
+		{"no options", nil, "[1:Mitosis 2:DNA 3:Centromere]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := config.NewMetadata("college")
+			for key, value := range tt.options {
+				metadata.WithOption(key, value)
+			}
+			result, err := Build(lines, metadata)
+			if err != nil {
+				t.Fatalf("Build() unexpected error: %v", err)
+			}
+			if !result.Success {
+				t.Fatalf("Build() failed with errors: %v", result.Errors)
+			}
+			if got := fmt.Sprint(prompts(result)); got != tt.want {
+				t.Errorf("questions = %s, want %s", got, tt.want)
+			}
+			// Numbers of the removed questions leave gaps that are not reported
+			for _, qaResult := range result.Tree.GetQAResults().Results {
+				if qaResult.Name == "Question numbering must be sequential" && !qaResult.Passed {
+					t.Errorf("numbering warnings = %v, want none", qaResult.Warnings)
+				}
+			}
+		})
+	}
+
+	// Gaps at numbers that were never written are still reported
+	gapped := append(append([]string{}, lines...), "6. What splits the cytoplasm? - Cytokinesis")
+	result, err := Build(gapped, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	var numbering []string
+	for _, qaResult := range result.Tree.GetQAResults().Results {
+		if qaResult.Name == "Question numbering must be sequential" {
+			numbering = qaResult.Warnings
+		}
+	}
+	if len(numbering) != 1 || !strings.Contains(numbering[0], "skips from 4 to 6") {
+		t.Errorf("numbering warnings = %v, want only the gap from 4 to 6", numbering)
+	}
+
+	// Unbalanced blocks and malformed conditions are reported on their lines
+	broken := []string{
+		"Biology Study Guide",
+		"@format: 2",
+		"College: Biology: BIO 101: Cells",
+		"@end",
+		"@if edition",
+		`@if edition == "teacher"`,
+		"1. What divides cells? - Mitosis",
+		"@end",
+	}
+	preOut, err := Preparse(broken, config.NewMetadata("college"))
+	if err != nil {
+		t.Fatalf("Preparse() unexpected error: %v", err)
+	}
+	var got []string
+	for _, e := range preOut.Errors {
+		got = append(got, fmt.Sprintf("%s@%d", e.Code, e.LineNumber))
+	}
+	if want := "[UNMATCHED_END@4 INVALID_CONDITION@5 UNCLOSED_BLOCK@5]"; fmt.Sprint(got) != want {
+		t.Errorf("Errors = %v, want %s", got, want)
+	}
+}
+
+func TestBuildTemplates(t *testing.T) {
+	lines := []string{
+		"Maths Study Guide",
+		"@format: 2",
+		"College: Maths: MATH 101: Arithmetic",
+		"1. What is 2 + 2? - 4",
+		"@param a in 1..20",
+		"@param b in 1..20",
+		"2. What is {a} + {b}? - {a+b}",
+		"Learn More: Add the ones first.",
+		"3. What is 10 - 3? - 7",
+	}
+	metadata := config.NewMetadata("college").WithOption("template_instances", "3").WithOption("template_seed", "7")
+
+	result, err := Build(lines, metadata)
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	questions := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Questions
+	if len(questions) != 5 {
+		t.Fatalf("got %d questions, want 5", len(questions))
+	}
+	for i, q := range questions {
+		if q.Order != i+1 {
+			t.Errorf("question %d has order %d", i, q.Order)
+		}
+	}
+	for _, q := range questions[1:4] {
+		a, b := q.TemplateValues["a"], q.TemplateValues["b"]
+		if q.Prompt != fmt.Sprintf("What is %d + %d?", a, b) || q.Answer != fmt.Sprint(a+b) {
+			t.Errorf("instance %v = %q - %q", q.TemplateValues, q.Prompt, q.Answer)
+		}
+		if q.TemplateSource != "What is {a} + {b}? - {a+b}" || len(q.LearnMore) != 1 {
+			t.Errorf("TemplateSource = %q, LearnMore = %v", q.TemplateSource, q.LearnMore)
+		}
+	}
+	// The instances count as one numbered question
+	for _, qaResult := range result.Tree.GetQAResults().Results {
+		if strings.HasPrefix(qaResult.Name, "Question numbering") && len(qaResult.Warnings) > 0 {
+			t.Errorf("QA %q warned: %v", qaResult.Name, qaResult.Warnings)
+		}
+	}
+
+	// The same seed gives the same questions and hashes
+	again, err := Build(lines, metadata)
+	if err != nil || !again.Success {
+		t.Fatalf("Build() = %v, %v", again, err)
+	}
+	for i, q := range again.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Questions {
+		if q.Prompt != questions[i].Prompt || q.Hash != questions[i].Hash {
+			t.Errorf("question %d = %q (%s), want %q (%s)", i, q.Prompt, q.Hash, questions[i].Prompt, questions[i].Hash)
+		}
+	}
+
+	// Invalid template options are rejected
+	if _, err := Build(lines, config.NewMetadata("college").WithOption("template_instances", "none")); err == nil {
+		t.Error("Build() expected an error for an invalid instance count")
+	}
+}
+
+func TestBuildPassageMetadata(t *testing.T) {
+	lines := []string{
+		"English Study Guide",
+		"@format: 2",
+		"College: English: ENG 101: American Literature",
+		"Passage: The River",
+		"@author: Mark Twain",
+		"@source: Life on the Mississippi",
+		"@year: 1883",
+		"@grade: 8",
+		"The face of the water, in time, became a wonderful book.",
+		"It was a book that was a dead language to the uneducated passenger.",
+		"1. Who wrote the passage? - Mark Twain",
+	}
+	metadata := config.NewMetadata("college").WithOption("reading_speed", "120")
+
+	result, err := Build(lines, metadata)
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Build() failed with errors: %v", result.Errors)
+	}
+	tag := result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0]
+	if len(tag.Passages) != 1 {
+		t.Fatalf("got %d passages, want 1", len(tag.Passages))
+	}
+	passage := tag.Passages[0]
+	if passage.Author != "Mark Twain" || passage.Source != "Life on the Mississippi" || passage.Year != 1883 || passage.GradeLevel != 8 {
+		t.Errorf("passage metadata = %q, %q, %d, %d", passage.Author, passage.Source, passage.Year, passage.GradeLevel)
+	}
+	if got := passage.Readability; got == nil || got.Words != 24 || got.Sentences != 2 || got.ReadingTimeSeconds != 12 {
+		t.Errorf("passage Readability = %+v, want 24 words in 2 sentences read in 12 seconds", got)
+	}
+	for _, ancestor := range []*tree.Tag{result.Tree.Root.ChildTags[0], tag} {
+		if ancestor.Readability == nil || *ancestor.Readability != *passage.Readability {
+			t.Errorf("%s Readability = %+v, want that of its only passage", ancestor.Title, ancestor.Readability)
+		}
+	}
+
+	// Passage metadata needs format 2; in format 1 the directives are passage content
+	result, err = Build(append([]string{lines[0]}, lines[2:]...), config.NewMetadata("college"))
+	if err != nil || !result.Success {
+		t.Fatalf("Build() = %v, %v, want the format 1 guide built", result, err)
+	}
+	passage = result.Tree.Root.ChildTags[0].ChildTags[0].ChildTags[0].ChildTags[0].Passages[0]
+	if passage.Author != "" || !strings.HasPrefix(passage.Content, "@author: Mark Twain") {
+		t.Errorf("format 1 passage = %q by %q, want the directives kept as content", passage.Content, passage.Author)
+	}
+
+	// The reading speed must be valid
+	if _, err := Build(lines, config.NewMetadata("college").WithOption("reading_speed", "fast")); err == nil {
+		t.Error("Build() expected an error for an invalid reading speed")
+	}
+}
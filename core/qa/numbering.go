@@ -0,0 +1,196 @@
+package qa
+
+import (
+	"fmt"
+
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+// QuestionNumberingQA validates the author's question numbering within each tag and passage.
+// It flags gaps ("1, 2, 4"), duplicates ("1, 2, 2") and restarts ("1, 2, 1").
+// Unnumbered (bulleted) questions are ignored, and the questions generated from one
+// cloze sentence or template count as a single numbered question.
+type QuestionNumberingQA struct {
+	removed map[int]bool // The numbers of questions that conditional blocks removed, where gaps are expected
+}
+
+func NewQuestionNumberingQA() *QuestionNumberingQA {
+	return &QuestionNumberingQA{}
+}
+
+// WithRemovedNumbers stops the check from reporting gaps made only of the given numbers,
+// for guides whose "@if" blocks removed numbered questions. Other gaps, duplicates and
+// restarts are still reported.
+func (qa *QuestionNumberingQA) WithRemovedNumbers(numbers []int) *QuestionNumberingQA {
+	qa.removed = make(map[int]bool, len(numbers))
+	for _, number := range numbers {
+		qa.removed[number] = true
+	}
+	return qa
+}
+
+func (qa *QuestionNumberingQA) RunQA(t tree.TreeQAble) tree.QAResult {
+	var warnings []string
+
+	visitQuestionGroups(t, func(location string, questions []*tree.Question) {
+		warnings = append(warnings, numberingWarnings(location, questions, qa.removed)...)
+	})
+
+	result := tree.NewQAResult("Question numbering must be sequential", len(warnings) == 0)
+	if len(warnings) > 0 {
+		result.Warnings = warnings
+	}
+	return result
+}
+
+// QuestionOrderQA reports questions whose author-supplied number differs from the
+// Order computed by the builder. Order counts questions across a whole tag, including
+// those inside passages, so numbering that restarts in each passage is reported here.
+// Extra questions generated from a cloze sentence or template are not counted. The
+// builder runs it only with the check_question_order option.
+type QuestionOrderQA struct{}
+
+func NewQuestionOrderQA() *QuestionOrderQA {
+	return &QuestionOrderQA{}
+}
+
+func (qa *QuestionOrderQA) RunQA(t tree.TreeQAble) tree.QAResult {
+	var warnings []string
+	var offsets map[*tree.Question]int
+
+	visitTagQuestionGroups(t, func(tag *tree.Tag) {
+		offsets = generatedOffsets(tag)
+	}, func(location string, questions []*tree.Question) {
+		for i, q := range questions {
+			if q.SourceNumber == 0 || isGeneratedSibling(questions, i) {
+				continue
+			}
+			if q.SourceNumber == q.Order-offsets[q] {
+				continue
+			}
+			warnings = append(warnings,
+				fmt.Sprintf("Question '%s' in %s is numbered %s but has order %d", q.Prompt, location, q.SourceLabel, q.Order))
+		}
+	})
+
+	result := tree.NewQAResult("Question numbering should match order", len(warnings) == 0)
+	if len(warnings) > 0 {
+		result.Warnings = warnings
+	}
+	return result
+}
+
+// visitQuestionGroups calls visitor with the questions of every tag and every passage
+func visitQuestionGroups(t tree.TreeQAble, visitor func(location string, questions []*tree.Question)) {
+	visitTagQuestionGroups(t, func(*tree.Tag) {}, visitor)
+}
+
+// visitTagQuestionGroups calls enter with every tag and then visitor with the questions
+// of the tag and of each of its passages
+func visitTagQuestionGroups(t tree.TreeQAble, enter func(tag *tree.Tag), visitor func(location string, questions []*tree.Question)) {
+	t.Traverse(func(tagQAble tree.TagQATarget, depth int) {
+		tag, ok := tagQAble.(*tree.Tag)
+		if !ok {
+			return
+		}
+		enter(tag)
+		if len(tag.Questions) > 0 {
+			visitor(fmt.Sprintf("tag '%s'", tag.Title), tag.Questions)
+		}
+		for _, passage := range tag.Passages {
+			if len(passage.Questions) > 0 {
+				visitor(fmt.Sprintf("passage '%s' of tag '%s'", passage.Title, tag.Title), passage.Questions)
+			}
+		}
+	})
+}
+
+// numberingWarnings checks a single group of questions for gaps, except those made only of
+// removed numbers, duplicates and restarts
+func numberingWarnings(location string, questions []*tree.Question, removed map[int]bool) []string {
+	var warnings []string
+	seen := map[int]bool{}
+	previous := 0
+
+	for i, q := range questions {
+		number := q.SourceNumber
+		if number == 0 || isGeneratedSibling(questions, i) {
+			continue
+		}
+
+		switch {
+		case number == previous:
+			warnings = append(warnings,
+				fmt.Sprintf("Numbering in %s repeats %s", location, q.SourceLabel))
+		case previous > 0 && number == 1:
+			warnings = append(warnings,
+				fmt.Sprintf("Numbering in %s restarts at %s after %d", location, q.SourceLabel, previous))
+			seen = map[int]bool{}
+		case seen[number]:
+			warnings = append(warnings,
+				fmt.Sprintf("Numbering in %s repeats %s", location, q.SourceLabel))
+		case previous > 0 && number > previous+1 && !onlyRemoved(previous+1, number, removed):
+			warnings = append(warnings,
+				fmt.Sprintf("Numbering in %s skips from %d to %s", location, previous, q.SourceLabel))
+		case previous > 0 && number < previous:
+			warnings = append(warnings,
+				fmt.Sprintf("Numbering in %s goes back from %d to %s", location, previous, q.SourceLabel))
+		}
+
+		seen[number] = true
+		previous = number
+	}
+
+	return warnings
+}
+
+// onlyRemoved reports whether every number from first up to, but not including, end was removed
+func onlyRemoved(first int, end int, removed map[int]bool) bool {
+	for number := first; number < end; number++ {
+		if !removed[number] {
+			return false
+		}
+	}
+	return true
+}
+
+// isGeneratedSibling reports whether questions[i] was generated from the same cloze
+// sentence or template as the question before it
+func isGeneratedSibling(questions []*tree.Question, i int) bool {
+	if i == 0 {
+		return false
+	}
+	q, previous := questions[i], questions[i-1]
+	return (q.ClozeSource != "" && previous.ClozeSource == q.ClozeSource) ||
+		(q.TemplateSource != "" && previous.TemplateSource == q.TemplateSource)
+}
+
+// generatedOffsets returns, for each question of a tag and its passages, how many extra
+// cloze or template questions come before it in the tag's order
+func generatedOffsets(tag *tree.Tag) map[*tree.Question]int {
+	groups := [][]*tree.Question{tag.Questions}
+	for _, passage := range tag.Passages {
+		groups = append(groups, passage.Questions)
+	}
+
+	var extras []int
+	for _, questions := range groups {
+		for i, q := range questions {
+			if isGeneratedSibling(questions, i) {
+				extras = append(extras, q.Order)
+			}
+		}
+	}
+
+	offsets := map[*tree.Question]int{}
+	for _, questions := range groups {
+		for _, q := range questions {
+			for _, order := range extras {
+				if order < q.Order {
+					offsets[q]++
+				}
+			}
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,127 @@
+package qa
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/tree"
+)
+
+func numberedQuestion(prompt string, label string, number int, order int) *tree.Question {
+	q := tree.NewQuestion(prompt, "answer", nil, nil, order)
+	q.SourceLabel = label
+	q.SourceNumber = number
+	return q
+}
+
+func TestQuestionNumberingQA(t *testing.T) {
+	tests := []struct {
+		name         string
+		numbers      []int
+		wantWarnings []string
+	}{
+		{"sequential", []int{1, 2, 3}, nil},
+		{"gap", []int{1, 2, 4}, []string{"skips from 2 to 4"}},
+		{"duplicate", []int{1, 2, 2}, []string{"repeats 2"}},
+		{"duplicate first", []int{1, 1}, []string{"repeats 1"}},
+		{"restart", []int{1, 2, 1, 2}, []string{"restarts at 1 after 2"}},
+		{"backwards", []int{1, 3, 2}, []string{"skips from 1 to 3", "goes back from 3 to 2"}},
+		{"bullets ignored", []int{1, 0, 2}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			treeObj := tree.NewTree(config.NewMetadata("test"))
+			tag := tree.NewTag("Topic")
+			for i, n := range tt.numbers {
+				label := ""
+				if n > 0 {
+					label = strconv.Itoa(n)
+				}
+				tag.Questions = append(tag.Questions, numberedQuestion("Q", label, n, i+1))
+			}
+			treeObj.Root.AddChildTag(tag)
+
+			result := NewQuestionNumberingQA().RunQA(treeObj)
+			if result.Passed != (len(tt.wantWarnings) == 0) {
+				t.Errorf("Passed = %v, warnings: %v", result.Passed, result.Warnings)
+			}
+			if len(result.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("got warnings %v, want %d", result.Warnings, len(tt.wantWarnings))
+			}
+			for i, want := range tt.wantWarnings {
+				if !strings.Contains(result.Warnings[i], want) {
+					t.Errorf("warning %q does not contain %q", result.Warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestQuestionNumberingQAWithRemovedNumbers(t *testing.T) {
+	treeObj := tree.NewTree(config.NewMetadata("test"))
+	tag := tree.NewTag("Topic")
+	tag.Questions = []*tree.Question{
+		numberedQuestion("First", "1", 1, 1),
+		numberedQuestion("Fourth", "4", 4, 2),
+		numberedQuestion("Again", "4", 4, 3),
+		numberedQuestion("Seventh", "7", 7, 4),
+	}
+	treeObj.Root.AddChildTag(tag)
+
+	// 2 and 3 were removed, but 5 and 6 were never written
+	result := NewQuestionNumberingQA().WithRemovedNumbers([]int{2, 3, 6}).RunQA(treeObj)
+	if len(result.Warnings) != 2 || !strings.Contains(result.Warnings[0], "repeats 4") ||
+		!strings.Contains(result.Warnings[1], "skips from 4 to 7") {
+		t.Errorf("Warnings = %v, want the repeated 4 and the gap from 4 to 7", result.Warnings)
+	}
+}
+
+func TestQuestionOrderQA(t *testing.T) {
+	treeObj := tree.NewTree(config.NewMetadata("test"))
+	tag := tree.NewTag("Topic")
+	tag.Questions = []*tree.Question{
+		numberedQuestion("First", "1", 1, 1),
+		numberedQuestion("Second", "2", 2, 2),
+	}
+	tag.Passages = []*tree.Passage{
+		tree.NewPassage("Reading", "", []*tree.Question{
+			numberedQuestion("Third", "1", 1, 3),
+			numberedQuestion("Bulleted", "", 0, 4),
+		}),
+	}
+	treeObj.Root.AddChildTag(tag)
+
+	result := NewQuestionOrderQA().RunQA(treeObj)
+	if result.Passed {
+		t.Fatal("expected order QA to fail for passage numbering that restarts")
+	}
+	if len(result.Warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", result.Warnings)
+	}
+	if want := "Question 'Third' in passage 'Reading' of tag 'Topic' is numbered 1 but has order 3"; result.Warnings[0] != want {
+		t.Errorf("warning = %q, want %q", result.Warnings[0], want)
+	}
+}
+
+func TestNumberingQAWithClozeQuestions(t *testing.T) {
+	treeObj := tree.NewTree(config.NewMetadata("test"))
+	tag := tree.NewTag("Topic")
+
+	// "1." is a cloze sentence with two deletions, so it becomes questions with order 1 and 2
+	first := numberedQuestion("The capital of [...] is Paris", "1", 1, 1)
+	second := numberedQuestion("The capital of France is [...]", "1", 1, 2)
+	first.ClozeSource, first.ClozeIndex = "The capital of {{c1::France}} is {{c2::Paris}}", 1
+	second.ClozeSource, second.ClozeIndex = first.ClozeSource, 2
+	tag.Questions = append(tag.Questions, first, second, numberedQuestion("Q2", "2", 2, 3))
+	treeObj.Root.AddChildTag(tag)
+
+	if result := NewQuestionNumberingQA().RunQA(treeObj); !result.Passed {
+		t.Errorf("QuestionNumberingQA warnings: %v", result.Warnings)
+	}
+	if result := NewQuestionOrderQA().RunQA(treeObj); !result.Passed {
+		t.Errorf("QuestionOrderQA warnings: %v", result.Warnings)
+	}
+}
@@ -37,7 +37,8 @@ func (qa *TagTypeQA) RunQA(t tree.TreeQAble) tree.QAResult {
 	return result
 }
 
-// ContextTypeQA validates that tags have proper context types assigned
+// ContextTypeQA validates that tags have proper context types assigned, and that the tags
+// of a branch with a declared context (see the "@context" directive) have that context
 type ContextTypeQA struct{}
 
 func NewContextTypeQA() *ContextTypeQA {
@@ -47,10 +48,19 @@ func NewContextTypeQA() *ContextTypeQA {
 func (qa *ContextTypeQA) RunQA(t tree.TreeQAble) tree.QAResult {
 	var warnings []string
 
+	// Traverse visits each top-level tag before its descendants
+	var declared ontology.ContextType
 	t.Traverse(func(tagQAble tree.TagQATarget, depth int) {
+		if depth == 1 {
+			declared = tagQAble.GetDeclaredContext()
+		}
 		if tagQAble.GetContext() == ontology.ContextTypeNone {
 			warnings = append(warnings,
 				fmt.Sprintf("Tag '%s' at depth %d has ContextTypeNone", tagQAble.GetTitle(), depth))
+		} else if declared != "" && tagQAble.GetContext() != declared {
+			warnings = append(warnings,
+				fmt.Sprintf("Tag '%s' at depth %d has ContextType %s but its branch declares %s",
+					tagQAble.GetTitle(), depth, tagQAble.GetContext(), declared))
 		}
 	})
 
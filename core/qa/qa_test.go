@@ -142,3 +142,28 @@ func TestQAResultsJSONMarshaling(t *testing.T) {
 		t.Errorf("Found 'warnings':null in JSON, expected empty arrays: %s", jsonStr)
 	}
 }
+
+func TestContextTypeQAChecksDeclaredContext(t *testing.T) {
+	treeObj := tree.NewTree(&config.Metadata{Type: "test", ContextType: ontology.ContextTypeCollege})
+
+	college := tree.NewTag("College")
+	college.AddChildTag(tree.NewTag("Biology"))
+	ap := tree.NewTag("AP Exams")
+	ap.DeclaredContext = ontology.ContextTypeAPExams
+	ap.AddChildTag(tree.NewTag("AP Biology"))
+	treeObj.Root.AddChildTag(college)
+	treeObj.Root.AddChildTag(ap)
+
+	// Assign every tag the guide's context, ignoring the declaration
+	treeObj.TraverseForTagTypes(func(tag tree.TagTypeAssignable, depth int) {
+		tag.SetContext(ontology.ContextTypeCollege)
+	})
+
+	result := NewContextTypeQA().RunQA(treeObj)
+	if result.Passed || len(result.Warnings) != 2 {
+		t.Fatalf("RunQA() = %+v, want warnings for both tags of the AP branch", result)
+	}
+	if !strings.Contains(result.Warnings[0], "'AP Exams'") || !strings.Contains(result.Warnings[0], "declares APExams") {
+		t.Errorf("warning = %q", result.Warnings[0])
+	}
+}
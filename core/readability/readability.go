@@ -0,0 +1,186 @@
+// Package readability measures how hard a text is to read. It counts the words, sentences
+// and syllables of a text and turns the counts into a Flesch-Kincaid grade level and an
+// estimated reading time. Counts add up, so the score of several passages together is
+// computed from their combined counts rather than averaged.
+package readability
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultReadingSpeed is the silent reading speed of adults, in words per minute
+	DefaultReadingSpeed = 238
+	// MinReadingSpeed and MaxReadingSpeed bound the reading speeds ParseReadingSpeed accepts
+	MinReadingSpeed = 50
+	MaxReadingSpeed = 1000
+)
+
+// abbreviations are the words whose final period does not end a sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true, "jr": true, "sr": true,
+	"vs": true, "etc": true, "e.g": true, "i.e": true, "no": true, "vol": true, "fig": true, "ca": true,
+}
+
+// Counts are the words, sentences and syllables of a text
+type Counts struct {
+	Words     int `json:"words"`
+	Sentences int `json:"sentences"`
+	Syllables int `json:"syllables"`
+}
+
+// Stats are the counts of a text with the scores computed from them
+type Stats struct {
+	Counts
+	// FleschKincaidGrade is the U.S. school grade needed to understand the text, rounded to
+	// one decimal and never below 0
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+	// ReadingTimeSeconds is how long the text takes to read, rounded up to a whole second
+	ReadingTimeSeconds int `json:"reading_time_seconds"`
+}
+
+// Count counts the words, sentences and syllables of text. A sentence ends with ".", "!",
+// "?" or "…", except after an abbreviation or an initial, and at the end of the text.
+func Count(text string) Counts {
+	var counts Counts
+	open := false // Whether words were counted since the last sentence ended
+	for _, token := range strings.FieldsFunc(text, isWordBreak) {
+		word := strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if word == "" {
+			continue
+		}
+		counts.Words++
+		counts.Syllables += syllables(word)
+		open = true
+		if endsSentence(token, word) {
+			counts.Sentences++
+			open = false
+		}
+	}
+	if open {
+		counts.Sentences++
+	}
+	return counts
+}
+
+// Add returns the combined counts of two texts
+func (c Counts) Add(other Counts) Counts {
+	return Counts{
+		Words:     c.Words + other.Words,
+		Sentences: c.Sentences + other.Sentences,
+		Syllables: c.Syllables + other.Syllables,
+	}
+}
+
+// Stats scores the counts, estimating the reading time at wordsPerMinute. It returns nil
+// for a text without words.
+func (c Counts) Stats(wordsPerMinute int) *Stats {
+	if c.Words == 0 || c.Sentences == 0 {
+		return nil
+	}
+	if wordsPerMinute <= 0 {
+		wordsPerMinute = DefaultReadingSpeed
+	}
+	grade := 0.39*float64(c.Words)/float64(c.Sentences) + 11.8*float64(c.Syllables)/float64(c.Words) - 15.59
+	return &Stats{
+		Counts:             c,
+		FleschKincaidGrade: math.Max(0, math.Round(grade*10)/10),
+		ReadingTimeSeconds: int(math.Ceil(float64(c.Words) * 60 / float64(wordsPerMinute))),
+	}
+}
+
+// ParseReadingSpeed reads a reading speed in words per minute. An empty value is DefaultReadingSpeed.
+func ParseReadingSpeed(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultReadingSpeed, nil
+	}
+	speed, err := strconv.Atoi(value)
+	if err != nil || speed < MinReadingSpeed || speed > MaxReadingSpeed {
+		return 0, fmt.Errorf("invalid reading speed %q, expected words per minute from %d to %d", value, MinReadingSpeed, MaxReadingSpeed)
+	}
+	return speed, nil
+}
+
+// isWordBreak separates words: spaces and dashes, but not hyphens, so "well-known" is one word
+func isWordBreak(r rune) bool {
+	return unicode.IsSpace(r) || r == '—' || r == '–'
+}
+
+// endsSentence reports whether token, whose letters and digits are word, ends a sentence
+func endsSentence(token string, word string) bool {
+	trimmed := strings.TrimRight(token, "\"'”’)]")
+	switch {
+	case strings.HasSuffix(trimmed, "!"), strings.HasSuffix(trimmed, "?"), strings.HasSuffix(trimmed, "…"):
+		return true
+	case strings.HasSuffix(trimmed, "."):
+		lower := strings.ToLower(word)
+		isInitial := len([]rune(word)) == 1 && unicode.IsUpper([]rune(word)[0])
+		return !abbreviations[lower] && !isInitial
+	}
+	return false
+}
+
+// syllables estimates the syllables of a word from its groups of vowels, not counting a
+// silent final "e" or the "e" of a final "-es" or "-ed" that is not pronounced. A number has a
+// syllable per digit.
+func syllables(word string) int {
+	word = strings.ToLower(word)
+	letters := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, word)
+	if letters == "" {
+		digits := 0
+		for _, r := range word {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits == 0 {
+			return 1
+		}
+		return digits
+	}
+	if len(letters) <= 3 {
+		return 1
+	}
+	for _, suffix := range []string{"es", "ed", "e"} {
+		if strings.HasSuffix(letters, suffix) && !strings.HasSuffix(letters, "le") {
+			stem := letters[:len(letters)-len(suffix)]
+			last := stem[len(stem)-1]
+			voiced := (suffix == "ed" && strings.ContainsRune("td", rune(last))) ||
+				(suffix == "es" && strings.ContainsRune("sxzcgh", rune(last)))
+			if !isVowel(rune(last)) && !voiced {
+				letters = stem
+			}
+			break
+		}
+	}
+	count := 0
+	previousVowel := false
+	for i, r := range letters {
+		vowel := isVowel(r) || (r == 'y' && i > 0)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// isVowel reports whether r is a vowel other than "y"
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
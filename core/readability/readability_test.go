@@ -0,0 +1,82 @@
+package readability
+
+import (
+	"testing"
+)
+
+func TestSyllables(t *testing.T) {
+	tests := map[string]int{
+		"cat":         1,
+		"the":         1,
+		"make":        1,
+		"table":       2,
+		"jumped":      1,
+		"wanted":      2,
+		"houses":      2,
+		"agreed":      2,
+		"river":       2,
+		"beautiful":   3,
+		"mississippi": 4,
+		"everything":  4,
+		"rhythm":      1,
+		"1776":        4,
+	}
+	for word, want := range tests {
+		if got := syllables(word); got != want {
+			t.Errorf("syllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want Counts
+	}{
+		{"", Counts{}},
+		{"The cat sat on the mat.", Counts{Words: 6, Sentences: 1, Syllables: 6}},
+		{"Run! Where to? Home", Counts{Words: 4, Sentences: 3, Syllables: 4}},
+		{"Mr. Smith met J. Doe at St. Paul's.", Counts{Words: 8, Sentences: 1, Syllables: 8}},
+		{"\"Stop.\" He did — quickly.", Counts{Words: 4, Sentences: 2, Syllables: 5}},
+		{"A well-known fact", Counts{Words: 3, Sentences: 1, Syllables: 4}},
+	}
+	for _, tt := range tests {
+		if got := Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	if stats := (Counts{}).Stats(DefaultReadingSpeed); stats != nil {
+		t.Errorf("Stats of no words = %+v, want nil", stats)
+	}
+
+	// 0.39*10 + 11.8*1.5 - 15.59 = 6.01
+	stats := Counts{Words: 100, Sentences: 10, Syllables: 150}.Stats(200)
+	if stats.FleschKincaidGrade != 6 || stats.ReadingTimeSeconds != 30 {
+		t.Errorf("Stats = %+v, want grade 6 and 30 seconds", stats)
+	}
+	if stats := (Counts{Words: 5, Sentences: 5, Syllables: 5}).Stats(DefaultReadingSpeed); stats.FleschKincaidGrade != 0 {
+		t.Errorf("FleschKincaidGrade = %v, want 0 for very simple text", stats.FleschKincaidGrade)
+	}
+
+	combined := Count("The cat sat.").Add(Count("It was happy."))
+	if combined != (Counts{Words: 6, Sentences: 2, Syllables: 7}) {
+		t.Errorf("Add = %+v", combined)
+	}
+}
+
+func TestParseReadingSpeed(t *testing.T) {
+	if speed, err := ParseReadingSpeed(""); err != nil || speed != DefaultReadingSpeed {
+		t.Errorf("ParseReadingSpeed(\"\") = %d, %v", speed, err)
+	}
+	if speed, err := ParseReadingSpeed(" 150 "); err != nil || speed != 150 {
+		t.Errorf("ParseReadingSpeed(150) = %d, %v", speed, err)
+	}
+	for _, value := range []string{"fast", "0", "10", "5000"} {
+		if _, err := ParseReadingSpeed(value); err == nil {
+			t.Errorf("ParseReadingSpeed(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package reference
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeInvalidURL       ErrorCode = "INVALID_URL"
+	CodeDisallowedScheme ErrorCode = "DISALLOWED_URL_SCHEME"
+	CodeInvalidISBN      ErrorCode = "INVALID_ISBN"
+)
+
+// ReferenceError reports a reference that failed validation. The caller knows the line.
+type ReferenceError struct {
+	Message   string
+	Code      ErrorCode
+	Reference Reference
+}
+
+// Error implements the error interface
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s (reference: %s)", e.Message, e.Reference.Text)
+}
+
+// NewReferenceError creates a new reference error with the given code and message
+func NewReferenceError(code ErrorCode, message string, reference Reference) *ReferenceError {
+	return &ReferenceError{
+		Message:   message,
+		Code:      code,
+		Reference: reference,
+	}
+}
@@ -0,0 +1,255 @@
+// Package reference extracts citations from guide text. It recognises URLs, DOIs,
+// ISBNs (after an "ISBN" label) and "Source: …" citations, normalises them so that
+// the same work written two ways is recognised as one, and validates them offline.
+package reference
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+// Kind identifies what a reference points to
+type Kind string
+
+const (
+	KindURL    Kind = "url"
+	KindDOI    Kind = "doi"
+	KindISBN   Kind = "isbn"
+	KindSource Kind = "source"
+)
+
+// Reference is a citation found in a line of text
+type Reference struct {
+	Kind  Kind   `json:"kind"`
+	Text  string `json:"text"`          // As written (e.g. "doi:10.1000/182")
+	Value string `json:"value"`         // Normalised: lowercase URL host, bare DOI, ISBN-13 digits or cleaned citation
+	URL   string `json:"url,omitempty"` // A link to the reference, for URLs and DOIs
+}
+
+// Key identifies the work a reference points to. References with the same key are duplicates.
+func (r Reference) Key() string {
+	value := r.Value
+	if r.Kind == KindSource {
+		value = cleanstring.New(value).Comparable()
+	}
+	return string(r.Kind) + ":" + value
+}
+
+// doiHosts are URL hosts whose links are read as DOIs
+var doiHosts = map[string]bool{"doi.org": true, "dx.doi.org": true}
+
+// Extract returns the references in text in the order they appear. A "Source:" citation
+// is returned as a whole, and any URL, DOI or ISBN inside it is also returned.
+func Extract(text string) []Reference {
+	var found []foundReference
+	taken := make([]bool, len(text))
+
+	for _, span := range regexes.URLRegex.FindAllStringIndex(text, -1) {
+		raw := trimTrailing(text[span[0]:span[1]])
+		markTaken(taken, span[0], span[0]+len(raw))
+		found = append(found, foundReference{span[0], newURLReference(raw)})
+	}
+
+	for _, match := range regexes.DOIRegex.FindAllStringSubmatchIndex(text, -1) {
+		if taken[match[0]] {
+			continue
+		}
+		raw := trimTrailing(text[match[0]:match[1]])
+		doi := trimTrailing(text[match[2]:match[3]])
+		found = append(found, foundReference{match[0], newDOIReference(raw, doi)})
+	}
+
+	for _, match := range regexes.ISBNRegex.FindAllStringSubmatchIndex(text, -1) {
+		if taken[match[0]] {
+			continue
+		}
+		found = append(found, foundReference{match[0], Reference{
+			Kind:  KindISBN,
+			Text:  text[match[0]:match[1]],
+			Value: normalizeISBN(text[match[2]:match[3]]),
+		}})
+	}
+
+	if match := regexes.SourceRegex.FindStringSubmatchIndex(text); match != nil {
+		found = append(found, foundReference{match[0], Reference{
+			Kind:  KindSource,
+			Text:  text[match[0]:match[1]],
+			Value: cleanstring.New(text[match[2]:match[3]]).Clean(),
+		}})
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].start < found[j].start })
+	var references []Reference
+	for _, f := range found {
+		references = append(references, f.reference)
+	}
+	return references
+}
+
+// foundReference is a reference and the byte offset where it starts in the text
+type foundReference struct {
+	start     int
+	reference Reference
+}
+
+// Validate checks a reference without network access: URLs must parse, have a host
+// and use one of the allowed schemes, and ISBNs must have a correct check digit
+func Validate(r Reference, allowedSchemes []string) *ReferenceError {
+	switch r.Kind {
+	case KindURL:
+		parsed, err := url.Parse(r.Text)
+		if err != nil || parsed.Host == "" {
+			return NewReferenceError(CodeInvalidURL, fmt.Sprintf("%q is not a valid URL", r.Text), r)
+		}
+		scheme := strings.ToLower(parsed.Scheme)
+		for _, allowed := range allowedSchemes {
+			if scheme == allowed {
+				return nil
+			}
+		}
+		return NewReferenceError(CodeDisallowedScheme,
+			fmt.Sprintf("URL scheme %q is not allowed; use one of %s", scheme, strings.Join(allowedSchemes, ", ")), r)
+
+	case KindISBN:
+		if !validISBN(r.Value) {
+			return NewReferenceError(CodeInvalidISBN, fmt.Sprintf("%q is not a valid ISBN", r.Text), r)
+		}
+	}
+	return nil
+}
+
+// ParseSchemes reads a comma-separated list of URL schemes, such as the value of the
+// allowed_url_schemes option. An empty value gives the default schemes.
+func ParseSchemes(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		value = constants.DefaultAllowedURLSchemes
+	}
+	var schemes []string
+	for _, scheme := range strings.Split(value, ",") {
+		if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
+
+// newURLReference creates a URL reference, or a DOI reference for doi.org links
+func newURLReference(raw string) Reference {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return Reference{Kind: KindURL, Text: raw, Value: raw}
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if doiHosts[host] && regexes.DOIRegex.MatchString(strings.TrimPrefix(parsed.Path, "/")) {
+		return newDOIReference(raw, strings.TrimPrefix(parsed.Path, "/"))
+	}
+	normalized := normalizeURL(parsed)
+	return Reference{Kind: KindURL, Text: raw, Value: normalized, URL: normalized}
+}
+
+// newDOIReference creates a DOI reference. DOIs are case-insensitive, so the value is lowercased.
+func newDOIReference(raw string, doi string) Reference {
+	return Reference{
+		Kind:  KindDOI,
+		Text:  raw,
+		Value: strings.ToLower(doi),
+		URL:   "https://doi.org/" + doi,
+	}
+}
+
+// normalizeURL lowercases the scheme and host and drops default ports, fragments
+// and a trailing slash, so that links to the same page compare equal
+func normalizeURL(parsed *url.URL) string {
+	normalized := *parsed
+	normalized.Scheme = strings.ToLower(parsed.Scheme)
+	normalized.Host = strings.ToLower(parsed.Host)
+	if port := parsed.Port(); (port == "80" && normalized.Scheme == "http") || (port == "443" && normalized.Scheme == "https") {
+		normalized.Host = strings.ToLower(parsed.Hostname())
+	}
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.Path = strings.TrimSuffix(parsed.Path, "/")
+	normalized.RawPath = strings.TrimSuffix(parsed.RawPath, "/")
+	return normalized.String()
+}
+
+// normalizeISBN removes hyphens and converts a valid ISBN-10 to ISBN-13
+func normalizeISBN(isbn string) string {
+	digits := strings.ToUpper(strings.ReplaceAll(isbn, "-", ""))
+	if len(digits) == 10 && validISBN(digits) {
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13))
+	}
+	return digits
+}
+
+// validISBN checks the length and check digit of an ISBN-10 or ISBN-13 without hyphens
+func validISBN(isbn string) bool {
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, r := range isbn {
+			value := int(r - '0')
+			if r == 'X' && i == 9 {
+				value = 10
+			} else if r < '0' || r > '9' {
+				return false
+			}
+			sum += value * (10 - i)
+		}
+		return sum%11 == 0
+	case 13:
+		for _, r := range isbn {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+		return isbn13CheckDigit(isbn[:12]) == rune(isbn[12])
+	}
+	return false
+}
+
+// isbn13CheckDigit computes the check digit for the first 12 digits of an ISBN-13
+func isbn13CheckDigit(digits string) rune {
+	sum := 0
+	for i, r := range digits[:12] {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(r-'0') * weight
+	}
+	return rune('0' + (10-sum%10)%10)
+}
+
+// trimTrailing removes punctuation that ends the sentence rather than the link,
+// and a closing bracket that has no opening bracket in the link
+func trimTrailing(raw string) string {
+	for raw != "" {
+		last := raw[len(raw)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"*", last) >= 0:
+			raw = raw[:len(raw)-1]
+		case last == ')' && strings.Count(raw, "(") < strings.Count(raw, ")"):
+			raw = raw[:len(raw)-1]
+		case last == ']' && strings.Count(raw, "[") < strings.Count(raw, "]"):
+			raw = raw[:len(raw)-1]
+		default:
+			return raw
+		}
+	}
+	return raw
+}
+
+// markTaken records that the bytes from start to end belong to a reference already found
+func markTaken(taken []bool, start int, end int) {
+	for i := start; i < end; i++ {
+		taken[i] = true
+	}
+}
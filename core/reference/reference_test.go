@@ -0,0 +1,137 @@
+package reference
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Reference
+	}{
+		{
+			name: "no references",
+			text: "The mitochondria is the powerhouse of the cell",
+			want: nil,
+		},
+		{
+			name: "URL with trailing punctuation",
+			text: "See https://Example.com/Cells/ (or the textbook).",
+			want: []Reference{
+				{Kind: KindURL, Text: "https://Example.com/Cells/", Value: "https://example.com/Cells", URL: "https://example.com/Cells"},
+			},
+		},
+		{
+			name: "URL in parentheses keeps its own brackets",
+			text: "(https://en.wikipedia.org/wiki/Cell_(biology))",
+			want: []Reference{
+				{Kind: KindURL, Text: "https://en.wikipedia.org/wiki/Cell_(biology)", Value: "https://en.wikipedia.org/wiki/Cell_(biology)", URL: "https://en.wikipedia.org/wiki/Cell_(biology)"},
+			},
+		},
+		{
+			name: "DOI with prefix",
+			text: "Watson & Crick, doi:10.1038/171737A0.",
+			want: []Reference{
+				{Kind: KindDOI, Text: "doi:10.1038/171737A0", Value: "10.1038/171737a0", URL: "https://doi.org/10.1038/171737A0"},
+			},
+		},
+		{
+			name: "doi.org link is a DOI",
+			text: "https://doi.org/10.1038/171737a0",
+			want: []Reference{
+				{Kind: KindDOI, Text: "https://doi.org/10.1038/171737a0", Value: "10.1038/171737a0", URL: "https://doi.org/10.1038/171737a0"},
+			},
+		},
+		{
+			name: "ISBN-10 is normalised to ISBN-13",
+			text: "Read ISBN 0-306-40615-2 first",
+			want: []Reference{
+				{Kind: KindISBN, Text: "ISBN 0-306-40615-2", Value: "9780306406157"},
+			},
+		},
+		{
+			name: "unlabelled numbers are not ISBNs",
+			text: "Call 0306406152",
+			want: nil,
+		},
+		{
+			name: "source citation with a link",
+			text: "Source: NASA Earth Observatory, https://earthobservatory.nasa.gov",
+			want: []Reference{
+				{Kind: KindSource, Text: "Source: NASA Earth Observatory, https://earthobservatory.nasa.gov", Value: "NASA Earth Observatory, https://earthobservatory.nasa.gov"},
+				{Kind: KindURL, Text: "https://earthobservatory.nasa.gov", Value: "https://earthobservatory.nasa.gov", URL: "https://earthobservatory.nasa.gov"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyMergesDuplicates(t *testing.T) {
+	pairs := [][2]string{
+		{"https://example.com/cells", "HTTPS://EXAMPLE.COM:443/cells/#top"},
+		{"doi:10.1038/171737a0", "https://dx.doi.org/10.1038/171737A0"},
+		{"ISBN 0-306-40615-2", "ISBN-13: 978-0-306-40615-7"},
+		{"Source: The Cell, 4th edition", "source: the cell 4th edition"},
+	}
+	for _, pair := range pairs {
+		a, b := Extract(pair[0]), Extract(pair[1])
+		if len(a) != 1 || len(b) != 1 {
+			t.Fatalf("Extract(%q) = %+v and Extract(%q) = %+v, want one reference each", pair[0], a, pair[1], b)
+		}
+		if a[0].Key() != b[0].Key() {
+			t.Errorf("Key() = %q and %q, want them equal", a[0].Key(), b[0].Key())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	allowed := ParseSchemes("")
+	tests := []struct {
+		name     string
+		text     string
+		wantCode ErrorCode
+	}{
+		{"https URL", "https://example.com", ""},
+		{"disallowed scheme", "ftp://files.example.com/notes.pdf", CodeDisallowedScheme},
+		{"URL without host", "https:///path", CodeInvalidURL},
+		{"valid ISBN-13", "ISBN 978-0-306-40615-7", ""},
+		{"ISBN with a wrong check digit", "ISBN 978-0-306-40615-8", CodeInvalidISBN},
+		{"ISBN-10 with X check digit", "ISBN 0-8044-2957-X", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			references := Extract(tt.text)
+			if len(references) != 1 {
+				t.Fatalf("Extract(%q) = %+v, want one reference", tt.text, references)
+			}
+			err := Validate(references[0], allowed)
+			var gotCode ErrorCode
+			if err != nil {
+				gotCode = err.Code
+			}
+			if gotCode != tt.wantCode {
+				t.Errorf("Validate(%q) code = %q, want %q", tt.text, gotCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestParseSchemes(t *testing.T) {
+	if got := ParseSchemes(""); !reflect.DeepEqual(got, []string{"http", "https"}) {
+		t.Errorf("ParseSchemes(\"\") = %q, want the defaults", got)
+	}
+	if got := ParseSchemes(" HTTPS, ftp ,"); !reflect.DeepEqual(got, []string{"https", "ftp"}) {
+		t.Errorf("ParseSchemes() = %q, want [https ftp]", got)
+	}
+}
@@ -0,0 +1,212 @@
+package regexes
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NumberingStyle describes how the label captured by a list item prefix is numbered
+type NumberingStyle string
+
+const (
+	// NumberingNone is used by bullet prefixes that carry no number
+	NumberingNone NumberingStyle = "none"
+	// NumberingDecimal is used by prefixes such as "1.", "1)" and "Q1:"
+	NumberingDecimal NumberingStyle = "decimal"
+	// NumberingAlpha is used by prefixes such as "a.", "b)" and "(c)"
+	NumberingAlpha NumberingStyle = "alpha"
+	// NumberingRoman is used by prefixes such as "(iv)"
+	NumberingRoman NumberingStyle = "roman"
+)
+
+// ListItemPrefix is a named pattern that recognises the prefix of a list item.
+// When the pattern carries a number, its first capture group holds the label
+// exactly as the author wrote it (e.g. "12", "b", "iv").
+type ListItemPrefix struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Style   NumberingStyle
+}
+
+// ListItemMatch describes a list item prefix found at the start of a line
+type ListItemMatch struct {
+	Prefix ListItemPrefix
+	Raw    string // The prefix text as written, without trailing whitespace (e.g. "1)")
+	Label  string // The author's label (e.g. "1", "b", "iv"), empty for bullets
+	Number int    // The label converted to an ordinal, 0 for bullets
+	Rest   string // The remainder of the line after the prefix
+}
+
+// Named list item prefixes. The roman pattern only accepts i, v and x so that
+// "(c)" and "(d)" are read as letters rather than as 100 and 500.
+var (
+	NumberDotPrefix    = ListItemPrefix{"number_dot", regexp.MustCompile(`^(\d+)\.\s+`), NumberingDecimal}
+	NumberParenPrefix  = ListItemPrefix{"number_paren", regexp.MustCompile(`^(\d+)\)\s+`), NumberingDecimal}
+	LetterDotPrefix    = ListItemPrefix{"letter_dot", regexp.MustCompile(`^([a-zA-Z])\.\s+`), NumberingAlpha}
+	LetterParenPrefix  = ListItemPrefix{"letter_paren", regexp.MustCompile(`^([a-zA-Z])\)\s+`), NumberingAlpha}
+	RomanWrappedPrefix = ListItemPrefix{"roman_wrapped", regexp.MustCompile(`^\(([ivxIVX]+)\)\s+`), NumberingRoman}
+	AlphaWrappedPrefix = ListItemPrefix{"letter_wrapped", regexp.MustCompile(`^\(([a-zA-Z])\)\s+`), NumberingAlpha}
+	QNumberPrefix      = ListItemPrefix{"q_number", regexp.MustCompile(`^[Qq](\d+)[:.]\s+`), NumberingDecimal}
+	StarBulletPrefix   = ListItemPrefix{"bullet_star", regexp.MustCompile(`^\*\s+`), NumberingNone}
+	DashBulletPrefix   = ListItemPrefix{"bullet_dash", regexp.MustCompile(`^\-\s+`), NumberingNone}
+)
+
+// DefaultListItemPrefixes matches the same prefixes as ListItemPrefixRegex: "1.", "*" and "-"
+var DefaultListItemPrefixes = []ListItemPrefix{
+	NumberDotPrefix,
+	StarBulletPrefix,
+	DashBulletPrefix,
+}
+
+// ExtendedListItemPrefixes adds "1)", "a.", "a)", "(iv)", "(a)" and "Q1:" to the defaults.
+// Order matters: the roman pattern is tried before the wrapped letter pattern, so "(i)",
+// "(v)" and "(x)" are roman numerals unless MatchListItemPrefixAfter finds them in a
+// lettered list.
+var ExtendedListItemPrefixes = []ListItemPrefix{
+	NumberDotPrefix,
+	NumberParenPrefix,
+	QNumberPrefix,
+	LetterDotPrefix,
+	LetterParenPrefix,
+	RomanWrappedPrefix,
+	AlphaWrappedPrefix,
+	StarBulletPrefix,
+	DashBulletPrefix,
+}
+
+// Named prefix sets accepted by ListItemPrefixesFromOption
+const (
+	ListItemPrefixSetDefault  = "default"
+	ListItemPrefixSetExtended = "extended"
+)
+
+// ListItemPrefixesFromOption resolves a configuration value to a list of prefixes.
+// The value is either a named set ("default" or "extended") or a comma-separated
+// list of prefix names (e.g. "number_dot,number_paren,bullet_dash").
+// An empty value resolves to DefaultListItemPrefixes.
+func ListItemPrefixesFromOption(value string) ([]ListItemPrefix, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "", ListItemPrefixSetDefault:
+		return DefaultListItemPrefixes, nil
+	case ListItemPrefixSetExtended:
+		return ExtendedListItemPrefixes, nil
+	}
+
+	var prefixes []ListItemPrefix
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix, ok := listItemPrefixByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown list item prefix %q", name)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("no list item prefixes in %q", value)
+	}
+	return prefixes, nil
+}
+
+func listItemPrefixByName(name string) (ListItemPrefix, bool) {
+	for _, prefix := range ExtendedListItemPrefixes {
+		if strings.EqualFold(prefix.Name, name) {
+			return prefix, true
+		}
+	}
+	return ListItemPrefix{}, false
+}
+
+// MatchListItemPrefix returns the first prefix in prefixes that matches the start of line
+func MatchListItemPrefix(line string, prefixes []ListItemPrefix) (ListItemMatch, bool) {
+	for _, prefix := range prefixes {
+		loc := prefix.Pattern.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		match := ListItemMatch{
+			Prefix: prefix,
+			Raw:    strings.TrimSpace(line[:loc[1]]),
+			Rest:   line[loc[1]:],
+		}
+		if len(loc) >= 4 && loc[2] >= 0 {
+			match.Label = line[loc[2]:loc[3]]
+			match.Number = prefix.Ordinal(match.Label)
+		}
+		return match, true
+	}
+	return ListItemMatch{}, false
+}
+
+// MatchListItemPrefixAfter is MatchListItemPrefix for a line that continues a list whose
+// previous item was numbered in the given style. A wrapped label that is both a roman
+// numeral and a letter, such as "(i)", "(v)" or "(x)", is read as a letter when the
+// previous item was lettered, and as a roman numeral otherwise.
+func MatchListItemPrefixAfter(line string, prefixes []ListItemPrefix, previous NumberingStyle) (ListItemMatch, bool) {
+	match, ok := MatchListItemPrefix(line, prefixes)
+	if !ok || previous != NumberingAlpha || match.Prefix.Style != NumberingRoman || len(match.Label) != 1 {
+		return match, ok
+	}
+	var letters []ListItemPrefix
+	for _, prefix := range prefixes {
+		if prefix.Style == NumberingAlpha {
+			letters = append(letters, prefix)
+		}
+	}
+	if letter, ok := MatchListItemPrefix(line, letters); ok {
+		return letter, true
+	}
+	return match, true
+}
+
+// HasListItemPrefix reports whether line starts with any of the given prefixes
+func HasListItemPrefix(line string, prefixes []ListItemPrefix) bool {
+	_, ok := MatchListItemPrefix(line, prefixes)
+	return ok
+}
+
+// Ordinal converts a label captured by this prefix into its position in a list.
+// It returns 0 when the prefix is unnumbered or the label cannot be converted.
+func (p ListItemPrefix) Ordinal(label string) int {
+	switch p.Style {
+	case NumberingDecimal:
+		n, err := strconv.Atoi(label)
+		if err != nil {
+			return 0
+		}
+		return n
+	case NumberingAlpha:
+		if len(label) != 1 {
+			return 0
+		}
+		return int(strings.ToLower(label)[0]-'a') + 1
+	case NumberingRoman:
+		return romanToInt(label)
+	default:
+		return 0
+	}
+}
+
+// romanToInt converts a roman numeral made of i, v and x to an integer
+func romanToInt(numeral string) int {
+	values := map[rune]int{'i': 1, 'v': 5, 'x': 10}
+	runes := []rune(strings.ToLower(numeral))
+	total := 0
+	for i, r := range runes {
+		value, ok := values[r]
+		if !ok {
+			return 0
+		}
+		if i+1 < len(runes) && values[runes[i+1]] > value {
+			total -= value
+		} else {
+			total += value
+		}
+	}
+	return total
+}
@@ -0,0 +1,142 @@
+package regexes
+
+import (
+	"testing"
+)
+
+func TestMatchListItemPrefix_Extended(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantName   string
+		wantRaw    string
+		wantLabel  string
+		wantNumber int
+		wantRest   string
+	}{
+		{"1. Question text", "number_dot", "1.", "1", 1, "Question text"},
+		{"12) Question text", "number_paren", "12)", "12", 12, "Question text"},
+		{"b. Question text", "letter_dot", "b.", "b", 2, "Question text"},
+		{"C) Question text", "letter_paren", "C)", "C", 3, "Question text"},
+		{"(iv) Question text", "roman_wrapped", "(iv)", "iv", 4, "Question text"},
+		{"(ix) Question text", "roman_wrapped", "(ix)", "ix", 9, "Question text"},
+		{"(c) Question text", "letter_wrapped", "(c)", "c", 3, "Question text"},
+		{"Q7: Question text", "q_number", "Q7:", "7", 7, "Question text"},
+		{"* Bullet point", "bullet_star", "*", "", 0, "Bullet point"},
+		{"- Dash bullet", "bullet_dash", "-", "", 0, "Dash bullet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := MatchListItemPrefix(tt.input, ExtendedListItemPrefixes)
+			if !ok {
+				t.Fatalf("MatchListItemPrefix(%q) did not match", tt.input)
+			}
+			if got.Prefix.Name != tt.wantName {
+				t.Errorf("prefix name = %q, want %q", got.Prefix.Name, tt.wantName)
+			}
+			if got.Raw != tt.wantRaw {
+				t.Errorf("raw = %q, want %q", got.Raw, tt.wantRaw)
+			}
+			if got.Label != tt.wantLabel {
+				t.Errorf("label = %q, want %q", got.Label, tt.wantLabel)
+			}
+			if got.Number != tt.wantNumber {
+				t.Errorf("number = %d, want %d", got.Number, tt.wantNumber)
+			}
+			if got.Rest != tt.wantRest {
+				t.Errorf("rest = %q, want %q", got.Rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestMatchListItemPrefixAfter(t *testing.T) {
+	tests := []struct {
+		input      string
+		previous   NumberingStyle
+		wantName   string
+		wantNumber int
+	}{
+		{"(i) Question text", "", "roman_wrapped", 1},
+		{"(v) Question text", NumberingRoman, "roman_wrapped", 5},
+		{"(i) Question text", NumberingAlpha, "letter_wrapped", 9},
+		{"(v) Question text", NumberingAlpha, "letter_wrapped", 22},
+		{"(x) Question text", NumberingAlpha, "letter_wrapped", 24},
+		{"(ii) Question text", NumberingAlpha, "roman_wrapped", 2},
+		{"(i) Question text", NumberingDecimal, "roman_wrapped", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+" after "+string(tt.previous), func(t *testing.T) {
+			got, ok := MatchListItemPrefixAfter(tt.input, ExtendedListItemPrefixes, tt.previous)
+			if !ok {
+				t.Fatalf("MatchListItemPrefixAfter(%q) did not match", tt.input)
+			}
+			if got.Prefix.Name != tt.wantName || got.Number != tt.wantNumber {
+				t.Errorf("got %q numbered %d, want %q numbered %d", got.Prefix.Name, got.Number, tt.wantName, tt.wantNumber)
+			}
+		})
+	}
+
+	// Without a letter prefix to fall back on, the roman reading is kept
+	if got, ok := MatchListItemPrefixAfter("(i) Question text", []ListItemPrefix{RomanWrappedPrefix}, NumberingAlpha); !ok || got.Number != 1 {
+		t.Errorf("got %+v, want the roman numeral 1", got)
+	}
+}
+
+func TestMatchListItemPrefix_DefaultsMatchLegacyRegex(t *testing.T) {
+	inputs := []string{
+		"1. Question text",
+		"* Bullet point",
+		"- Dash bullet",
+		"1.2. Not a match",
+		"*Not a match (no space)",
+		"2) Not a match (wrong symbol)",
+		"a. Not a match by default",
+		"Q1: Not a match by default",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			if got, want := HasListItemPrefix(input, DefaultListItemPrefixes), ListItemPrefixRegex.MatchString(input); got != want {
+				t.Errorf("HasListItemPrefix(%q) = %v, legacy regex = %v", input, got, want)
+			}
+		})
+	}
+}
+
+func TestListItemPrefixesFromOption(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantNames []string
+		wantErr   bool
+	}{
+		{"", []string{"number_dot", "bullet_star", "bullet_dash"}, false},
+		{"default", []string{"number_dot", "bullet_star", "bullet_dash"}, false},
+		{"number_paren, q_number", []string{"number_paren", "q_number"}, false},
+		{"number_paren,unknown", nil, true},
+		{" , ", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ListItemPrefixesFromOption(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListItemPrefixesFromOption(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantNames) {
+				t.Fatalf("got %d prefixes, want %d", len(got), len(tt.wantNames))
+			}
+			for i, name := range tt.wantNames {
+				if got[i].Name != name {
+					t.Errorf("prefix %d = %q, want %q", i, got[i].Name, name)
+				}
+			}
+		})
+	}
+
+	extended, err := ListItemPrefixesFromOption("extended")
+	if err != nil || len(extended) != len(ExtendedListItemPrefixes) {
+		t.Errorf("extended set = %d prefixes (err %v), want %d", len(extended), err, len(ExtendedListItemPrefixes))
+	}
+}
@@ -5,3 +5,116 @@ import "regexp"
 // ListItemPrefixRegex is a compiled regular expression that matches list item prefixes
 // in the format of numbered items (e.g., "1.") or bullet points (e.g., "*" or "-")
 var ListItemPrefixRegex = regexp.MustCompile(`^(\d+\.|\*|\-)\s+`)
+
+// PassageListItemRegex matches list items inside passage text. "-" and "*" lines reach
+// the passage as content only when they have no answer delimiter.
+var PassageListItemRegex = regexp.MustCompile(`^(?:[•◦‣+*\-])\s+`)
+
+// TableRowRegex matches a pipe-delimited table row (e.g. "| Year | Event |")
+var TableRowRegex = regexp.MustCompile(`^\|.*\|$`)
+
+// TableSeparatorCellRegex matches a cell of a table header separator row (e.g. "---" or ":---:")
+var TableSeparatorCellRegex = regexp.MustCompile(`^:?-{3,}:?$`)
+
+// QuoteRegex matches a block quote line (e.g. "> To be or not to be")
+var QuoteRegex = regexp.MustCompile(`^>\s?`)
+
+// CodeFenceRegex matches the opening fence of a code block (e.g. "```python" or "~~~ go answer").
+// Group 1 is the fence, group 2 the language and group 3 what the block is attached to.
+var CodeFenceRegex = regexp.MustCompile("^(`{3,}|~{3,})\\s*([^\\s`]*)\\s*([^\\s`]*)\\s*$")
+
+// DirectiveLikeRegex matches a line that starts like a directive: one or two words, then a
+// separator (e.g. "Pasage: The Alps" or "Learn more - https://example.com").
+// Group 1 is the words, group 2 the separator and group 3 the rest of the line.
+var DirectiveLikeRegex = regexp.MustCompile(`^(?:#{1,6}\s*)?([A-Za-z]+(?:\s[A-Za-z]+)?)\s*([:=\-–—])\s*(.*)$`)
+
+// DirectiveRegex matches a directive line (e.g. "@context: APExams" or "@context APExams").
+// Group 1 is the directive name and group 2 its value.
+var DirectiveRegex = regexp.MustCompile(`^@([A-Za-z_]+)(?:\s*:\s*|\s+|$)(.*)$`)
+
+// VariableNameRegex matches a whole variable name (e.g. "course_code")
+var VariableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// VariableDefinitionRegex matches the value of a "@define" directive (e.g. "course = BIO 101").
+// Group 1 is the variable name and group 2 its value.
+var VariableDefinitionRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+// VariableReferenceRegex matches a reference to a variable (e.g. "{{course}}"). Cloze
+// deletions such as "{{c1::Paris}}" do not match. Group 1 is the variable name.
+var VariableReferenceRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ConditionRegex matches the value of an "@if" directive (e.g. `edition == "teacher"`):
+// an option name, "==" or "!=", and a value in double quotes or a single word
+var ConditionRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*(==|!=)\s*("[^"]*"|[^\s"]+)$`)
+
+// ParamRegex matches the value of a "@param" directive (e.g. "a in 1..20"). Group 1 is the
+// parameter name and groups 2 and 3 the bounds of its range.
+var ParamRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s+in\s+(-?\d+)\s*\.\.\s*(-?\d+)$`)
+
+// QuestionShapeRegex matches a question mark followed by an answer delimiter (e.g. "What is X? - Y")
+var QuestionShapeRegex = regexp.MustCompile(`\?\s*[-–—]\s*\S`)
+
+// NumberedLineRegex matches a line starting with a number and a malformed list item prefix
+// (e.g. "1 What" or "1, What"). Group 1 is the number and group 2 the rest of the line.
+var NumberedLineRegex = regexp.MustCompile(`^(\d+)\s*[.,:;)\]]?\s*(\S.*)$`)
+
+// AnnotationRegex matches the annotation at the end of a question line (e.g.
+// "{difficulty: 3, bloom: apply}"). Group 1 is the text inside the braces. The first
+// entry must start with a known annotation key, so template placeholders such as "{a+b}"
+// and set-builder notation such as "{x : x > 0}" do not match.
+var AnnotationRegex = regexp.MustCompile(`(?:^|\s)\{(\s*(?i:difficulty|bloom|keywords)\s*:[^{}]*)\}\s*$`)
+
+// NumericAnswerRegex matches an answer that starts with a number (e.g. "9.8 m/s^2",
+// "1,000", "6.02 × 10^23 mol"). Group 1 is the number, group 2 the power of ten of
+// scientific notation and group 3 the rest, which must be a unit.
+var NumericAnswerRegex = regexp.MustCompile(`^([-+]?(?:\d{1,3}(?:,\d{3})+|\d+)(?:\.\d+)?(?:[eE][-+]?\d+)?|[-+]?\.\d+)(?:\s*[×x]\s*10\^([-+]?\d+))?\s*(.*)$`)
+
+// FractionAnswerRegex matches an answer that is a fraction (e.g. "7/2"). Group 1 is the
+// numerator, group 2 the denominator and group 3 the rest, which must be a unit.
+var FractionAnswerRegex = regexp.MustCompile(`^([-+]?\d+)\s*/\s*(\d+)(?:\s+(.*))?$`)
+
+// EraYearRegex matches a year with an optional era (e.g. "1776", "AD 79", "44 BC").
+// Group 1 is an era before the year, group 2 the year and group 3 an era after it.
+var EraYearRegex = regexp.MustCompile(`(?i)^(?:(AD|A\.D\.|CE)\s+)?(\d{1,4})(?:\s*(AD|A\.D\.|CE|BC|B\.C\.|BCE|B\.C\.E\.))?$`)
+
+// YearQuestionRegex matches a prompt that asks for a year or a date (e.g. "In what year ...", "When did ...")
+var YearQuestionRegex = regexp.MustCompile(`(?i)\b(?:year|when)\b`)
+
+// OrdinalDayRegex matches the suffix of an ordinal day in a date (e.g. the "th" of "July 4th"). Group 1 is the day.
+var OrdinalDayRegex = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)\b`)
+
+// ListSeparatorRegex matches the separators of an enumerated answer: commas, with an
+// optional "and" or "or" after them, and a final "and" or "or" (e.g. "red, green and blue")
+var ListSeparatorRegex = regexp.MustCompile(`\s*,\s*(?:(?:and|or)\s+)?|\s+(?:and|or)\s+`)
+
+// QuestionTypeTagRegex matches a question type tag at the start of a prompt (e.g. "[tf] ")
+var QuestionTypeTagRegex = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+
+// ToleranceRegex matches a numeric answer with a tolerance (e.g. "9.8 ± 0.1" or "9.8 +/- 0.1").
+// Group 1 is the value and group 2 the tolerance.
+var ToleranceRegex = regexp.MustCompile(`^([-+]?\d+(?:\.\d+)?)\s*(?:±|\+/-)\s*(\d+(?:\.\d+)?)$`)
+
+// URLRegex matches a URL with any scheme (e.g. "https://example.com/page")
+var URLRegex = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.\-]*://[^\s<>"]+`)
+
+// DOIRegex matches a DOI, optionally written with a "doi:" prefix (e.g. "doi:10.1000/182").
+// Group 1 is the DOI itself.
+var DOIRegex = regexp.MustCompile(`(?i)\b(?:doi:\s*)?(10\.\d{4,9}/[^\s"<>]+)`)
+
+// ISBNRegex matches an ISBN after an "ISBN" label (e.g. "ISBN 978-0-306-40615-7").
+// Group 1 is the number with its hyphens.
+var ISBNRegex = regexp.MustCompile(`(?i)\bISBN(?:-1[03])?:?\s*(\d(?:-?\d){8,11}-?[\dX])\b`)
+
+// SourceRegex matches a "Source:" citation. Group 1 is the citation text.
+var SourceRegex = regexp.MustCompile(`(?i)\bsource:\s*(.+)$`)
+
+// EditorialMarkerRegex matches a TODO, FIXME or REVIEW marker in a comment (e.g. "TODO: verify this date").
+// Group 1 is the marker.
+var EditorialMarkerRegex = regexp.MustCompile(`\b(TODO|FIXME|REVIEW)\b`)
+
+// ClozeRegex matches a cloze deletion (e.g. "{{c1::Paris}}" or "{{c1::Paris::a city}}").
+// Group 1 is the index, group 2 the deleted text and group 3 the optional hint.
+var ClozeRegex = regexp.MustCompile(`\{\{c(\d+)::(.+?)(?:::(.*?))?\}\}`)
+
+// ClozeOpenRegex matches the start of a cloze deletion, well-formed or not
+var ClozeOpenRegex = regexp.MustCompile(`\{\{c\d*::`)
@@ -9,7 +9,9 @@ const (
 	SchemaTypeParser    SchemaType = "parser"
 	SchemaTypeBuilder   SchemaType = "builder"
 	SchemaTypeHash      SchemaType = "hash"
+	SchemaTypeExplain   SchemaType = "explain"
 )
 
-// Version is the current schema version
-const Version = "1.0.0"
+// Version is the current schema version. The major version changes when an existing field
+// changes shape: 2.0.0 made learn_more a list of entries, one per "Learn More:" line.
+const Version = "2.0.0"
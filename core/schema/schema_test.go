@@ -14,6 +14,7 @@ func TestAllSchemaTypes(t *testing.T) {
 		{SchemaTypeParser, "parser"},
 		{SchemaTypeBuilder, "builder"},
 		{SchemaTypeHash, "hash"},
+		{SchemaTypeExplain, "explain"},
 	}
 
 	for _, tt := range tests {
@@ -26,7 +27,7 @@ func TestAllSchemaTypes(t *testing.T) {
 }
 
 func TestVersionConstant(t *testing.T) {
-	if Version != "1.0.0" {
-		t.Errorf("expected version '1.0.0', got '%s'", Version)
+	if Version != "2.0.0" {
+		t.Errorf("expected version '2.0.0', got '%s'", Version)
 	}
 }
@@ -0,0 +1,57 @@
+// Package source maps the lines of a processed guide back to the files and
+// line numbers they came from. Guides can be assembled from several files
+// (includes) or have lines removed before lexing (front matter), so the line
+// numbers seen by the lexer are not always the ones an author should be shown.
+package source
+
+// Position is a line in an original source file
+type Position struct {
+	File string `json:"file,omitempty"` // Empty when the guide was not read from a file
+	Line int    `json:"line"`
+}
+
+// Map maps 1-based line numbers of a processed guide to their original positions.
+// A nil Map is the identity mapping with no file name.
+type Map struct {
+	positions []Position // Original position of each line, nil for an identity mapping
+	offset    int        // Lines skipped from the start of an identity mapping
+}
+
+// NewMap creates a map from the original position of each processed line
+func NewMap(positions []Position) *Map {
+	return &Map{positions: positions}
+}
+
+// Position returns the original position of a processed line.
+// Line 0 is used for errors that are not tied to a line and is returned unchanged.
+func (m *Map) Position(line int) Position {
+	if m == nil || line < 1 {
+		return Position{Line: line}
+	}
+	if m.positions == nil {
+		return Position{Line: line + m.offset}
+	}
+	if line > len(m.positions) {
+		return Position{Line: line}
+	}
+	return m.positions[line-1]
+}
+
+// Skip returns a map for the lines that remain after the first n lines are removed,
+// so that line 1 of the result maps to line n+1 of m.
+func (m *Map) Skip(n int) *Map {
+	if n <= 0 {
+		return m
+	}
+	if m == nil || m.positions == nil {
+		offset := n
+		if m != nil {
+			offset += m.offset
+		}
+		return &Map{offset: offset}
+	}
+	if n >= len(m.positions) {
+		return &Map{positions: []Position{}}
+	}
+	return &Map{positions: m.positions[n:]}
+}
@@ -0,0 +1,35 @@
+package source
+
+import "testing"
+
+func TestNilMapIsIdentity(t *testing.T) {
+	var m *Map
+	if got := m.Position(5); got != (Position{Line: 5}) {
+		t.Errorf("Position(5) = %+v, want line 5", got)
+	}
+	if got := m.Skip(3).Position(2); got != (Position{Line: 5}) {
+		t.Errorf("Skip(3).Position(2) = %+v, want line 5", got)
+	}
+	if got := m.Skip(3).Skip(1).Position(1); got != (Position{Line: 5}) {
+		t.Errorf("Skip(3).Skip(1).Position(1) = %+v, want line 5", got)
+	}
+}
+
+func TestMapPositions(t *testing.T) {
+	m := NewMap([]Position{
+		{File: "main.txt", Line: 1},
+		{File: "main.txt", Line: 2},
+		{File: "part.txt", Line: 1},
+		{File: "main.txt", Line: 4},
+	})
+
+	if got := m.Position(3); got != (Position{File: "part.txt", Line: 1}) {
+		t.Errorf("Position(3) = %+v", got)
+	}
+	if got := m.Skip(2).Position(2); got != (Position{File: "main.txt", Line: 4}) {
+		t.Errorf("Skip(2).Position(2) = %+v", got)
+	}
+	if got := m.Position(0); got != (Position{Line: 0}) {
+		t.Errorf("Position(0) = %+v, want line 0", got)
+	}
+}
@@ -0,0 +1,35 @@
+package templates
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeInvalidParam      ErrorCode = "INVALID_PARAM"
+	CodeDuplicateParam    ErrorCode = "DUPLICATE_PARAM"
+	CodeInvalidExpression ErrorCode = "INVALID_EXPRESSION"
+	CodeUnknownParam      ErrorCode = "UNKNOWN_PARAM"
+	CodeUnusedParam       ErrorCode = "UNUSED_PARAM"
+)
+
+// TemplateError reports a malformed parameter or placeholder. The caller knows the line.
+type TemplateError struct {
+	Message string
+	Code    ErrorCode
+	Text    string // The parameter or placeholder at fault
+}
+
+// Error implements the error interface
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s (text: %s)", e.Message, e.Text)
+}
+
+// NewTemplateError creates a new template error with the given code and message
+func NewTemplateError(code ErrorCode, message string, text string) *TemplateError {
+	return &TemplateError{
+		Message: message,
+		Code:    code,
+		Text:    text,
+	}
+}
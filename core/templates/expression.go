@@ -0,0 +1,223 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Expression is an arithmetic expression over parameters, such as "a+b" or "(a*b) % 7".
+// It supports numbers, parameter names, parentheses, unary minus and the operators
+// + - * / %, and is evaluated exactly: "/" gives a fraction and "%" needs integers.
+type Expression struct {
+	Source string
+	root   expressionNode
+	names  []string
+}
+
+type expressionNode interface {
+	eval(values map[string]int) (*big.Rat, error)
+}
+
+type numberNode struct{ value *big.Rat }
+
+type nameNode struct{ name string }
+
+type negateNode struct{ operand expressionNode }
+
+type binaryNode struct {
+	operator    byte
+	left, right expressionNode
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+func (n numberNode) eval(map[string]int) (*big.Rat, error) {
+	return n.value, nil
+}
+
+func (n nameNode) eval(values map[string]int) (*big.Rat, error) {
+	value, ok := values[n.name]
+	if !ok {
+		return nil, fmt.Errorf("parameter %q has no value", n.name)
+	}
+	return new(big.Rat).SetInt64(int64(value)), nil
+}
+
+func (n negateNode) eval(values map[string]int) (*big.Rat, error) {
+	operand, err := n.operand.eval(values)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Neg(operand), nil
+}
+
+func (n binaryNode) eval(values map[string]int) (*big.Rat, error) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(values)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case '+':
+		return new(big.Rat).Add(left, right), nil
+	case '-':
+		return new(big.Rat).Sub(left, right), nil
+	case '*':
+		return new(big.Rat).Mul(left, right), nil
+	case '/':
+		if right.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		return new(big.Rat).Quo(left, right), nil
+	default: // '%'
+		if !left.IsInt() || !right.IsInt() {
+			return nil, errors.New("% needs whole numbers")
+		}
+		if right.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		return new(big.Rat).SetInt(new(big.Int).Rem(left.Num(), right.Num())), nil
+	}
+}
+
+// ParseExpression parses the text of a placeholder
+func ParseExpression(text string) (*Expression, *TemplateError) {
+	p := &expressionParser{text: text}
+	root, err := p.parseSum()
+	if err == nil && p.skipSpace() < len(p.text) {
+		err = fmt.Errorf("unexpected %q", p.text[p.pos:])
+	}
+	if err != nil {
+		return nil, NewTemplateError(CodeInvalidExpression,
+			fmt.Sprintf("invalid expression {%s}: %s", text, err), text)
+	}
+	return &Expression{Source: text, root: root, names: p.names}, nil
+}
+
+// Names returns the parameters the expression refers to, in the order they first appear
+func (e *Expression) Names() []string {
+	return e.names
+}
+
+// Evaluate computes the value of the expression for the given parameter values
+func (e *Expression) Evaluate(values map[string]int) (*big.Rat, error) {
+	return e.root.eval(values)
+}
+
+// FormatValue writes a value the way it appears in a generated question: a whole number,
+// or a fraction in lowest terms such as "7/2"
+func FormatValue(value *big.Rat) string {
+	if value.IsInt() {
+		return value.Num().String()
+	}
+	return value.RatString()
+}
+
+// expressionParser is a recursive descent parser over the grammar
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/" | "%") unary }
+//	unary   = "-" unary | primary
+//	primary = number | name | "(" sum ")"
+type expressionParser struct {
+	text  string
+	pos   int
+	names []string
+}
+
+func (p *expressionParser) skipSpace() int {
+	for p.pos < len(p.text) && unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+	return p.pos
+}
+
+func (p *expressionParser) parseSum() (expressionNode, error) {
+	left, err := p.parseProduct()
+	for err == nil && p.skipSpace() < len(p.text) && strings.IndexByte("+-", p.text[p.pos]) >= 0 {
+		operator := p.text[p.pos]
+		p.pos++
+		var right expressionNode
+		if right, err = p.parseProduct(); err == nil {
+			left = binaryNode{operator: operator, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) parseProduct() (expressionNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.skipSpace() < len(p.text) && strings.IndexByte("*/%", p.text[p.pos]) >= 0 {
+		operator := p.text[p.pos]
+		p.pos++
+		var right expressionNode
+		if right, err = p.parseUnary(); err == nil {
+			left = binaryNode{operator: operator, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) parseUnary() (expressionNode, error) {
+	if p.skipSpace() < len(p.text) && p.text[p.pos] == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (expressionNode, error) {
+	if p.skipSpace() >= len(p.text) {
+		return nil, errors.New("unexpected end")
+	}
+	start := p.pos
+	switch c := rune(p.text[p.pos]); {
+	case c == '(':
+		p.pos++
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace() >= len(p.text) || p.text[p.pos] != ')' {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return inner, nil
+	case unicode.IsDigit(c):
+		for p.pos < len(p.text) && (unicode.IsDigit(rune(p.text[p.pos])) || p.text[p.pos] == '.') {
+			p.pos++
+		}
+		value, ok := new(big.Rat).SetString(p.text[start:p.pos])
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", p.text[start:p.pos])
+		}
+		return numberNode{value: value}, nil
+	case c == '_' || unicode.IsLetter(c):
+		for p.pos < len(p.text) && (p.text[p.pos] == '_' || unicode.IsLetter(rune(p.text[p.pos])) || unicode.IsDigit(rune(p.text[p.pos]))) {
+			p.pos++
+		}
+		name := p.text[start:p.pos]
+		p.addName(name)
+		return nameNode{name: name}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", p.text[p.pos:])
+}
+
+func (p *expressionParser) addName(name string) {
+	for _, seen := range p.names {
+		if seen == name {
+			return
+		}
+	}
+	p.names = append(p.names, name)
+}
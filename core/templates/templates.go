@@ -0,0 +1,257 @@
+// Package templates generates questions from template questions. The "@param" directives
+// before a question declare its parameters, and "{...}" placeholders in the question are
+// replaced by expressions over them:
+//
+//	@param a in 1..20
+//	@param b in 1..20
+//	1. What is {a} + {b}? - {a+b}
+//
+// Each instance draws values for the parameters from a seeded generator, so the same
+// template and seed always give the same questions.
+package templates
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/constants"
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+)
+
+const (
+	// DefaultCount is how many instances a template generates when no count is set
+	DefaultCount = 5
+	// MaxCount is the largest number of instances a template may generate
+	MaxCount = 1000
+
+	// attemptsPerInstance bounds the draws made for each instance, since templates with
+	// few distinct value sets cannot fill every instance
+	attemptsPerInstance = 20
+)
+
+// Param is a parameter of a template, which takes whole values from Min to Max
+type Param struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+// Template is a question whose texts contain placeholders
+type Template struct {
+	Params []Param
+	Source string // The texts as written, joined by " - "
+	texts  [][]segment
+}
+
+// Instance is a question generated from a template
+type Instance struct {
+	Values map[string]int // The value of each parameter
+	Texts  []string       // The texts of the template with their placeholders replaced
+}
+
+// segment is a part of a text: literal text, or a placeholder when expression is set
+type segment struct {
+	text       string
+	expression *Expression
+}
+
+// ParseParam reads the value of a "@param" directive, written "name in min..max". The range
+// may hold at most math.MaxInt64 values, so that Generate can draw from it.
+func ParseParam(text string) (*Param, *TemplateError) {
+	match := regexes.ParamRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return nil, NewTemplateError(CodeInvalidParam,
+			fmt.Sprintf("invalid parameter %q, expected \"name in min..max\"", text), text)
+	}
+	min, minErr := strconv.Atoi(match[2])
+	max, maxErr := strconv.Atoi(match[3])
+	if minErr != nil || maxErr != nil || min > max {
+		return nil, NewTemplateError(CodeInvalidParam,
+			fmt.Sprintf("invalid range %s..%s for parameter %q", match[2], match[3], match[1]), text)
+	}
+	if width := int64(max) - int64(min); width < 0 || width == math.MaxInt64 {
+		return nil, NewTemplateError(CodeInvalidParam,
+			fmt.Sprintf("range %s..%s for parameter %q is too wide", match[2], match[3], match[1]), text)
+	}
+	return &Param{Name: match[1], Min: min, Max: max}, nil
+}
+
+// Compile reads the placeholders of the texts of a template question, usually its prompt
+// and answer. Every placeholder must use only the given parameters, and every parameter
+// must be used.
+func Compile(params []Param, texts ...string) (*Template, *TemplateError) {
+	declared := make(map[string]bool, len(params))
+	for _, param := range params {
+		if declared[param.Name] {
+			return nil, NewTemplateError(CodeDuplicateParam,
+				fmt.Sprintf("parameter %q is declared more than once", param.Name), param.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	t := &Template{Params: params, Source: strings.Join(texts, constants.AnswerDelimiter)}
+	used := make(map[string]bool, len(params))
+	for _, text := range texts {
+		segments, err := parseSegments(text)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range segments {
+			if s.expression == nil {
+				continue
+			}
+			for _, name := range s.expression.Names() {
+				if !declared[name] {
+					return nil, NewTemplateError(CodeUnknownParam,
+						fmt.Sprintf("{%s} uses %q, which is not declared with @param", s.expression.Source, name), s.expression.Source)
+				}
+				used[name] = true
+			}
+		}
+		t.texts = append(t.texts, segments)
+	}
+	for _, param := range params {
+		if !used[param.Name] {
+			return nil, NewTemplateError(CodeUnusedParam,
+				fmt.Sprintf("parameter %q is not used in the question", param.Name), param.Name)
+		}
+	}
+	return t, nil
+}
+
+// parseSegments splits text into literal text and placeholders. Double braces, as in cloze
+// deletions, are literal text.
+func parseSegments(text string) ([]segment, *TemplateError) {
+	var segments []segment
+	var literal strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' {
+			literal.WriteByte(text[i])
+			continue
+		}
+		if strings.HasPrefix(text[i:], "{{") {
+			end := strings.Index(text[i:], "}}")
+			if end < 0 {
+				end = len(text) - i - 2
+			}
+			literal.WriteString(text[i : i+end+2])
+			i += end + 1
+			continue
+		}
+		end := strings.IndexByte(text[i:], '}')
+		if end < 0 {
+			return nil, NewTemplateError(CodeInvalidExpression, fmt.Sprintf("placeholder %q is not closed with }", text[i:]), text[i:])
+		}
+		expression, err := ParseExpression(text[i+1 : i+end])
+		if err != nil {
+			return nil, err
+		}
+		if literal.Len() > 0 {
+			segments = append(segments, segment{text: literal.String()})
+			literal.Reset()
+		}
+		segments = append(segments, segment{expression: expression})
+		i += end
+	}
+	if literal.Len() > 0 {
+		segments = append(segments, segment{text: literal.String()})
+	}
+	return segments, nil
+}
+
+// Render replaces the placeholders of the template's texts with their values
+func (t *Template) Render(values map[string]int) ([]string, error) {
+	texts := make([]string, len(t.texts))
+	for i, segments := range t.texts {
+		var b strings.Builder
+		for _, s := range segments {
+			if s.expression == nil {
+				b.WriteString(s.text)
+				continue
+			}
+			value, err := s.expression.Evaluate(values)
+			if err != nil {
+				return nil, fmt.Errorf("{%s}: %w", s.expression.Source, err)
+			}
+			b.WriteString(FormatValue(value))
+		}
+		texts[i] = b.String()
+	}
+	return texts, nil
+}
+
+// Generate draws up to count instances with distinct parameter values. Fewer are returned
+// when the parameters have fewer distinct values, and values for which a placeholder
+// cannot be evaluated, such as a division by zero, are skipped. The template's source
+// is mixed into the seed, so templates sharing a seed still draw different values.
+func (t *Template) Generate(count int, seed int64) []Instance {
+	hash := fnv.New64a()
+	hash.Write([]byte(t.Source))
+	random := rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+
+	var instances []Instance
+	seen := make(map[string]bool)
+	for attempt := 0; len(instances) < count && attempt < count*attemptsPerInstance; attempt++ {
+		values := make(map[string]int, len(t.Params))
+		for _, param := range t.Params {
+			values[param.Name] = param.Min + int(random.Int63n(int64(param.Max)-int64(param.Min)+1))
+		}
+		key := Key("", values)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		texts, err := t.Render(values)
+		if err != nil {
+			continue
+		}
+		instances = append(instances, Instance{Values: values, Texts: texts})
+	}
+	return instances
+}
+
+// Key identifies an instance by its template's source and its parameter values, for
+// hashing: "What is {a} + {b}? - {a+b}::a=3,b=5"
+func Key(source string, values map[string]int) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assignments := make([]string, len(names))
+	for i, name := range names {
+		assignments[i] = fmt.Sprintf("%s=%d", name, values[name])
+	}
+	return source + "::" + strings.Join(assignments, ",")
+}
+
+// ParseCount reads the number of instances each template generates. An empty value is DefaultCount.
+func ParseCount(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultCount, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 || count > MaxCount {
+		return 0, fmt.Errorf("invalid template instance count %q, expected a number from 1 to %d", value, MaxCount)
+	}
+	return count, nil
+}
+
+// ParseSeed reads the seed of the generator. An empty value is seed 0.
+func ParseSeed(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid template seed %q, expected a whole number", value)
+	}
+	return seed, nil
+}
@@ -0,0 +1,166 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParam(t *testing.T) {
+	got, err := ParseParam(" a in -3 .. 12 ")
+	if err != nil || !reflect.DeepEqual(got, &Param{Name: "a", Min: -3, Max: 12}) {
+		t.Errorf("ParseParam() = %+v, %v, want a in -3..12", got, err)
+	}
+	for _, text := range []string{"a", "a in 1", "a in 1..b", "1a in 1..2", "a in 5..1",
+		"a in 0..9223372036854775807", "a in -1..9223372036854775806", "a in -9223372036854775808..0"} {
+		if _, err := ParseParam(text); err == nil || err.Code != CodeInvalidParam {
+			t.Errorf("ParseParam(%q) error = %v, want %s", text, err, CodeInvalidParam)
+		}
+	}
+
+	// The widest range that fits still generates values
+	param, err := ParseParam("a in 1..9223372036854775807")
+	if err != nil {
+		t.Fatalf("ParseParam() unexpected error: %v", err)
+	}
+	template, err := Compile([]Param{*param}, "What is {a}?", "{a}")
+	if err != nil {
+		t.Fatalf("Compile() unexpected error: %v", err)
+	}
+	if instances := template.Generate(3, 1); len(instances) != 3 {
+		t.Errorf("Generate() = %d instances, want 3", len(instances))
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	values := map[string]int{"a": 7, "b": 2}
+	tests := map[string]string{
+		"a+b":           "9",
+		"a - b * 3":     "1",
+		"(a - b) * 3":   "15",
+		"a / b":         "7/2",
+		"a % b":         "1",
+		"-a + 0.5":      "-13/2",
+		" a*a - b*b ":   "45",
+		"((a))":         "7",
+		"a / (b - 2.0)": "",
+	}
+	for text, want := range tests {
+		expression, err := ParseExpression(text)
+		if err != nil {
+			t.Fatalf("ParseExpression(%q) error: %v", text, err)
+		}
+		value, evalErr := expression.Evaluate(values)
+		if want == "" {
+			if evalErr == nil {
+				t.Errorf("Evaluate(%q) expected a division by zero", text)
+			}
+			continue
+		}
+		if evalErr != nil || FormatValue(value) != want {
+			t.Errorf("Evaluate(%q) = %v, %v, want %s", text, value, evalErr, want)
+		}
+	}
+	for _, text := range []string{"", "a +", "(a", "a b", "a ^ b", "1..2"} {
+		if _, err := ParseExpression(text); err == nil || err.Code != CodeInvalidExpression {
+			t.Errorf("ParseExpression(%q) error = %v, want %s", text, err, CodeInvalidExpression)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	params := []Param{{Name: "a", Min: 1, Max: 3}, {Name: "b", Min: 1, Max: 3}}
+	template, err := Compile(params, "What is {a} + {b} in {{c1::maths}}?", "{a+b}")
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+	texts, renderErr := template.Render(map[string]int{"a": 1, "b": 2})
+	if want := []string{"What is 1 + 2 in {{c1::maths}}?", "3"}; renderErr != nil || !reflect.DeepEqual(texts, want) {
+		t.Errorf("Render() = %q, %v, want %q", texts, renderErr, want)
+	}
+
+	tests := []struct {
+		params []Param
+		texts  []string
+		want   ErrorCode
+	}{
+		{append(params, Param{Name: "a"}), []string{"{a}", "{b}"}, CodeDuplicateParam},
+		{params, []string{"{a} and {c}", "{a+b}"}, CodeUnknownParam},
+		{params, []string{"{a}", "{a}"}, CodeUnusedParam},
+		{params, []string{"{a} and {b", "1"}, CodeInvalidExpression},
+		{params, []string{"{a} and {b +}", "1"}, CodeInvalidExpression},
+	}
+	for _, tt := range tests {
+		if _, err := Compile(tt.params, tt.texts...); err == nil || err.Code != tt.want {
+			t.Errorf("Compile(%q) error = %v, want %s", tt.texts, err, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	params := []Param{{Name: "a", Min: 1, Max: 20}, {Name: "b", Min: 1, Max: 20}}
+	template, err := Compile(params, "What is {a} + {b}?", "{a+b}")
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	instances := template.Generate(5, 42)
+	if len(instances) != 5 {
+		t.Fatalf("Generate() returned %d instances, want 5", len(instances))
+	}
+	seen := map[string]bool{}
+	for _, instance := range instances {
+		a, b := instance.Values["a"], instance.Values["b"]
+		if a < 1 || a > 20 || b < 1 || b > 20 {
+			t.Errorf("values %v are out of range", instance.Values)
+		}
+		if want, _ := template.Render(instance.Values); !reflect.DeepEqual(instance.Texts, want) {
+			t.Errorf("Texts = %q, want %q", instance.Texts, want)
+		}
+		key := Key(template.Source, instance.Values)
+		if seen[key] {
+			t.Errorf("values %v were drawn twice", instance.Values)
+		}
+		seen[key] = true
+	}
+	if again := template.Generate(5, 42); !reflect.DeepEqual(again, instances) {
+		t.Error("Generate() should give the same instances for the same seed")
+	}
+	if other := template.Generate(5, 43); reflect.DeepEqual(other, instances) {
+		t.Error("Generate() should give other instances for another seed")
+	}
+
+	// Few distinct values give fewer instances, and values that cannot be evaluated are skipped
+	small, err := Compile([]Param{{Name: "a", Min: 0, Max: 2}}, "What is 6 / {a}?", "{6/a}")
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+	if instances := small.Generate(10, 1); len(instances) != 2 {
+		t.Errorf("Generate() returned %d instances, want 2", len(instances))
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got, want := Key("What is {a}? - {a}", map[string]int{"b": 2, "a": -1}), "What is {a}? - {a}::a=-1,b=2"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+}
+
+func TestParseCountAndSeed(t *testing.T) {
+	if count, err := ParseCount(""); err != nil || count != DefaultCount {
+		t.Errorf("ParseCount(\"\") = %d, %v, want %d", count, err, DefaultCount)
+	}
+	if count, err := ParseCount("12"); err != nil || count != 12 {
+		t.Errorf("ParseCount(12) = %d, %v", count, err)
+	}
+	for _, value := range []string{"0", "-1", "many", "1001"} {
+		if _, err := ParseCount(value); err == nil {
+			t.Errorf("ParseCount(%q) expected an error", value)
+		}
+	}
+	if seed, err := ParseSeed("-7"); err != nil || seed != -7 {
+		t.Errorf("ParseSeed(-7) = %d, %v", seed, err)
+	}
+	if _, err := ParseSeed("x"); err == nil {
+		t.Error("ParseSeed(x) expected an error")
+	}
+}
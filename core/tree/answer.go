@@ -0,0 +1,16 @@
+package tree
+
+// StructuredAnswer is the answer of a typed question in structured form.
+// Only the field for the question's type is set.
+type StructuredAnswer struct {
+	Truth    *bool       `json:"truth,omitempty"`    // TrueFalse
+	Answers  []string    `json:"answers,omitempty"`  // MultiSelect: every correct answer
+	Sequence []string    `json:"sequence,omitempty"` // Ordering: the items in their correct order
+	Pairs    []MatchPair `json:"pairs,omitempty"`    // Matching: the correct pairs
+}
+
+// MatchPair is one correct pair of a matching question
+type MatchPair struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
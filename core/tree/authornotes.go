@@ -0,0 +1,36 @@
+package tree
+
+// AuthorNotesEntry holds the author notes of a tag, passage or question. Notes are left
+// out of the nodes' own JSON, so that they are only exported when asked for.
+type AuthorNotesEntry struct {
+	Hash  string   `json:"hash"`
+	Notes []string `json:"notes"`
+}
+
+// CollectAuthorNotes sets AuthorNotes to the notes of every tag, passage and question
+// that has any, in tree order
+func (t *Tree) CollectAuthorNotes() {
+	var entries []*AuthorNotesEntry
+	add := func(hash string, notes []string) {
+		if len(notes) > 0 {
+			entries = append(entries, &AuthorNotesEntry{Hash: hash, Notes: notes})
+		}
+	}
+	t.TraverseForTagTypes(func(tagTypeAssignable TagTypeAssignable, depth int) {
+		tag, ok := tagTypeAssignable.(*Tag)
+		if !ok {
+			return
+		}
+		add(tag.Hash, tag.AuthorNotes)
+		for _, question := range tag.Questions {
+			add(question.Hash, question.AuthorNotes)
+		}
+		for _, passage := range tag.Passages {
+			add(passage.Hash, passage.AuthorNotes)
+			for _, question := range passage.Questions {
+				add(question.Hash, question.AuthorNotes)
+			}
+		}
+	})
+	t.AuthorNotes = entries
+}
@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/reference"
+)
+
+// BibliographyEntry is a work cited in the guide, with the hashes of the tags,
+// passages and questions that cite it
+type BibliographyEntry struct {
+	reference.Reference
+	CitedBy []string `json:"cited_by"`
+}
+
+// FilterReferences removes the references for which keep returns false from every
+// tag, passage and question
+func (t *Tree) FilterReferences(keep func(reference.Reference) bool) {
+	t.visitReferences(func(hash string, references *[]reference.Reference) {
+		var kept []reference.Reference
+		for _, r := range *references {
+			if keep(r) {
+				kept = append(kept, r)
+			}
+		}
+		*references = kept
+	})
+}
+
+// CollectBibliography sets Bibliography to the distinct references in the tree, in the
+// order they are first cited. References to the same work written differently are merged.
+func (t *Tree) CollectBibliography() {
+	var entries []*BibliographyEntry
+	byKey := map[string]*BibliographyEntry{}
+
+	t.visitReferences(func(hash string, references *[]reference.Reference) {
+		for _, r := range *references {
+			entry, ok := byKey[r.Key()]
+			if !ok {
+				entry = &BibliographyEntry{Reference: r, CitedBy: []string{}}
+				byKey[r.Key()] = entry
+				entries = append(entries, entry)
+			}
+			if n := len(entry.CitedBy); n == 0 || entry.CitedBy[n-1] != hash {
+				entry.CitedBy = append(entry.CitedBy, hash)
+			}
+		}
+	})
+	t.Bibliography = entries
+}
+
+// visitReferences calls visitor with the hash and references of every tag, passage and question
+func (t *Tree) visitReferences(visitor func(hash string, references *[]reference.Reference)) {
+	t.TraverseForTagTypes(func(tagTypeAssignable TagTypeAssignable, depth int) {
+		tag, ok := tagTypeAssignable.(*Tag)
+		if !ok {
+			return
+		}
+		visitor(tag.Hash, &tag.References)
+		for _, question := range tag.Questions {
+			visitor(question.Hash, &question.References)
+		}
+		for _, passage := range tag.Passages {
+			visitor(passage.Hash, &passage.References)
+			for _, question := range passage.Questions {
+				visitor(question.Hash, &question.References)
+			}
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package tree
+
+// BlockType identifies the kind of a passage content block
+type BlockType string
+
+const (
+	BlockTypeParagraph BlockType = "paragraph"
+	BlockTypeList      BlockType = "list"
+	BlockTypeTable     BlockType = "table"
+	BlockTypeQuote     BlockType = "quote"
+	BlockTypeCode      BlockType = "code"
+)
+
+// Block is a structural unit of passage content: a paragraph, list, table, quote or code block.
+// Only the fields relevant to the block's type are set.
+type Block struct {
+	Type   BlockType  `json:"type"`
+	Text   string     `json:"text,omitempty"`   // Paragraph and quote text
+	Items  []string   `json:"items,omitempty"`  // List items
+	Header []string   `json:"header,omitempty"` // Table header cells, when the table has a separator row
+	Rows   [][]string `json:"rows,omitempty"`   // Table body rows
+	Code   *CodeBlock `json:"code,omitempty"`   // Code block contents
+}
+
+// CodeBlock is a fenced code block. Code keeps the lines exactly as written,
+// including indentation and '#' lines, joined with newlines.
+type CodeBlock struct {
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code"`
+}
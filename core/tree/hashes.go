@@ -0,0 +1,51 @@
+package tree
+
+import (
+	"fmt"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/idgen"
+	"github.com/studyguides-com/study-guides-parser/core/templates"
+)
+
+// The hashes of tags, passages and questions are computed by these functions only. The
+// constructors use cleanstring.LegacyPolicy, and the builder the guide's normalization
+// policy, so that a guide hashes the same way wherever its items are created.
+
+// TagHash returns the hash of a tag from its parent's title, empty for a top-level tag,
+// and its own
+func TagHash(parentTitle string, title string, policy cleanstring.Policy) string {
+	return hashKey(parentTitle+title, policy)
+}
+
+// PassageHash returns the hash of a passage from its title
+func PassageHash(title string, policy cleanstring.Policy) string {
+	return hashKey(title, policy)
+}
+
+// QuestionHash returns the hash of a question from its prompt and answer
+func QuestionHash(prompt string, answer string, policy cleanstring.Policy) string {
+	return hashKey(prompt+answer, policy)
+}
+
+// ClozeQuestionHash returns the hash of the question that blanks a cloze index of a
+// sentence. The source is the whole sentence with every deletion as written, so the
+// questions of a sentence differ only by index and all change when any part is reworded.
+func ClozeQuestionHash(source string, index int, policy cleanstring.Policy) string {
+	return hashKey(fmt.Sprintf("%s::c%d", source, index), policy)
+}
+
+// TemplateQuestionHash returns the hash of a template instance from the template and its
+// parameter values, so it stays the same across builds with the same seed
+func TemplateQuestionHash(source string, values map[string]int, policy cleanstring.Policy) string {
+	return hashKey(templates.Key(source, values), policy)
+}
+
+// hashKey hashes text as the policy keys it. A policy that keeps case hashes the text as
+// written, since the lexer has already applied the rest of the policy to it.
+func hashKey(text string, policy cleanstring.Policy) string {
+	if !policy.KeepCase {
+		text = policy.Key(text)
+	}
+	return idgen.HashFrom(text)
+}
@@ -1,7 +1,10 @@
 package tree
 
 import (
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
 	"github.com/studyguides-com/study-guides-parser/core/idgen"
+	"github.com/studyguides-com/study-guides-parser/core/readability"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
 )
 
 type Passage struct {
@@ -9,15 +12,47 @@ type Passage struct {
 	Hash      string      `json:"hash,omitempty"`
 	Title     string      `json:"title"`
 	Content   string      `json:"content,omitempty"`
+	Blocks    []*Block    `json:"blocks,omitempty"`
 	Questions []*Question `json:"questions,omitempty"`
+	LearnMore []string    `json:"learn_more,omitempty"` // Further reading on the whole passage
+
+	// Where the passage comes from and who it is for, from the directives after its title
+	Author     string `json:"author,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Year       int    `json:"year,omitempty"`
+	GradeLevel int    `json:"grade_level,omitempty"`
+
+	// Readability of the passage's prose, set by AnalyzeReadability
+	Readability *readability.Stats `json:"readability,omitempty"`
+
+	// URLs, DOIs, ISBNs and citations found in the title, content and Learn More lines
+	References []reference.Reference `json:"references,omitempty"`
+
+	// Comments the author wrote around the passage, kept only with the keep_comments
+	// option and exported only in the tree's AuthorNotes
+	AuthorNotes []string `json:"-"`
+
+	// Rich-text versions of Title, Content and LearnMore, set only when they contain markup
+	TitleRich     *RichText   `json:"title_rich,omitempty"`
+	ContentRich   *RichText   `json:"content_rich,omitempty"`
+	LearnMoreRich []*RichText `json:"learn_more_rich,omitempty"`
 }
 
 func NewPassage(title string, content string, questions []*Question) *Passage {
 	return &Passage{
 		InsertID:  idgen.NewCUID(),
-		Hash:      idgen.HashFrom(title),
+		Hash:      PassageHash(title, cleanstring.LegacyPolicy),
 		Title:     title,
 		Content:   content,
 		Questions: questions,
+
+		TitleRich:   NewRichText(title),
+		ContentRich: NewRichText(content),
 	}
 }
+
+// AddLearnMore appends a Learn More entry to the passage
+func (p *Passage) AddLearnMore(text string) {
+	p.LearnMore = append(p.LearnMore, text)
+	p.LearnMoreRich = NewRichTexts(p.LearnMore)
+}
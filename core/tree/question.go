@@ -1,32 +1,94 @@
 package tree
 
 import (
+	"github.com/studyguides-com/study-guides-parser/core/answers"
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
 	"github.com/studyguides-com/study-guides-parser/core/idgen"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
 )
 
 type Question struct {
-	InsertID    string   `json:"insert_id,omitempty"`
-	Hash        string   `json:"hash,omitempty"`
-	Prompt      string   `json:"prompt"`
-	Answer      string   `json:"answer"`
-	Distractors []string `json:"distractors"`
-	LearnMore   string   `json:"learn_more"`
-	Order       int      `json:"order"`
+	InsertID     string   `json:"insert_id,omitempty"`
+	Hash         string   `json:"hash,omitempty"`
+	Prompt       string   `json:"prompt"`
+	Answer       string   `json:"answer"`
+	Distractors  []string `json:"distractors"`
+	LearnMore    []string `json:"learn_more"` // Further reading, one entry per "Learn More:" line
+	Order        int      `json:"order"`
+	SourceLabel  string   `json:"source_label,omitempty"`  // The author's label from the source (e.g. "3", "b", "iv")
+	SourceNumber int      `json:"source_number,omitempty"` // The author's label as an ordinal, 0 when unnumbered
+
+	// QuestionType says how the question is answered. For every type except ShortAnswer,
+	// StructuredAnswer holds the parsed answer; Answer keeps the answer as written.
+	QuestionType     ontology.QuestionType `json:"question_type"`
+	StructuredAnswer *StructuredAnswer     `json:"structured_answer,omitempty"`
+
+	// Other answers accepted for a short-answer question besides Answer, and how far
+	// a numeric answer may be off. Used by the grading package.
+	AcceptableAnswers []string `json:"acceptable_answers,omitempty"`
+	Tolerance         float64  `json:"tolerance,omitempty"`
+
+	// Pedagogical details from the annotation at the end of the question line. Without
+	// an annotated difficulty the builder estimates one and sets DifficultyEstimated.
+	Difficulty          int                 `json:"difficulty,omitempty"`
+	DifficultyEstimated bool                `json:"difficulty_estimated,omitempty"`
+	BloomLevel          ontology.BloomLevel `json:"bloom_level,omitempty"`
+	Keywords            []string            `json:"keywords,omitempty"`
+
+	// The kind of value the answer holds, with the number, unit, date or items read from it
+	AnswerValue *answers.Value `json:"answer_value,omitempty"`
+
+	// URLs, DOIs, ISBNs and citations found in the question's Learn More lines
+	References []reference.Reference `json:"references,omitempty"`
+
+	// Comments the author wrote around the question, kept only with the keep_comments
+	// option and exported only in the tree's AuthorNotes
+	AuthorNotes []string `json:"-"`
+
+	// Questions generated from a cloze sentence share its source; ClozeIndex is the
+	// deletion index (c1, c2, ...) that the question blanks out
+	ClozeIndex  int    `json:"cloze_index,omitempty"`
+	ClozeSource string `json:"cloze_source,omitempty"`
+
+	// Questions generated from a template question share its source, the question as
+	// written; TemplateValues holds the parameter values of this instance
+	TemplateSource string         `json:"template_source,omitempty"`
+	TemplateValues map[string]int `json:"template_values,omitempty"`
+
+	// Code blocks that follow the question line, attached to the prompt or the answer
+	PromptCode []*CodeBlock `json:"prompt_code,omitempty"`
+	AnswerCode []*CodeBlock `json:"answer_code,omitempty"`
+
+	// Rich-text versions of Prompt, Answer and LearnMore, set only when they contain markup.
+	// LearnMoreRich has one entry per LearnMore entry, nil for the plain ones.
+	PromptRich    *RichText   `json:"prompt_rich,omitempty"`
+	AnswerRich    *RichText   `json:"answer_rich,omitempty"`
+	LearnMoreRich []*RichText `json:"learn_more_rich,omitempty"`
 }
 
-func NewQuestion(prompt string, answer string, distractors []string, learnMore string, order int) *Question {
-	// If distractors is nil, create an empty slice
+func NewQuestion(prompt string, answer string, distractors []string, learnMore []string, order int) *Question {
+	// If distractors or learnMore is nil, create an empty slice
 	if distractors == nil {
 		distractors = []string{}
 	}
+	if learnMore == nil {
+		learnMore = []string{}
+	}
 
 	return &Question{
 		InsertID:    idgen.NewCUID(),
-		Hash:        idgen.HashFrom(prompt + answer),
+		Hash:        QuestionHash(prompt, answer, cleanstring.LegacyPolicy),
 		Prompt:      prompt,
 		Answer:      answer,
 		Distractors: distractors,
 		LearnMore:   learnMore,
 		Order:       order,
+
+		QuestionType: ontology.QuestionTypeShortAnswer,
+
+		PromptRich:    NewRichText(prompt),
+		AnswerRich:    NewRichText(answer),
+		LearnMoreRich: NewRichTexts(learnMore),
 	}
 }
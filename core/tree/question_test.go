@@ -3,11 +3,14 @@ package tree
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
+	"github.com/studyguides-com/study-guides-parser/core/idgen"
 )
 
 func TestNewQuestionWithNilDistractors(t *testing.T) {
 	// Test with nil distractors
-	question := NewQuestion("What is 1 + 1?", "2", nil, "Simple addition", 1)
+	question := NewQuestion("What is 1 + 1?", "2", nil, []string{"Simple addition"}, 1)
 
 	jsonData, err := json.Marshal(question)
 	if err != nil {
@@ -29,7 +32,7 @@ func TestNewQuestionWithNilDistractors(t *testing.T) {
 
 func TestNewQuestionWithEmptyDistractors(t *testing.T) {
 	// Test with empty slice
-	question := NewQuestion("What is 1 + 1?", "2", []string{}, "Simple addition", 1)
+	question := NewQuestion("What is 1 + 1?", "2", []string{}, []string{"Simple addition"}, 1)
 
 	jsonData, err := json.Marshal(question)
 	if err != nil {
@@ -47,7 +50,7 @@ func TestNewQuestionWithEmptyDistractors(t *testing.T) {
 func TestNewQuestionWithDistractors(t *testing.T) {
 	// Test with actual distractors
 	distractors := []string{"3", "4", "5"}
-	question := NewQuestion("What is 1 + 1?", "2", distractors, "Simple addition", 1)
+	question := NewQuestion("What is 1 + 1?", "2", distractors, []string{"Simple addition"}, 1)
 
 	jsonData, err := json.Marshal(question)
 	if err != nil {
@@ -64,9 +67,9 @@ func TestNewQuestionWithDistractors(t *testing.T) {
 
 func TestNewQuestionOrder(t *testing.T) {
 	// Test that order is correctly set
-	q1 := NewQuestion("Question 1?", "A", nil, "", 1)
-	q2 := NewQuestion("Question 2?", "B", nil, "", 2)
-	q3 := NewQuestion("Question 3?", "C", nil, "", 5)
+	q1 := NewQuestion("Question 1?", "A", nil, nil, 1)
+	q2 := NewQuestion("Question 2?", "B", nil, nil, 2)
+	q3 := NewQuestion("Question 3?", "C", nil, nil, 5)
 
 	if q1.Order != 1 {
 		t.Errorf("Expected q1.Order to be 1, got %d", q1.Order)
@@ -81,7 +84,7 @@ func TestNewQuestionOrder(t *testing.T) {
 
 func TestQuestionOrderInJSON(t *testing.T) {
 	// Test that order is included in JSON serialization
-	question := NewQuestion("What is 2 + 2?", "4", nil, "", 3)
+	question := NewQuestion("What is 2 + 2?", "4", nil, nil, 3)
 
 	jsonData, err := json.Marshal(question)
 	if err != nil {
@@ -95,3 +98,19 @@ func TestQuestionOrderInJSON(t *testing.T) {
 		t.Errorf("Expected order:3 in JSON, got: %s", jsonStr)
 	}
 }
+
+func TestQuestionHash(t *testing.T) {
+	if q := NewQuestion("Capital of France?", "Paris", nil, nil, 1); q.Hash != idgen.HashFrom("Capital of France?Paris") {
+		t.Errorf("NewQuestion hash = %q, want the hash of the prompt and answer as written", q.Hash)
+	}
+	folded, err := cleanstring.ParsePolicy("nfc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if QuestionHash("Capital of France?", "PARIS", folded) != QuestionHash("capital of france?", "paris", folded) {
+		t.Error("QuestionHash should fold case for a policy that does not keep case")
+	}
+	if QuestionHash("Capital of France?", "PARIS", cleanstring.LegacyPolicy) == QuestionHash("capital of france?", "paris", cleanstring.LegacyPolicy) {
+		t.Error("QuestionHash should keep case for the legacy policy")
+	}
+}
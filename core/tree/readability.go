@@ -0,0 +1,62 @@
+package tree
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/readability"
+)
+
+// AnalyzeReadability scores the prose of every passage, and of every tag as the passages
+// in it and in the tags under it taken together. Reading times assume wordsPerMinute.
+// Passages and tags without prose are left without a score.
+func (t *Tree) AnalyzeReadability(wordsPerMinute int) {
+	if t.Root == nil {
+		return
+	}
+	for _, tag := range t.Root.ChildTags {
+		analyzeTagReadability(tag, wordsPerMinute)
+	}
+}
+
+// analyzeTagReadability scores the passages of tag and the tags under it, and returns
+// their combined counts
+func analyzeTagReadability(tag *Tag, wordsPerMinute int) readability.Counts {
+	var total readability.Counts
+	for _, passage := range tag.Passages {
+		counts := passage.readabilityCounts()
+		passage.Readability = counts.Stats(wordsPerMinute)
+		total = total.Add(counts)
+	}
+	for _, child := range tag.ChildTags {
+		total = total.Add(analyzeTagReadability(child, wordsPerMinute))
+	}
+	tag.Readability = total.Stats(wordsPerMinute)
+	return total
+}
+
+// readabilityCounts counts the prose of the passage: its paragraphs, quotes and list items,
+// read without markup. Tables and code are not prose and are left out. The end of each
+// paragraph, quote and item also ends a sentence.
+func (p *Passage) readabilityCounts() readability.Counts {
+	if len(p.Blocks) == 0 {
+		return readability.Count(plainText(p.Content))
+	}
+	var counts readability.Counts
+	for _, block := range p.Blocks {
+		switch block.Type {
+		case BlockTypeParagraph, BlockTypeQuote:
+			counts = counts.Add(readability.Count(plainText(block.Text)))
+		case BlockTypeList:
+			for _, item := range block.Items {
+				counts = counts.Add(readability.Count(plainText(item)))
+			}
+		}
+	}
+	return counts
+}
+
+// plainText returns text without its inline markup
+func plainText(text string) string {
+	if rich := NewRichText(text); rich != nil {
+		return rich.Plain
+	}
+	return text
+}
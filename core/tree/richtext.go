@@ -0,0 +1,41 @@
+package tree
+
+import (
+	"github.com/studyguides-com/study-guides-parser/core/markup"
+)
+
+// RichText is text with inline markup parsed into a rich-text AST, plus its
+// plain-text rendering with the markers removed
+type RichText struct {
+	Nodes []*markup.Node `json:"nodes"`
+	Plain string         `json:"plain"`
+}
+
+// NewRichText parses the inline markup in text. It returns nil when the text
+// contains no markup or escapes, so plain fields stay plain in the output.
+func NewRichText(text string) *RichText {
+	nodes, _ := markup.Parse(text)
+	plain := markup.PlainText(nodes)
+	if markup.IsPlain(nodes) && plain == text {
+		return nil
+	}
+	return &RichText{
+		Nodes: nodes,
+		Plain: plain,
+	}
+}
+
+// NewRichTexts parses each of texts. It returns nil when none of them contains markup;
+// otherwise the result has one entry per text, nil for the plain ones.
+func NewRichTexts(texts []string) []*RichText {
+	var rich []*RichText
+	for i, text := range texts {
+		if r := NewRichText(text); r != nil {
+			if rich == nil {
+				rich = make([]*RichText, len(texts))
+			}
+			rich[i] = r
+		}
+	}
+	return rich
+}
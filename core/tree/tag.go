@@ -1,8 +1,11 @@
 package tree
 
 import (
+	"github.com/studyguides-com/study-guides-parser/core/cleanstring"
 	"github.com/studyguides-com/study-guides-parser/core/idgen"
 	"github.com/studyguides-com/study-guides-parser/core/ontology"
+	"github.com/studyguides-com/study-guides-parser/core/readability"
+	"github.com/studyguides-com/study-guides-parser/core/reference"
 )
 
 // TagQATarget defines the interface for tags that can be QA'd
@@ -10,6 +13,7 @@ type TagQATarget interface {
 	GetTagType() ontology.TagType
 	GetTitle() string
 	GetContext() ontology.ContextType
+	GetDeclaredContext() ontology.ContextType
 }
 
 // TagContainer interface for types that can contain child tags
@@ -52,13 +56,21 @@ type Tag struct {
 	TagType            ontology.TagType           `json:"tag_type,omitempty"`
 	InsertID           string                     `json:"insert_id,omitempty"`
 	Context            ontology.ContextType       `json:"context,omitempty"`
+	DeclaredContext    ontology.ContextType       `json:"declared_context,omitempty"` // Set on top-level tags by an "@context" directive, overriding the guide's context
 	ContentRating      ontology.ContentRatingType `json:"content_rating"`
 	ContentDescriptors []string                   `json:"content_descriptors"`
 	MetaTags           []string                   `json:"meta_tags"`
 	Hash               string                     `json:"hash,omitempty"`
 	Overview           *Overview                  `json:"overview"`
+	Notes              string                     `json:"notes,omitempty"`           // Text written under the tag's header outside any passage
+	NotesRich          *RichText                  `json:"notes_rich,omitempty"`      // Rich-text version of Notes, set only when it contains markup
+	LearnMore          []string                   `json:"learn_more,omitempty"`      // Further reading on the tag's topic
+	LearnMoreRich      []*RichText                `json:"learn_more_rich,omitempty"` // Rich-text versions of LearnMore, nil entries for plain text
+	References         []reference.Reference      `json:"references,omitempty"`      // URLs, DOIs, ISBNs and citations in Notes and LearnMore
+	AuthorNotes        []string                   `json:"-"`                         // Comments around the header, kept with the keep_comments option and exported only in the tree's AuthorNotes
 	Questions          []*Question                `json:"questions,omitempty"`
 	Passages           []*Passage                 `json:"passages,omitempty"`
+	Readability        *readability.Stats         `json:"readability,omitempty"` // Readability of every passage in the tag and the tags under it, taken together
 	ChildTags          []*Tag                     `json:"child_tags"`
 }
 
@@ -66,7 +78,7 @@ func NewTag(title string) *Tag {
 	return &Tag{
 		InsertID:           idgen.NewCUID(),
 		Title:              title,
-		Hash:               idgen.HashFrom(title),
+		Hash:               TagHash("", title, cleanstring.LegacyPolicy),
 		TagType:            ontology.TagTypeNone,
 		Context:            ontology.ContextTypeNone,
 		ContentRating:      ontology.ContentRatingRatingPending,
@@ -81,7 +93,7 @@ func NewTagWithParent(title string, parentTitle string) *Tag {
 	return &Tag{
 		InsertID:           idgen.NewCUID(),
 		Title:              title,
-		Hash:               idgen.HashFrom(parentTitle + title),
+		Hash:               TagHash(parentTitle, title, cleanstring.LegacyPolicy),
 		TagType:            ontology.TagTypeNone,
 		Context:            ontology.ContextTypeNone,
 		ContentRating:      ontology.ContentRatingRatingPending,
@@ -91,6 +103,22 @@ func NewTagWithParent(title string, parentTitle string) *Tag {
 	}
 }
 
+// AddNote appends a line of text to the tag's notes
+func (t *Tag) AddNote(text string) {
+	if t.Notes == "" {
+		t.Notes = text
+	} else {
+		t.Notes += "\n" + text
+	}
+	t.NotesRich = NewRichText(t.Notes)
+}
+
+// AddLearnMore appends a Learn More entry to the tag
+func (t *Tag) AddLearnMore(text string) {
+	t.LearnMore = append(t.LearnMore, text)
+	t.LearnMoreRich = NewRichTexts(t.LearnMore)
+}
+
 func (t *Tag) GetChildTags() []*Tag {
 	return t.ChildTags
 }
@@ -119,6 +147,11 @@ func (t *Tag) GetContext() ontology.ContextType {
 	return t.Context
 }
 
+// GetDeclaredContext returns the context type declared for the tag's branch, empty if none
+func (t *Tag) GetDeclaredContext() ontology.ContextType {
+	return t.DeclaredContext
+}
+
 // GetTitle implements TagQATarget
 func (t *Tag) GetTitle() string {
 	return t.Title
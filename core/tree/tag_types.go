@@ -1,28 +1,58 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/studyguides-com/study-guides-parser/core/ontology"
 )
 
-// AssignTagTypes implements TagTypeAssigner interface
+// AssignTagTypes implements TagTypeAssigner interface. Each top-level branch is resolved
+// against the context declared for it by an "@context" directive, or contextType when
+// none was declared. Branches with a declared context are matched by their own depth,
+// the others by the depth of the whole tree.
 func (t *Tree) AssignTagTypes(contextType ontology.ContextType) error {
 	// First, determine the maximum depth of the tree
 	maxDepth := t.getMaxDepth()
 
 	// Find the ontology entry for this total depth
 	tagOntology := ontology.FindTagOntology(contextType, maxDepth)
-	if tagOntology == nil {
+	if tagOntology == nil && !t.HasDeclaredContext() {
 		return fmt.Errorf("no ontology found for context type '%s' with depth %d", contextType, maxDepth)
 	}
 
-	// Now traverse and assign types based on individual tag depths
-	t.TraverseForTagTypes(func(tag TagTypeAssignable, depth int) {
-		assignTagTypeFromOntology(tag, contextType, depth, tagOntology)
-	})
+	// Now traverse each branch and assign types based on individual tag depths
+	var errs []error
+	for _, branch := range t.Root.ChildTags {
+		branchContext, branchOntology, depth := contextType, tagOntology, maxDepth
+		if branch.DeclaredContext != "" {
+			branchContext, depth = branch.DeclaredContext, tagDepth(branch)
+			branchOntology = ontology.FindTagOntology(branchContext, depth)
+		}
+		if branchOntology == nil {
+			errs = append(errs, fmt.Errorf("no ontology found for context type '%s' with depth %d in '%s'",
+				branchContext, depth, branch.Title))
+			continue
+		}
+		traverseTag(branch, 1, func(tag TagTypeAssignable, depth int) {
+			assignTagTypeFromOntology(tag, branchContext, depth, branchOntology)
+		})
+	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// HasDeclaredContext reports whether any top-level branch declares its own context
+func (t *Tree) HasDeclaredContext() bool {
+	if t.Root == nil {
+		return false
+	}
+	for _, branch := range t.Root.ChildTags {
+		if branch.DeclaredContext != "" {
+			return true
+		}
+	}
+	return false
 }
 
 // getMaxDepth calculates the maximum depth of the tree
@@ -32,23 +62,37 @@ func (t *Tree) getMaxDepth() int {
 	}
 
 	var maxDepth int
-	var traverse func(*Tag, int)
-	traverse = func(tag *Tag, depth int) {
-		if depth > maxDepth {
+	for _, child := range t.Root.ChildTags {
+		if depth := tagDepth(child); depth > maxDepth {
 			maxDepth = depth
 		}
-		for _, child := range tag.ChildTags {
-			traverse(child, depth+1)
-		}
 	}
 
-	for _, child := range t.Root.ChildTags {
-		traverse(child, 1)
-	}
+	return maxDepth
+}
 
+// tagDepth returns the number of levels in the branch below and including tag
+func tagDepth(tag *Tag) int {
+	var maxDepth int
+	traverseTag(tag, 1, func(_ TagTypeAssignable, depth int) {
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	})
 	return maxDepth
 }
 
+// traverseTag visits tag at depth and then its descendants, depth first
+func traverseTag(tag *Tag, depth int, visitor func(TagTypeAssignable, int)) {
+	if tag == nil {
+		return
+	}
+	visitor(tag, depth)
+	for _, child := range tag.ChildTags {
+		traverseTag(child, depth+1, visitor)
+	}
+}
+
 // assignTagTypeFromOntology assigns the appropriate tag type based on context and depth within a known ontology
 func assignTagTypeFromOntology(tag TagTypeAssignable, contextType ontology.ContextType, depth int, tagOntology *ontology.TagOntology) {
 	if depth <= len(tagOntology.TagTypes) {
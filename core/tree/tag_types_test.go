@@ -104,3 +104,42 @@ func TestAssignTagTypesError(t *testing.T) {
 		t.Errorf("Expected error message '%s', got '%s'", expectedError, err.Error())
 	}
 }
+
+func TestAssignTagTypesWithDeclaredContext(t *testing.T) {
+	tree := NewTree(&config.Metadata{Type: "test", ContextType: ontology.ContextTypeCollege})
+
+	college := NewTag("College")
+	department := NewTag("Biology")
+	course := NewTag("BIO 101")
+	college.AddChildTag(department)
+	department.AddChildTag(course)
+	course.AddChildTag(NewTag("Cells"))
+
+	ap := NewTag("AP Exams")
+	ap.DeclaredContext = ontology.ContextTypeAPExams
+	exam := NewTag("AP Biology")
+	ap.AddChildTag(exam)
+	exam.AddChildTag(NewTag("Unit 1"))
+
+	tree.Root.AddChildTag(college)
+	tree.Root.AddChildTag(ap)
+
+	if err := tree.AssignTagTypes(ontology.ContextTypeCollege); err != nil {
+		t.Fatalf("AssignTagTypes() error: %v", err)
+	}
+	if course.TagType != ontology.TagTypeCourse || course.Context != ontology.ContextTypeCollege {
+		t.Errorf("course = %s/%s, want %s/%s", course.TagType, course.Context, ontology.TagTypeCourse, ontology.ContextTypeCollege)
+	}
+	if exam.TagType != ontology.TagTypeAPExam || exam.Context != ontology.ContextTypeAPExams {
+		t.Errorf("exam = %s/%s, want %s/%s", exam.TagType, exam.Context, ontology.TagTypeAPExam, ontology.ContextTypeAPExams)
+	}
+
+	// A branch whose declared context has no ontology for its depth is reported on its own
+	ap.DeclaredContext = ontology.ContextTypeCollege
+	if err := tree.AssignTagTypes(ontology.ContextTypeCollege); err == nil {
+		t.Error("AssignTagTypes() expected an error for the three-level College branch")
+	}
+	if course.TagType != ontology.TagTypeCourse {
+		t.Errorf("course = %s, want the other branch still assigned", course.TagType)
+	}
+}
@@ -2,11 +2,14 @@ package tree
 
 import (
 	"github.com/studyguides-com/study-guides-parser/core/config"
+	"github.com/studyguides-com/study-guides-parser/core/ontology"
 )
 
 type Tree struct {
-	Root     *Root            `json:"root"`
-	Metadata *config.Metadata `json:"metadata"`
+	Root         *Root                `json:"root"`
+	Metadata     *config.Metadata     `json:"metadata"`
+	Bibliography []*BibliographyEntry `json:"bibliography,omitempty"` // Every work cited in the guide, without duplicates
+	AuthorNotes  []*AuthorNotesEntry  `json:"author_notes,omitempty"` // The author notes, only when exported with CollectAuthorNotes
 }
 
 func NewTree(metadata *config.Metadata) *Tree {
@@ -32,3 +35,12 @@ func (t *Tree) LeafNodes() []*Tag {
 
 	return leafNodes
 }
+
+// ApplyContentRating sets the content rating of every tag that is still pending a rating
+func (t *Tree) ApplyContentRating(rating ontology.ContentRatingType) {
+	t.TraverseForTagTypes(func(tag TagTypeAssignable, depth int) {
+		if tagTag, ok := tag.(*Tag); ok && tagTag.ContentRating == ontology.ContentRatingRatingPending {
+			tagTag.ContentRating = rating
+		}
+	})
+}
@@ -120,3 +120,38 @@ func TestTreeLeafNodesSingleNode(t *testing.T) {
 		t.Errorf("Expected leaf node title 'SingleTag', got '%s'", leafNodes[0].Title)
 	}
 }
+
+func TestTreeAnalyzeReadability(t *testing.T) {
+	tree := NewTree(&config.Metadata{Type: "test"})
+	course := NewTag("Course")
+	unit := NewTag("Unit")
+	empty := NewTag("Empty")
+	course.AddChildTag(unit)
+	course.AddChildTag(empty)
+	tree.Root.AddChildTag(course)
+
+	first := NewPassage("First", "", nil)
+	first.Blocks = []*Block{
+		{Type: BlockTypeParagraph, Text: "The cat sat on the **mat**. It was happy"},
+		{Type: BlockTypeList, Items: []string{"Red", "Blue"}},
+		{Type: BlockTypeTable, Rows: [][]string{{"Not", "counted"}}},
+	}
+	second := NewPassage("Second", "Dogs bark.", nil)
+	course.Passages = []*Passage{second}
+	unit.Passages = []*Passage{first}
+
+	tree.AnalyzeReadability(60)
+
+	if got := first.Readability; got == nil || got.Words != 11 || got.Sentences != 4 || got.ReadingTimeSeconds != 11 {
+		t.Errorf("first passage Readability = %+v, want 11 words in 4 sentences read in 11 seconds", got)
+	}
+	if got := unit.Readability; got == nil || *got != *first.Readability {
+		t.Errorf("unit Readability = %+v, want that of its only passage", got)
+	}
+	if got := course.Readability; got == nil || got.Words != 13 || got.Sentences != 5 {
+		t.Errorf("course Readability = %+v, want both passages together", got)
+	}
+	if empty.Readability != nil {
+		t.Errorf("Readability of a tag without passages = %+v, want nil", empty.Readability)
+	}
+}
@@ -0,0 +1,35 @@
+package variables
+
+import "fmt"
+
+// ErrorCode represents a service error code
+type ErrorCode string
+
+const (
+	CodeInvalidDefinition ErrorCode = "INVALID_DEFINITION"
+	CodeRedefinedVariable ErrorCode = "REDEFINED_VARIABLE"
+	CodeUndefinedVariable ErrorCode = "UNDEFINED_VARIABLE"
+	// Variables a guide defines but never uses; reported as warnings
+	CodeUnusedVariable ErrorCode = "UNUSED_VARIABLE"
+)
+
+// VariableError reports a malformed definition or a problem with a variable's use. The caller knows the line.
+type VariableError struct {
+	Message string
+	Code    ErrorCode
+	Name    string // The variable, empty when a definition cannot be read
+}
+
+// Error implements the error interface
+func (e *VariableError) Error() string {
+	return fmt.Sprintf("%s (variable: %s)", e.Message, e.Name)
+}
+
+// NewVariableError creates a new variable error with the given code and message
+func NewVariableError(code ErrorCode, message string, name string) *VariableError {
+	return &VariableError{
+		Message: message,
+		Code:    code,
+		Name:    name,
+	}
+}
@@ -0,0 +1,111 @@
+// Package variables expands references to guide variables. A guide defines a variable
+// with a "@define" directive, or receives it in its metadata or front matter, and refers
+// to it as "{{name}}" in its headers, questions, answers and passages:
+//
+//	@define course = BIO 101
+//	College: Biology: {{course}}: Cells
+//	1. Which course covers mitosis? - {{course}}
+//
+// Variable names are case sensitive, and a value is used exactly as written.
+package variables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/studyguides-com/study-guides-parser/core/regexes"
+	"github.com/studyguides-com/study-guides-parser/core/source"
+)
+
+// Definition is a variable defined in the guide
+type Definition struct {
+	Name     string
+	Value    string
+	Position source.Position // Where the "@define" directive is in the original source
+}
+
+// Table holds the variables of a guide and records which of them are used
+type Table struct {
+	values      map[string]string
+	definitions map[string]Definition // The variables defined in the guide, by name
+	order       []string              // The names of the variables defined in the guide, in definition order
+	used        map[string]bool
+}
+
+// NewTable creates a table holding the given variables, which come from outside the guide
+// and are never reported as unused
+func NewTable(values map[string]string) *Table {
+	t := &Table{
+		values:      make(map[string]string, len(values)),
+		definitions: make(map[string]Definition),
+		used:        make(map[string]bool),
+	}
+	for name, value := range values {
+		t.values[name] = value
+	}
+	return t
+}
+
+// ParseDefinition reads the value of a "@define" directive, written "name = value"
+func ParseDefinition(text string) (string, string, *VariableError) {
+	match := regexes.VariableDefinitionRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return "", "", NewVariableError(CodeInvalidDefinition,
+			fmt.Sprintf("invalid definition %q, expected \"name = value\" with a name of letters, digits and underscores", text), "")
+	}
+	return match[1], strings.TrimSpace(match[2]), nil
+}
+
+// ValidName reports whether name can be used as a variable name
+func ValidName(name string) bool {
+	return regexes.VariableNameRegex.MatchString(name)
+}
+
+// Define adds a variable defined in the guide at the given position. A variable may only be
+// defined once, in the guide or outside it.
+func (t *Table) Define(name string, value string, position source.Position) *VariableError {
+	if previous, ok := t.definitions[name]; ok {
+		where := fmt.Sprintf("line %d", previous.Position.Line)
+		if previous.Position.File != "" {
+			where = fmt.Sprintf("%s line %d", previous.Position.File, previous.Position.Line)
+		}
+		return NewVariableError(CodeRedefinedVariable,
+			fmt.Sprintf("variable %q is already defined on %s", name, where), name)
+	}
+	if _, ok := t.values[name]; ok {
+		return NewVariableError(CodeRedefinedVariable,
+			fmt.Sprintf("variable %q is already defined in the metadata", name), name)
+	}
+	t.values[name] = value
+	t.definitions[name] = Definition{Name: name, Value: value, Position: position}
+	t.order = append(t.order, name)
+	return nil
+}
+
+// Expand replaces the variable references in text with their values. References to
+// undefined variables are left as written and reported.
+func (t *Table) Expand(text string) (string, []*VariableError) {
+	var errs []*VariableError
+	expanded := regexes.VariableReferenceRegex.ReplaceAllStringFunc(text, func(reference string) string {
+		name := regexes.VariableReferenceRegex.FindStringSubmatch(reference)[1]
+		value, ok := t.values[name]
+		if !ok {
+			errs = append(errs, NewVariableError(CodeUndefinedVariable, fmt.Sprintf("variable %q is not defined", name), name))
+			return reference
+		}
+		t.used[name] = true
+		return value
+	})
+	return expanded, errs
+}
+
+// Unused returns the variables defined in the guide that were never expanded, in definition order
+func (t *Table) Unused() []Definition {
+	var unused []Definition
+	for _, name := range t.order {
+		if !t.used[name] {
+			unused = append(unused, t.definitions[name])
+		}
+	}
+	return unused
+}
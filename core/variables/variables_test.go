@@ -0,0 +1,85 @@
+package variables
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/studyguides-com/study-guides-parser/core/source"
+)
+
+func TestParseDefinition(t *testing.T) {
+	name, value, err := ParseDefinition(" course_code =  BIO 101 ")
+	if err != nil || name != "course_code" || value != "BIO 101" {
+		t.Errorf("ParseDefinition() = %q, %q, %v, want course_code, BIO 101", name, value, err)
+	}
+	if _, value, err := ParseDefinition("empty ="); err != nil || value != "" {
+		t.Errorf("ParseDefinition(empty) = %q, %v, want an empty value", value, err)
+	}
+	for _, text := range []string{"course", "= BIO 101", "course code = BIO 101", "1st = one"} {
+		if _, _, err := ParseDefinition(text); err == nil || err.Code != CodeInvalidDefinition {
+			t.Errorf("ParseDefinition(%q) error = %v, want %s", text, err, CodeInvalidDefinition)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	table := NewTable(map[string]string{"agency": "CompTIA"})
+	if err := table.Define("course", "BIO 101", source.Position{Line: 2}); err != nil {
+		t.Fatalf("Define() error: %v", err)
+	}
+
+	tests := []struct {
+		text      string
+		want      string
+		wantCodes []ErrorCode
+	}{
+		{"College: Biology: {{course}}: Cells", "College: Biology: BIO 101: Cells", nil},
+		{"{{ agency }} and {{course}}", "CompTIA and BIO 101", nil},
+		{"Capital of {{c1::France}}", "Capital of {{c1::France}}", nil},
+		{"In {{year}} - {{Course}}", "In {{year}} - {{Course}}", []ErrorCode{CodeUndefinedVariable, CodeUndefinedVariable}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, errs := table.Expand(tt.text)
+			var codes []ErrorCode
+			for _, err := range errs {
+				codes = append(codes, err.Code)
+			}
+			if got != tt.want || !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("Expand() = %q, %v, want %q, %v", got, codes, tt.want, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestDefineAndUnused(t *testing.T) {
+	table := NewTable(map[string]string{"agency": "CompTIA"})
+	for i, name := range []string{"course", "year", "term"} {
+		if err := table.Define(name, "value", source.Position{Line: i + 2}); err != nil {
+			t.Fatalf("Define(%s) error: %v", name, err)
+		}
+	}
+	if err := table.Define("course", "BIO 102", source.Position{Line: 9}); err == nil || err.Code != CodeRedefinedVariable ||
+		!strings.Contains(err.Message, "on line 2") {
+		t.Errorf("Define(course) again error = %v, want %s on line 2", err, CodeRedefinedVariable)
+	}
+	table.Define("unit", "value", source.Position{File: "units.txt", Line: 5})
+	table.Expand("{{unit}}")
+	if err := table.Define("unit", "Cells", source.Position{Line: 10}); err == nil ||
+		!strings.Contains(err.Message, "on units.txt line 5") {
+		t.Errorf("Define(unit) again error = %v, want the first definition on units.txt line 5", err)
+	}
+	if err := table.Define("agency", "ISC2", source.Position{Line: 9}); err == nil || err.Code != CodeRedefinedVariable {
+		t.Errorf("Define(agency) error = %v, want %s", err, CodeRedefinedVariable)
+	}
+
+	table.Expand("{{year}}")
+	want := []Definition{
+		{Name: "course", Value: "value", Position: source.Position{Line: 2}},
+		{Name: "term", Value: "value", Position: source.Position{Line: 4}},
+	}
+	if got := table.Unused(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unused() = %+v, want %+v (variables from outside the guide are not reported)", got, want)
+	}
+}